@@ -2,24 +2,32 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 
 	"github.com/charmbracelet/x/term"
 	"github.com/golang/glog"
+	"github.com/sbezverk/routercommander/pkg/inventory"
 	"github.com/sbezverk/routercommander/pkg/log"
 	"github.com/sbezverk/routercommander/pkg/messenger"
 	"github.com/sbezverk/routercommander/pkg/messenger/email"
+	"github.com/sbezverk/routercommander/pkg/messenger/webhook"
+	"github.com/sbezverk/routercommander/pkg/results"
+	"github.com/sbezverk/routercommander/pkg/runner"
+	"github.com/sbezverk/routercommander/pkg/sshclient"
 	"github.com/sbezverk/routercommander/pkg/types"
-	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -40,8 +48,42 @@ var (
 	knownHostsFile string
 	insecureSSH    bool
 	passwordStdin  bool
+	resultsFormat  string
+	replayLog      string
+	recordDir      string
+	sshKeyFile     string
+	sshKeyPass     string
+	sshAgent       bool
+	sshKbdInteract bool
+	proxyJump      string
+	parallel       int
+	summaryFile    string
+	webhookURL     string
+	webhookFormat  string
+	webhookHeaders stringList
+	cmdVars        stringList
+	webhookTmpl    string
+	webhookLogURL  string
+	notifyEvents   string
+	reconnectTries int
+	reconnectWait  int
+	reconnectMax   int
+	keepAliveIntvl int
+	keepAliveFails int
 )
 
+// stringList is a flag which can be specified multiple times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
 func init() {
 	flag.BoolVar(&local, "local", false, "when set to true, routercommander is running on the local router")
 	// Breaking change
@@ -61,96 +103,28 @@ func init() {
 	flag.StringVar(&knownHostsFile, "known-hosts-file", "/tmp/routercommander_known_hosts", "path to the known hosts file for SSH")
 	flag.BoolVar(&insecureSSH, "insecure-ssh", false, "when set to true, SSH host key verification will be disabled and new host keys will not be added to the known hosts file")
 	flag.BoolVar(&passwordStdin, "password-stdin", false, "read the password from stdin")
-}
-
-type RouterInventory struct {
-	Routers map[string]*RouterTarget `yaml:"routers"`
-}
-
-type RouterTarget struct {
-	Address  string `yaml:"address"`
-	Port     int    `yaml:"port"`
-	Platform string `yaml:"platform"`
-	Username string `yaml:"username"`
-}
-
-type ResolvedTarget struct {
-	Name     string
-	Address  string
-	Port     int
-	Platform string
-	Username string
-}
-
-func normalizeRouterName(name string) string {
-	return strings.Trim(strings.ToLower(strings.TrimSpace(name)), "\n\t,")
-}
-
-func resolveRouterTarget(name string, inventory *RouterInventory, defaultPort int, defaultUser string) (*ResolvedTarget, error) {
-	normalized := normalizeRouterName(name)
-	if inventory == nil {
-		// Not failing if inventory is not provided, will be using specified name as actual address to connect to
-		glog.Warningf("routers inventory is not provided, using specified router name %s as an address to connect to", normalized)
-		return nil, nil
-	}
-	target, ok := inventory.Routers[normalized]
-	if !ok {
-		// Not failing if router is not found in the inventory, will be using specified name as actual address to connect to
-		glog.Warningf("router %s is not found in the inventory, using specified router name as an address to connect to", normalized)
-		return nil, nil
-	}
-	if target.Address == "" {
-		return nil, fmt.Errorf("address for router %s is not specified in the inventory", name)
-	}
-	if target.Port == 0 {
-		target.Port = defaultPort
-	}
-	if target.Username == "" {
-		target.Username = defaultUser
-	}
-	return &ResolvedTarget{
-		Name:     normalized,
-		Address:  target.Address,
-		Port:     target.Port,
-		Platform: target.Platform,
-		Username: target.Username,
-	}, nil
-}
-
-func getRoutersInventory(fileName string) (*RouterInventory, error) {
-	f, err := os.Open(fileName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open router inventory file %s with error: %+v", fileName, err)
-	}
-	defer f.Close()
-	b, err := io.ReadAll(f)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read router inventory file %s with error: %+v", fileName, err)
-	}
-	inventory := &RouterInventory{}
-	if err := yaml.Unmarshal(b, inventory); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal router inventory file %s with error: %+v", fileName, err)
-	}
-	normalized := &RouterInventory{
-		Routers: make(map[string]*RouterTarget),
-	}
-	for name, target := range inventory.Routers {
-		normName := normalizeRouterName(name)
-		if normName == "" {
-			glog.Warningf("router with empty name is found in the inventory file %s, skipping...", fileName)
-			continue
-		}
-		if target.Address == "" {
-			glog.Warningf("router %s has empty address in the inventory file %s, skipping...", name, fileName)
-			continue
-		}
-		if target.Port == 0 {
-			target.Port = 22
-		}
-		normalized.Routers[normName] = target
-	}
-
-	return normalized, nil
+	flag.StringVar(&replayLog, "replay", "", "path to a previously captured routercommander log, commands are served from the log instead of a router")
+	flag.StringVar(&recordDir, "record", "", "directory to record raw SSH sessions with routers to, a fixture file per router is created, fixtures are served by \"routercommander simulate --fixture\"")
+	flag.StringVar(&resultsFormat, "results-format", "", "when set to json or jsonl, machine readable results file is created next to the log file of each router")
+	flag.StringVar(&sshKeyFile, "ssh-key-file", "", "path to the private key file to use for ssh public key authentication")
+	flag.StringVar(&sshKeyPass, "ssh-key-passphrase", "", "passphrase of the encrypted private key file")
+	flag.BoolVar(&sshAgent, "ssh-agent", false, "when set to true, keys from ssh-agent pointed by SSH_AUTH_SOCK are used for ssh authentication")
+	flag.StringVar(&proxyJump, "proxy-jump", "", "comma separated list of jump hosts in the form of [user@]host[:port] used to reach routers, routers' inventory settings take precedence")
+	flag.IntVar(&parallel, "parallel", 0, "maximum number of routers connected and processed at the same time, 0 processes all routers at once")
+	flag.StringVar(&summaryFile, "summary-file", "", "path to the end of run summary report file, .html extension produces HTML, otherwise Markdown")
+	flag.StringVar(&webhookURL, "webhook-url", "", "url of the webhook to post the notification with the log summary to")
+	flag.StringVar(&webhookFormat, "webhook-format", "generic", "format of the webhook's body: generic, slack or teams")
+	flag.Var(&cmdVars, "var", "variable in the form of \"key=value\" referenced by commands as {{.key}}, overrides variables of the commands file and the inventory, can be specified multiple times")
+	flag.Var(&webhookHeaders, "webhook-header", "header in the form of \"Name: value\" added to the webhook request, can be specified multiple times")
+	flag.StringVar(&webhookTmpl, "webhook-template", "", "path to the text/template file producing JSON body of the generic webhook")
+	flag.StringVar(&webhookLogURL, "webhook-log-url", "", "base url where logs are published, the link to the log is included in the webhook's message")
+	flag.StringVar(&notifyEvents, "notify-events", "repro_triggered,test_triggered,connection_lost,run_finished", "comma separated list of events to send notifications about: repro_triggered, test_triggered, connection_lost, run_finished")
+	flag.IntVar(&reconnectTries, "reconnect-attempts", 0, "maximum number of attempts to re-establish the lost connection to a router, 0 disables reconnection")
+	flag.IntVar(&reconnectWait, "reconnect-backoff", 10, "number of seconds to wait before the first reconnect attempt, doubled after every failed attempt")
+	flag.IntVar(&reconnectMax, "reconnect-max-backoff", 300, "maximum number of seconds to wait between reconnect attempts")
+	flag.IntVar(&keepAliveIntvl, "ssh-keepalive-interval", 30, "number of seconds between SSH keepalive requests, 0 disables keepalives")
+	flag.IntVar(&keepAliveFails, "ssh-keepalive-max-failures", 3, "number of consecutive failed SSH keepalive requests after which the connection is considered dead")
+	flag.BoolVar(&sshKbdInteract, "ssh-keyboard-interactive", false, "when set to true, keyboard-interactive authentication answering prompts with the password is used as a fallback")
 }
 
 func main() {
@@ -162,6 +136,22 @@ func main() {
     +---------------------------------------------------+
 `
 
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		_ = flag.Set("logtostderr", "true")
+		if err := runSimulate(os.Args[2:]); err != nil {
+			glog.Errorf("simulate failed with error: %+v", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		_ = flag.Set("logtostderr", "true")
+		if err := runValidate(os.Args[2:]); err != nil {
+			glog.Errorf("validate failed with error: %+v", err)
+			os.Exit(1)
+		}
+		return
+	}
 	flag.Parse()
 	_ = flag.Set("logtostderr", "true")
 
@@ -171,53 +161,86 @@ func main() {
 		glog.Infof("no commands file is specified, nothing to do, exiting...")
 		os.Exit(1)
 	}
+	if resultsFormat != "" && resultsFormat != results.FormatJSON && resultsFormat != results.FormatJSONLines {
+		glog.Errorf("unsupported --results-format %q, supported formats: %s, %s, exiting...", resultsFormat, results.FormatJSON, results.FormatJSONLines)
+		os.Exit(1)
+	}
 	if passwordStdin && pass != "" {
 		glog.Error("both --password and --password-stdin parameters cannot be provided simultaneously, exiting...")
 		os.Exit(1)
 	}
 	var n messenger.Notifier
 	routers := make([]string, 0)
-	var inventory *RouterInventory
-	var err error
-	var fatalErr error
-	var wg sync.WaitGroup
+	var inv *inventory.RouterInventory
 
 	singleRouterCase := rtrName != ""
-	if !local {
+	globalAuth := &sshclient.SSHAuth{
+		PrivateKeyFile:       sshKeyFile,
+		PrivateKeyPassphrase: sshKeyPass,
+		UseAgent:             sshAgent,
+		KeyboardInteractive:  sshKbdInteract,
+	}
+	globalJump, err := inventory.ParseProxyJump(proxyJump)
+	if err != nil {
+		glog.Errorf("failed to parse --proxy-jump parameter with error: %+v, exiting...", err)
+		os.Exit(1)
+	}
+	if recordDir != "" {
+		if local || replayLog != "" {
+			glog.Error("--record parameter requires routers reachable over SSH, it cannot be combined with --replay or --local, exiting...")
+			os.Exit(1)
+		}
+		if err := os.MkdirAll(recordDir, 0755); err != nil {
+			glog.Errorf("failed to create --record directory %s with error: %+v, exiting...", recordDir, err)
+			os.Exit(1)
+		}
+	}
+	if replayLog != "" {
+		if local {
+			glog.Error("--replay and --local parameters cannot be provided simultaneously, exiting...")
+			os.Exit(1)
+		}
+		name := rtrName
+		if name == "" {
+			name = routerNameFromLog(replayLog)
+		}
+		routers = append(routers, name)
+	}
+	if !local && replayLog == "" {
 		switch {
 		case rtrName != "" && rtrFile == "":
 			// Case when only router's name if provided without inventory file
-			// this case requires both username and password to be provided
-			if login == "" || (pass == "" && !passwordStdin) {
-				glog.Error("--username and --password or --password-stdin are mandatory parameters, when no inventory file is provided, exiting...")
+			// this case requires both username and password or a key to be provided
+			if login == "" || (pass == "" && !passwordStdin && !globalAuth.HasKeyAuth()) {
+				glog.Error("--username and --password, --password-stdin, --ssh-key-file or --ssh-agent are mandatory parameters, when no inventory file is provided, exiting...")
 				os.Exit(1)
 			}
 			routers = append(routers, rtrName)
 		case rtrName != "" && rtrFile != "":
 			// Case when both router's name and inventory file are provided, inventory will be used to get more details abot a router
-			if pass == "" && !passwordStdin {
-				glog.Error("--password or --password-stdin is a mandatory parameter, when routers' inventory file is provided, exiting...")
-				os.Exit(1)
-			}
-			inventory, err = getRoutersInventory(rtrFile)
+			inv, err = inventory.GetRoutersInventory(rtrFile)
 			if err != nil {
 				glog.Errorf("failed to get routers inventory from file: %s with error: %+v, exiting...", rtrFile, err)
 				os.Exit(1)
 			}
+			if pass == "" && !passwordStdin && !globalAuth.HasKeyAuth() && !inv.HasKeyAuth([]string{rtrName}) {
+				glog.Error("--password, --password-stdin, --ssh-key-file or --ssh-agent is a mandatory parameter, when routers' inventory file does not define keys, exiting...")
+				os.Exit(1)
+			}
 			routers = append(routers, rtrName)
 		case rtrName == "" && rtrFile != "":
 			// Case when only inventory file is provided, all routers from the inventory will be processed
-			if pass == "" && !passwordStdin {
-				glog.Error("--password or --password-stdin is a mandatory parameter, when routers' inventory file is provided, exiting...")
-				os.Exit(1)
-			}
-			inventory, err = getRoutersInventory(rtrFile)
+			inv, err = inventory.GetRoutersInventory(rtrFile)
 			if err != nil {
 				glog.Errorf("failed to get routers inventory from file: %s with error: %+v, exiting...", rtrFile, err)
 				os.Exit(1)
 			}
-			for name := range inventory.Routers {
-				routers = append(routers, normalizeRouterName(name))
+			for name := range inv.Routers {
+				routers = append(routers, inventory.NormalizeRouterName(name))
+			}
+			if pass == "" && !passwordStdin && !globalAuth.HasKeyAuth() && !inv.HasKeyAuth(routers) {
+				glog.Error("--password, --password-stdin, --ssh-key-file or --ssh-agent is a mandatory parameter, when routers' inventory file does not define keys for all routers, exiting...")
+				os.Exit(1)
 			}
 		default:
 			glog.Error("either --router-name or --routers-file parameter should be provided, exiting...")
@@ -254,6 +277,35 @@ func main() {
 			}
 		}
 	}
+	if webhookURL != "" {
+		bodyTemplate := ""
+		if webhookTmpl != "" {
+			b, err := os.ReadFile(webhookTmpl)
+			if err != nil {
+				glog.Errorf("failed to read webhook template file %s with error: %+v, exiting...", webhookTmpl, err)
+				os.Exit(1)
+			}
+			bodyTemplate = string(b)
+		}
+		wn, err := webhook.NewWebhookNotifier(webhookURL, webhookFormat, webhookHeaders, bodyTemplate, webhookLogURL)
+		if err != nil {
+			glog.Errorf("failed to initialize webhook notifier with error: %+v, exiting...", err)
+			os.Exit(1)
+		}
+		if n != nil {
+			n = messenger.NewMultiNotifier(n, wn)
+		} else {
+			n = wn
+		}
+	}
+	if n != nil {
+		events, err := messenger.ParseEventTypes(notifyEvents)
+		if err != nil {
+			glog.Errorf("failed to parse --notify-events parameter with error: %+v, exiting...", err)
+			os.Exit(1)
+		}
+		n = messenger.NewEventFilter(n, events)
+	}
 	if local {
 		b, err := exec.Command("hostname").Output()
 		if err != nil {
@@ -262,6 +314,22 @@ func main() {
 		}
 		routers = append(routers, strings.Trim(string(b), " \n\t,"))
 	}
+	validate := types.ValidateCommandFile
+	if local {
+		validate = types.ValidateLocalCommandFile
+	}
+	problems, err := validate(cmdFile)
+	if err != nil {
+		glog.Errorf("failed to validate commands file: %s with error: %+v, exiting...", cmdFile, err)
+		os.Exit(1)
+	}
+	if len(problems) != 0 {
+		for _, p := range problems {
+			glog.Errorf("%s", p)
+		}
+		glog.Errorf("found %d problem(s) in commands file: %s, exiting...", len(problems), cmdFile)
+		os.Exit(1)
+	}
 	commands, err := types.GetCommands(cmdFile)
 	if err != nil {
 		glog.Errorf("failed to get list of commands from file: %s with error: %+v, exiting...", cmdFile, err)
@@ -272,12 +340,16 @@ func main() {
 		if commands.Collect != nil {
 			stopOnError = commands.Collect.StopOnError
 		}
+		if replayLog != "" && commands.Repro != nil {
+			// Replayed iterations come from the log, no need to wait between them
+			commands.Repro.Interval = 0
+		}
 	}
-	errCh := make(chan error, (len(routers)))
-	runProcessing := func(r types.Router) {
-		errCh <- process(r, commands, n)
+	vars, err := parseVars(cmdVars)
+	if err != nil {
+		glog.Errorf("failed to parse --var parameter with error: %+v, exiting...", err)
+		os.Exit(1)
 	}
-
 	if passwordStdin {
 		var pw string
 		pw, err = readPasswordFromStdin()
@@ -287,77 +359,71 @@ func main() {
 		}
 		pass = pw
 	}
-	processesStarted := 0
-	for _, router := range routers {
-		actRouter := router
-		actPort := port
-		actLogin := login
-		actPlatform := ""
-		if inventory != nil {
-			var target *ResolvedTarget
-			target, err = resolveRouterTarget(router, inventory, port, login)
-			if err != nil {
-				glog.Errorf("failed to resolve router target for router: %s with error: %+v", router, err)
-				if !stopOnError && !singleRouterCase {
-					continue
-				}
-				fatalErr = err
-				break
-			}
-			if target != nil {
-				actRouter = target.Address
-				actPort = target.Port
-				actPlatform = target.Platform
-				actLogin = target.Username
-			}
-		}
-		var li log.Logger
-		li, err = log.NewLogger(router, logLoc)
+	globalAuth.Password = pass
+	if runtime.GOOS == "windows" {
+		// Routers are processed one by one on windows
+		parallel = 1
+	}
+	// A single verifier is shared by all routers, so host keys learnt by routers connecting in parallel
+	// are added to the known hosts file one by one
+	var sshVerifier sshclient.Verifier
+	if replayLog == "" && !local {
+		sshVerifier, err = sshclient.NewVerifier(knownHostsFile, insecureSSH)
 		if err != nil {
-			glog.Errorf("failed to instantiate logger interface with error: %+v", err)
+			glog.Errorf("failed to get SSH configuration with error: %+v, exiting...", err)
 			os.Exit(1)
 		}
-		var r types.Router
-		if local {
-			r = types.NewLocalRouter(actRouter, li)
-		} else {
-			var sshVerifier Verifier
-			sshVerifier, err = NewVerifier(knownHostsFile, insecureSSH)
-			if err != nil {
-				glog.Errorf("failed to get SSH configuration with error: %+v, exiting...", err)
-				os.Exit(1)
-			}
-			r, err = types.NewRouter(actRouter, actPort, actPlatform, sshVerifier.GetSSHConfig(actLogin, pass), li)
-			if err != nil {
-				glog.Errorf("failed to instantiate router object for router: %s:%d with error: %+v", actRouter, actPort, err)
-				if !stopOnError && !singleRouterCase {
-					continue
-				}
-				fatalErr = err
-				break
-			}
+	}
+	targets := make([]*runner.Target, len(routers))
+	for i, router := range routers {
+		router := router
+		targets[i] = &runner.Target{
+			Name: router,
+			Connect: func() (types.Router, results.Recorder, error) {
+				return connect(router, inv, globalAuth, globalJump, sshVerifier)
+			},
+			Vars: inv.RouterVars(router),
 		}
-		if runtime.GOOS != "windows" {
-			wg.Add(1)
-			go func(r types.Router) {
-				defer wg.Done()
-				runProcessing(r)
-			}(r)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		// Restoring the default handling, so the next signal terminates immediately
+		signal.Stop(sigs)
+		glog.Warningf("received %s, aborting in-flight commands and stopping, send it again to exit immediately", sig)
+		cancel()
+	}()
+	report, fatalErr := runner.New(commands, targets, &runner.Options{
+		Parallel: parallel,
+		// Stopping the processing of routers which have not been started yet only when routers' connection failure
+		// is considered fatal
+		StopOnFailure: stopOnError || singleRouterCase,
+		Notifier:      n,
+		Vars:          vars,
+	}).Run(ctx)
+	if errors.Is(fatalErr, runner.ErrDuplicateTarget) {
+		glog.Errorf("%+v, exiting...", fatalErr)
+	}
+	if err := report.WriteText(os.Stdout); err != nil {
+		glog.Errorf("failed to print summary with error: %+v", err)
+	}
+	if summaryFile != "" {
+		if err := report.WriteFile(summaryFile); err != nil {
+			glog.Errorf("%+v", err)
 		} else {
-			runProcessing(r)
+			glog.Infof("summary is stored at %s", summaryFile)
 		}
-		processesStarted++
 	}
 	pass = ""
-	for i := 0; i < processesStarted; i++ {
-		err := <-errCh
-		if err != nil {
-			glog.Errorf("processing finished with error: %+v", err)
-			fatalErr = err
+	globalAuth.Password = ""
+	if sshVerifier != nil {
+		if err := sshVerifier.Close(); err != nil {
+			glog.Errorf("failed to close known hosts file with error: %+v", err)
 		}
 	}
-	wg.Wait()
-	close(errCh)
 	glog.Infof("all processes have finished, exiting...")
 	if fatalErr == nil {
 		os.Exit(0)
@@ -365,6 +431,113 @@ func main() {
 	os.Exit(1)
 }
 
+// connect connects to the router, failures which are not specific to the router stop processing of
+// routers which have not been started yet.
+func connect(router string, inv *inventory.RouterInventory, globalAuth *sshclient.SSHAuth, globalJump []*inventory.JumpHost, sshVerifier sshclient.Verifier) (types.Router, results.Recorder, error) {
+	actRouter := router
+	actPort := port
+	actLogin := login
+	actPlatform := ""
+	actAuth := globalAuth
+	actJump, err := inventory.ResolveJumpHosts(globalJump, login, globalAuth)
+	if err != nil {
+		return nil, nil, runner.NewStopError(fmt.Errorf("failed to resolve jump hosts with error: %+v", err))
+	}
+	if inv != nil {
+		target, err := inventory.ResolveRouterTarget(router, inv, port, login, globalAuth, globalJump)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve router target for router: %s with error: %+v", router, err)
+		}
+		if target != nil {
+			actRouter = target.Address
+			actPort = target.Port
+			actPlatform = target.Platform
+			actLogin = target.Username
+			actAuth = target.Auth
+			actJump = target.ProxyJump
+		}
+	}
+	li, err := log.NewLogger(router, logLoc)
+	if err != nil {
+		return nil, nil, runner.NewStopError(fmt.Errorf("failed to instantiate logger interface with error: %+v", err))
+	}
+	var r types.Router
+	if replayLog != "" {
+		r, err = types.NewReplayRouter(router, replayLog, li)
+		if err != nil {
+			return nil, nil, runner.NewStopError(fmt.Errorf("failed to instantiate replay of log %s with error: %+v", replayLog, err))
+		}
+	} else if local {
+		r = types.NewLocalRouter(actRouter, li)
+	} else {
+		sshConfig, err := sshVerifier.GetSSHConfigWithAuth(actLogin, actAuth)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get SSH configuration for router: %s with error: %+v", router, err)
+		}
+		opts := &types.RouterOptions{
+			KeepAlive: &types.KeepAlivePolicy{
+				Interval:    keepAliveIntvl,
+				MaxFailures: keepAliveFails,
+			},
+		}
+		if recordDir != "" {
+			opts.Record = filepath.Join(recordDir, router+".yaml")
+		}
+		if reconnectTries > 0 {
+			opts.Reconnect = &types.ReconnectPolicy{
+				MaxAttempts: reconnectTries,
+				Backoff:     reconnectWait,
+				MaxBackoff:  reconnectMax,
+			}
+		}
+		if len(actJump) != 0 {
+			opts.Dial, err = sshclient.NewProxyJumpDialer(sshVerifier, actJump)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to set up jump hosts for router: %s with error: %+v", router, err)
+			}
+		}
+		r, err = types.NewRouterWithOptions(actRouter, actPort, actPlatform, sshConfig, li, opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to instantiate router object for router: %s:%d with error: %+v", actRouter, actPort, err)
+		}
+	}
+	var rec results.Recorder
+	if resultsFormat != "" {
+		rec, err = results.NewRecorder(router, logLoc, li.GetLogFileName(), resultsFormat)
+		if err != nil {
+			r.Close()
+			return nil, nil, runner.NewStopError(fmt.Errorf("failed to instantiate results recorder with error: %+v", err))
+		}
+	}
+
+	return r, rec, nil
+}
+
+var logTimestamp = regexp.MustCompile(`_\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}\.log$`)
+
+// routerNameFromLog returns the router name used as the prefix of a routercommander log file name.
+func routerNameFromLog(fn string) string {
+	base := filepath.Base(fn)
+	if loc := logTimestamp.FindStringIndex(base); loc != nil {
+		return base[:loc[0]]
+	}
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// parseVars parses variables in the form of "key=value"
+func parseVars(l []string) (map[string]string, error) {
+	vars := make(map[string]string, len(l))
+	for _, kv := range l {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("variable %q is not in the form of \"key=value\"", kv)
+		}
+		vars[strings.TrimSpace(k)] = v
+	}
+
+	return vars, nil
+}
+
 func readPasswordFromStdin() (string, error) {
 	pw := ""
 	s := ""
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/golang/glog"
+	"github.com/sbezverk/routercommander/pkg/simulator"
+)
+
+// runSimulate runs the simulated router until SIGINT or SIGTERM is received
+func runSimulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	config := fs.String("config", "", "YAML file defining the simulated router, when not specified, the router answers only session and show platform commands")
+	fixture := fs.String("fixture", "", "session recorded by --record to serve, overrides the fixture of the configuration")
+	listen := fs.String("listen", "127.0.0.1:2222", "address the simulated router listens on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c := &simulator.Config{}
+	if *config != "" {
+		var err error
+		if c, err = simulator.LoadConfig(*config); err != nil {
+			return err
+		}
+	}
+	if *fixture != "" {
+		c.Fixture = *fixture
+	}
+	sim, err := simulator.NewSimulator(*listen, c)
+	if err != nil {
+		return fmt.Errorf("failed to start simulated router with error: %+v", err)
+	}
+	defer sim.Close()
+	glog.Infof("simulated router is listening on %s, press Ctrl-C to stop", sim.Addr())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	<-sigs
+	glog.Info("stopping simulated router")
+
+	return nil
+}
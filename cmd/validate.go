@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sbezverk/routercommander/pkg/types"
+)
+
+// runValidate prints problems found in the commands file, an error is returned when any problem is found
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	file := fs.String("commands-file", "", "YAML formated file with commands to validate")
+	local := fs.Bool("local", false, "validate commands executed on the local host")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("--commands-file parameter is required")
+	}
+	validate := types.ValidateCommandFile
+	if *local {
+		validate = types.ValidateLocalCommandFile
+	}
+	problems, err := validate(*file)
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Fprintln(os.Stdout, p)
+	}
+	if len(problems) != 0 {
+		return fmt.Errorf("found %d problem(s) in %s", len(problems), *file)
+	}
+	fmt.Fprintf(os.Stdout, "%s: no problems found\n", *file)
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package inventory
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/sbezverk/routercommander/pkg/sshclient"
+	"gopkg.in/yaml.v3"
+)
+
+type RouterInventory struct {
+	// ProxyJump defines the chain of jump hosts used for all routers which do not define their own.
+	ProxyJump []*JumpHost              `yaml:"proxy_jump"`
+	Routers   map[string]*RouterTarget `yaml:"routers"`
+}
+
+// TargetAuth defines authentication settings of a router or a jump host in the inventory.
+type TargetAuth struct {
+	PrivateKeyFile       string `yaml:"private_key_file"`
+	PrivateKeyPassphrase string `yaml:"private_key_passphrase"`
+	SSHAgent             *bool  `yaml:"ssh_agent"`
+	KeyboardInteractive  *bool  `yaml:"keyboard_interactive"`
+}
+
+// HasKeyAuth returns true if the target defines its own public key authentication.
+func (t *TargetAuth) HasKeyAuth() bool {
+	return t.PrivateKeyFile != "" || (t.SSHAgent != nil && *t.SSHAgent)
+}
+
+type RouterTarget struct {
+	Address string `yaml:"address"`
+	Port    int    `yaml:"port"`
+	// Platform selects the driver of the router's operating system: iosxr (default), iosxe, nxos, junos or eos
+	Platform   string `yaml:"platform"`
+	Username   string `yaml:"username"`
+	TargetAuth `yaml:",inline"`
+	// ProxyJump overrides the inventory wide jump hosts chain, an empty list disables jump hosts for the router.
+	ProxyJump []*JumpHost `yaml:"proxy_jump"`
+	// Vars defines variables of the router referenced by commands, they override variables of the commands file.
+	Vars map[string]string `yaml:"vars"`
+}
+
+type ResolvedTarget struct {
+	Name      string
+	Address   string
+	Port      int
+	Platform  string
+	Username  string
+	Auth      *sshclient.SSHAuth
+	ProxyJump []*sshclient.Hop
+}
+
+// ResolveAuth returns the authentication for the target, values defined in the inventory
+// override the global ones field by field.
+func ResolveAuth(target *TargetAuth, defaultAuth *sshclient.SSHAuth) *sshclient.SSHAuth {
+	auth := &sshclient.SSHAuth{}
+	if defaultAuth != nil {
+		*auth = *defaultAuth
+	}
+	if target.PrivateKeyFile != "" {
+		auth.PrivateKeyFile = target.PrivateKeyFile
+	}
+	if target.PrivateKeyPassphrase != "" {
+		auth.PrivateKeyPassphrase = target.PrivateKeyPassphrase
+	}
+	if target.SSHAgent != nil {
+		auth.UseAgent = *target.SSHAgent
+	}
+	if target.KeyboardInteractive != nil {
+		auth.KeyboardInteractive = *target.KeyboardInteractive
+	}
+
+	return auth
+}
+
+func NormalizeRouterName(name string) string {
+	return strings.Trim(strings.ToLower(strings.TrimSpace(name)), "\n\t,")
+}
+
+// ResolveRouterTarget returns the router's connection details from the inventory, nil is returned when the inventory
+// is not provided. A router which is not in the inventory uses its name as the address to connect to, the default
+// settings and the inventory wide jump hosts chain.
+func ResolveRouterTarget(name string, inventory *RouterInventory, defaultPort int, defaultUser string, defaultAuth *sshclient.SSHAuth, defaultJump []*JumpHost) (*ResolvedTarget, error) {
+	normalized := NormalizeRouterName(name)
+	if inventory == nil {
+		// Not failing if inventory is not provided, will be using specified name as actual address to connect to
+		glog.Warningf("routers inventory is not provided, using specified router name %s as an address to connect to", normalized)
+		return nil, nil
+	}
+	jump := defaultJump
+	if inventory.ProxyJump != nil {
+		jump = inventory.ProxyJump
+	}
+	target, ok := inventory.Routers[normalized]
+	if !ok {
+		// Not failing if router is not found in the inventory, will be using specified name as actual address to connect to
+		glog.Warningf("router %s is not found in the inventory, using specified router name as an address to connect to", normalized)
+		hops, err := ResolveJumpHosts(jump, defaultUser, defaultAuth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve jump hosts for router %s with error: %+v", name, err)
+		}
+		return &ResolvedTarget{
+			Name:      normalized,
+			Address:   normalized,
+			Port:      defaultPort,
+			Username:  defaultUser,
+			Auth:      defaultAuth,
+			ProxyJump: hops,
+		}, nil
+	}
+	if target.Address == "" {
+		return nil, fmt.Errorf("address for router %s is not specified in the inventory", name)
+	}
+	if target.Port == 0 {
+		target.Port = defaultPort
+	}
+	if target.Username == "" {
+		target.Username = defaultUser
+	}
+	if target.ProxyJump != nil {
+		jump = target.ProxyJump
+	}
+	hops, err := ResolveJumpHosts(jump, defaultUser, defaultAuth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve jump hosts for router %s with error: %+v", name, err)
+	}
+	return &ResolvedTarget{
+		Name:      normalized,
+		Address:   target.Address,
+		Port:      target.Port,
+		Platform:  target.Platform,
+		Username:  target.Username,
+		Auth:      ResolveAuth(&target.TargetAuth, defaultAuth),
+		ProxyJump: hops,
+	}, nil
+}
+
+// RouterVars returns variables of the router, nil is returned when the router is not in the inventory.
+func (i *RouterInventory) RouterVars(name string) map[string]string {
+	if i == nil {
+		return nil
+	}
+	target, ok := i.Routers[NormalizeRouterName(name)]
+	if !ok {
+		return nil
+	}
+
+	return target.Vars
+}
+
+// HasKeyAuth returns true if every router of names defines public key authentication in the inventory, routers
+// which are not in the inventory do not define it.
+func (i *RouterInventory) HasKeyAuth(names []string) bool {
+	if i == nil || len(names) == 0 {
+		return false
+	}
+	for _, name := range names {
+		target, ok := i.Routers[NormalizeRouterName(name)]
+		if !ok || !target.HasKeyAuth() {
+			return false
+		}
+	}
+	return true
+}
+
+// GetRoutersInventory reads the routers' inventory file, routers' names are normalized and routers without
+// an address are skipped.
+func GetRoutersInventory(fileName string) (*RouterInventory, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open router inventory file %s with error: %+v", fileName, err)
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read router inventory file %s with error: %+v", fileName, err)
+	}
+	inventory := &RouterInventory{}
+	if err := yaml.Unmarshal(b, inventory); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal router inventory file %s with error: %+v", fileName, err)
+	}
+	normalized := &RouterInventory{
+		ProxyJump: inventory.ProxyJump,
+		Routers:   make(map[string]*RouterTarget),
+	}
+	for name, target := range inventory.Routers {
+		normName := NormalizeRouterName(name)
+		if normName == "" {
+			glog.Warningf("router with empty name is found in the inventory file %s, skipping...", fileName)
+			continue
+		}
+		if target.Address == "" {
+			glog.Warningf("router %s has empty address in the inventory file %s, skipping...", name, fileName)
+			continue
+		}
+		if target.Port == 0 {
+			target.Port = 22
+		}
+		normalized.Routers[normName] = target
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,62 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/sbezverk/routercommander/pkg/sshclient"
+)
+
+func TestResolveAuth(t *testing.T) {
+	enabled := true
+	disabled := false
+	global := &sshclient.SSHAuth{Password: "cisco123", PrivateKeyFile: "/global/key", UseAgent: true}
+
+	auth := ResolveAuth(&TargetAuth{}, global)
+	if *auth != *global {
+		t.Fatalf("expected global authentication %+v, got %+v", *global, *auth)
+	}
+	auth = ResolveAuth(&TargetAuth{PrivateKeyFile: "/router/key", SSHAgent: &disabled, KeyboardInteractive: &enabled}, global)
+	if auth.PrivateKeyFile != "/router/key" || auth.UseAgent || !auth.KeyboardInteractive || auth.Password != "cisco123" {
+		t.Fatalf("router authentication is not applied correctly: %+v", *auth)
+	}
+	if global.PrivateKeyFile != "/global/key" || !global.UseAgent {
+		t.Fatalf("global authentication must not be modified: %+v", *global)
+	}
+	global.PrivateKeyPassphrase = "secret"
+	auth = ResolveAuth(&TargetAuth{PrivateKeyFile: "/router/key"}, global)
+	if auth.PrivateKeyFile != "/router/key" || auth.PrivateKeyPassphrase != "secret" {
+		t.Fatalf("global passphrase is not applied to the router's key: %+v", *auth)
+	}
+	auth = ResolveAuth(&TargetAuth{PrivateKeyPassphrase: "router"}, global)
+	if auth.PrivateKeyFile != "/global/key" || auth.PrivateKeyPassphrase != "router" {
+		t.Fatalf("router passphrase is not applied to the global key: %+v", *auth)
+	}
+}
+
+func TestHasKeyAuth(t *testing.T) {
+	enabled := true
+	inv := &RouterInventory{Routers: map[string]*RouterTarget{
+		"r1": {TargetAuth: TargetAuth{PrivateKeyFile: "/r1/key"}},
+		"r2": {TargetAuth: TargetAuth{SSHAgent: &enabled}},
+		"r3": {},
+	}}
+	tests := []struct {
+		name   string
+		inv    *RouterInventory
+		names  []string
+		expect bool
+	}{
+		{name: "all routers with keys", inv: inv, names: []string{"r1", "r2"}, expect: true},
+		{name: "router without keys", inv: inv, names: []string{"r1", "r3"}},
+		{name: "router not in inventory", inv: inv, names: []string{"r1", "r4"}},
+		{name: "no routers", inv: inv},
+		{name: "no inventory", names: []string{"r1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.inv.HasKeyAuth(tt.names); got != tt.expect {
+				t.Fatalf("expected %t, got %t", tt.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package inventory
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/sbezverk/routercommander/pkg/sshclient"
+)
+
+// JumpHost defines a single hop of the proxy jump chain used to reach a router.
+type JumpHost struct {
+	Address    string `yaml:"address"`
+	Port       int    `yaml:"port"`
+	Username   string `yaml:"username"`
+	TargetAuth `yaml:",inline"`
+}
+
+// ParseProxyJump parses the proxy jump specification in the form of [user@]host[:port][,[user@]host[:port]],
+// hops are listed in the order they are dialed.
+func ParseProxyJump(spec string) ([]*JumpHost, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	hops := make([]*JumpHost, 0)
+	for _, h := range strings.Split(spec, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			return nil, fmt.Errorf("empty jump host in proxy jump %q", spec)
+		}
+		hop := &JumpHost{}
+		if i := strings.LastIndex(h, "@"); i != -1 {
+			hop.Username = h[:i]
+			h = h[i+1:]
+		}
+		host, p, err := net.SplitHostPort(h)
+		if err != nil {
+			// No port specified, the whole string is the host
+			host = strings.TrimSuffix(strings.TrimPrefix(h, "["), "]")
+		} else {
+			if hop.Port, err = strconv.Atoi(p); err != nil {
+				return nil, fmt.Errorf("invalid port %q of jump host %q", p, h)
+			}
+		}
+		if host == "" {
+			return nil, fmt.Errorf("invalid jump host %q in proxy jump %q", h, spec)
+		}
+		hop.Address = host
+		hops = append(hops, hop)
+	}
+
+	return hops, nil
+}
+
+// ResolveJumpHosts applies default port, user and authentication to the jump hosts
+func ResolveJumpHosts(hops []*JumpHost, defaultUser string, defaultAuth *sshclient.SSHAuth) ([]*sshclient.Hop, error) {
+	resolved := make([]*sshclient.Hop, 0, len(hops))
+	for _, hop := range hops {
+		if hop.Address == "" {
+			return nil, fmt.Errorf("address of a jump host is not specified")
+		}
+		r := &sshclient.Hop{
+			Address:  hop.Address,
+			Port:     hop.Port,
+			Username: hop.Username,
+			Auth:     ResolveAuth(&hop.TargetAuth, defaultAuth),
+		}
+		if r.Port == 0 {
+			r.Port = 22
+		}
+		if r.Username == "" {
+			r.Username = defaultUser
+		}
+		resolved = append(resolved, r)
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,114 @@
+package inventory
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sbezverk/routercommander/pkg/sshclient"
+)
+
+func TestParseProxyJump(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expect []*JumpHost
+		fail   bool
+	}{
+		{name: "empty", input: "", expect: nil},
+		{name: "host only", input: "bastion", expect: []*JumpHost{{Address: "bastion"}}},
+		{name: "user host and port", input: "admin@bastion:2222", expect: []*JumpHost{{Address: "bastion", Port: 2222, Username: "admin"}}},
+		{name: "ipv6 with port", input: "[2001:db8::1]:22", expect: []*JumpHost{{Address: "2001:db8::1", Port: 22}}},
+		{name: "ipv6 without port", input: "admin@[2001:db8::1]", expect: []*JumpHost{{Address: "2001:db8::1", Username: "admin"}}},
+		{
+			name:  "chain",
+			input: "admin@bastion1, bastion2:2022",
+			expect: []*JumpHost{
+				{Address: "bastion1", Username: "admin"},
+				{Address: "bastion2", Port: 2022},
+			},
+		},
+		{name: "invalid port", input: "bastion:ssh", fail: true},
+		{name: "empty hop", input: "bastion1,,bastion2", fail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hops, err := ParseProxyJump(tt.input)
+			if err != nil && !tt.fail {
+				t.Fatalf("supposed to succeed but failed with error: %+v", err)
+			}
+			if err == nil && tt.fail {
+				t.Fatalf("supposed to fail but succeeded")
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(hops, tt.expect) {
+				t.Fatalf("expected hops %+v, got %+v", tt.expect, hops)
+			}
+		})
+	}
+}
+
+func TestResolveRouterTargetProxyJump(t *testing.T) {
+	global := []*JumpHost{{Address: "cli-bastion"}}
+	inventory := &RouterInventory{
+		ProxyJump: []*JumpHost{{Address: "inventory-bastion", Username: "jump"}},
+		Routers: map[string]*RouterTarget{
+			"r1": {Address: "10.0.0.1"},
+			"r2": {Address: "10.0.0.2", ProxyJump: []*JumpHost{{Address: "r2-bastion1", Port: 2222}, {Address: "r2-bastion2"}}},
+			"r3": {Address: "10.0.0.3", ProxyJump: []*JumpHost{}},
+		},
+	}
+	auth := &sshclient.SSHAuth{Password: "cisco123"}
+	tests := []struct {
+		router string
+		expect []*sshclient.Hop
+	}{
+		{
+			router: "r1",
+			expect: []*sshclient.Hop{{Address: "inventory-bastion", Port: 22, Username: "jump", Auth: auth}},
+		},
+		{
+			router: "r2",
+			expect: []*sshclient.Hop{
+				{Address: "r2-bastion1", Port: 2222, Username: "cisco", Auth: auth},
+				{Address: "r2-bastion2", Port: 22, Username: "cisco", Auth: auth},
+			},
+		},
+		{
+			router: "r3",
+			expect: []*sshclient.Hop{},
+		},
+		{
+			router: "r4",
+			expect: []*sshclient.Hop{{Address: "inventory-bastion", Port: 22, Username: "jump", Auth: auth}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.router, func(t *testing.T) {
+			target, err := ResolveRouterTarget(tt.router, inventory, 22, "cisco", auth, global)
+			if err != nil {
+				t.Fatalf("failed to resolve router target with error: %+v", err)
+			}
+			if !reflect.DeepEqual(target.ProxyJump, tt.expect) {
+				t.Fatalf("expected jump hosts %+v, got %+v", tt.expect, target.ProxyJump)
+			}
+		})
+	}
+	// Without inventory wide jump hosts, the global ones are used
+	inventory.ProxyJump = nil
+	target, err := ResolveRouterTarget("r1", inventory, 22, "cisco", auth, global)
+	if err != nil {
+		t.Fatalf("failed to resolve router target with error: %+v", err)
+	}
+	if len(target.ProxyJump) != 1 || target.ProxyJump[0].Address != "cli-bastion" {
+		t.Fatalf("expected global jump host, got %+v", target.ProxyJump)
+	}
+	target, err = ResolveRouterTarget("r4", inventory, 22, "cisco", auth, global)
+	if err != nil {
+		t.Fatalf("failed to resolve router target with error: %+v", err)
+	}
+	if target.Address != "r4" || len(target.ProxyJump) != 1 || target.ProxyJump[0].Address != "cli-bastion" {
+		t.Fatalf("expected router r4 behind global jump host, got %+v", target)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/smtp"
 	"strings"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/sbezverk/routercommander/pkg/messenger"
@@ -89,6 +90,33 @@ func (em *eMessenger) Notify(fn string, b []byte) error {
 	return nil
 }
 
+func (em *eMessenger) NotifyEvent(e *messenger.Event) error {
+	if e.Type == messenger.EventRunFinished {
+		return em.Notify(e.LogFileName, e.Output)
+	}
+	host, _, _ := net.SplitHostPort(em.server)
+	ua := smtp.PlainAuth("routercommander", em.user, em.pass, host)
+	body := fmt.Sprintf("%s\ntime: %s\n", e.Message, e.Time.Format(time.RFC3339))
+	if e.Cmd != "" {
+		body += fmt.Sprintf("command: %s\n", e.Cmd)
+	}
+	if len(e.TestIDs) != 0 {
+		body += fmt.Sprintf("test ids: %v\n", e.TestIDs)
+	}
+	if len(e.Output) != 0 {
+		body += "\n" + string(e.Output) + "\n"
+	}
+	msg, err := NewMailMessage(em.to, []string{}, []string{}, e.Subject(), body, nil)
+	if err != nil {
+		return err
+	}
+	if err := smtp.SendMail(em.server, ua, em.from, em.to, msg.MarshalBytes()); err != nil {
+		return fmt.Errorf("SendMail failed with error: %+v", err)
+	}
+
+	return nil
+}
+
 func NewEmailNotifier(smtp, user, pass, from string, to string) (messenger.Notifier, error) {
 	if len(strings.Split(smtp, ":")) < 2 {
 		return nil, fmt.Errorf("server address %s must include smtp port", smtp)
@@ -0,0 +1,32 @@
+package messenger
+
+var _ Notifier = &eventFilter{}
+
+type eventFilter struct {
+	n     Notifier
+	types map[EventType]bool
+}
+
+func (f *eventFilter) Notify(fn string, b []byte) error {
+	return f.n.Notify(fn, b)
+}
+
+func (f *eventFilter) NotifyEvent(e *Event) error {
+	if !f.types[e.Type] {
+		return nil
+	}
+	return f.n.NotifyEvent(e)
+}
+
+// NewEventFilter returns a notifier passing only events of the listed types to the notifier
+func NewEventFilter(n Notifier, types []EventType) Notifier {
+	f := &eventFilter{
+		n:     n,
+		types: make(map[EventType]bool),
+	}
+	for _, t := range types {
+		f.types[t] = true
+	}
+
+	return f
+}
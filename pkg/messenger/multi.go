@@ -0,0 +1,45 @@
+package messenger
+
+import "fmt"
+
+var _ Notifier = &multiNotifier{}
+
+type multiNotifier struct {
+	notifiers []Notifier
+}
+
+func (m *multiNotifier) Notify(fn string, b []byte) error {
+	var errs []error
+	for _, n := range m.notifiers {
+		if err := n.Notify(fn, b); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("%d of %d notifiers failed: %+v", len(errs), len(m.notifiers), errs)
+	}
+
+	return nil
+}
+
+func (m *multiNotifier) NotifyEvent(e *Event) error {
+	var errs []error
+	for _, n := range m.notifiers {
+		if err := n.NotifyEvent(e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("%d of %d notifiers failed: %+v", len(errs), len(m.notifiers), errs)
+	}
+
+	return nil
+}
+
+// NewMultiNotifier returns a notifier sending the notification through all notifiers, a failure
+// of one notifier does not prevent others from sending.
+func NewMultiNotifier(notifiers ...Notifier) Notifier {
+	return &multiNotifier{
+		notifiers: notifiers,
+	}
+}
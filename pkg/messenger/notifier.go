@@ -1,5 +1,72 @@
 package messenger
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventType is the type of an event notifiers are notified about
+type EventType string
+
+const (
+	// EventReproTriggered is sent when a repro iteration triggers the failure condition
+	EventReproTriggered EventType = "repro_triggered"
+	// EventTestTriggered is sent when a command's test is triggered outside of a repro
+	EventTestTriggered EventType = "test_triggered"
+	// EventConnectionLost is sent when the connection to a router is lost during processing
+	EventConnectionLost EventType = "connection_lost"
+	// EventRunFinished is sent when processing of a router is finished, the event carries the router's log
+	EventRunFinished EventType = "run_finished"
+)
+
+// EventTypes lists all supported event types
+var EventTypes = []EventType{EventReproTriggered, EventTestTriggered, EventConnectionLost, EventRunFinished}
+
+// Event describes what happened on a router at the moment it happened.
+type Event struct {
+	Type      EventType
+	Router    string
+	Time      time.Time
+	Iteration int
+	Cmd       string
+	TestIDs   []int
+	Message   string
+	// Output is the output of the triggering command, for EventRunFinished it is the router's log
+	Output      []byte
+	LogFileName string
+}
+
+// Subject returns a short description of the event
+func (e *Event) Subject() string {
+	return fmt.Sprintf("routercommander: %s on router %s", strings.ReplaceAll(string(e.Type), "_", " "), e.Router)
+}
+
 type Notifier interface {
 	Notify(string, []byte) error
+	NotifyEvent(*Event) error
+}
+
+// ParseEventTypes parses comma separated list of event types
+func ParseEventTypes(s string) ([]EventType, error) {
+	types := make([]EventType, 0)
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		found := false
+		for _, et := range EventTypes {
+			if string(et) == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown event type %q, supported event types: %v", t, EventTypes)
+		}
+		types = append(types, EventType(t))
+	}
+
+	return types, nil
 }
@@ -0,0 +1,57 @@
+package messenger
+
+import (
+	"fmt"
+	"testing"
+)
+
+type testNotifier struct {
+	events []*Event
+	fail   bool
+}
+
+func (tn *testNotifier) Notify(string, []byte) error {
+	return nil
+}
+
+func (tn *testNotifier) NotifyEvent(e *Event) error {
+	if tn.fail {
+		return fmt.Errorf("failed to notify")
+	}
+	tn.events = append(tn.events, e)
+	return nil
+}
+
+func TestEventFilter(t *testing.T) {
+	types, err := ParseEventTypes("repro_triggered, connection_lost")
+	if err != nil {
+		t.Fatalf("failed to parse event types with error: %+v", err)
+	}
+	if _, err := ParseEventTypes("repro_triggered,router_rebooted"); err == nil {
+		t.Fatal("expected to fail on unknown event type")
+	}
+	tn := &testNotifier{}
+	n := NewEventFilter(tn, types)
+	for _, et := range EventTypes {
+		if err := n.NotifyEvent(&Event{Type: et, Router: "r1"}); err != nil {
+			t.Fatalf("failed to notify with error: %+v", err)
+		}
+	}
+	if len(tn.events) != 2 || tn.events[0].Type != EventReproTriggered || tn.events[1].Type != EventConnectionLost {
+		t.Fatalf("unexpected events passed by the filter: %+v", tn.events)
+	}
+	if s := tn.events[0].Subject(); s != "routercommander: repro triggered on router r1" {
+		t.Fatalf("unexpected subject: %s", s)
+	}
+}
+
+func TestMultiNotifier(t *testing.T) {
+	ok := &testNotifier{}
+	n := NewMultiNotifier(&testNotifier{fail: true}, ok)
+	if err := n.NotifyEvent(&Event{Type: EventTestTriggered}); err == nil {
+		t.Fatal("expected the failure of the first notifier to be returned")
+	}
+	if len(ok.events) != 1 {
+		t.Fatal("failure of the first notifier prevented the second one from sending")
+	}
+}
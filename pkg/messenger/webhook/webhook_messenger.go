@@ -0,0 +1,259 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/sbezverk/routercommander/pkg/messenger"
+	"github.com/sbezverk/routercommander/pkg/types"
+)
+
+// Supported formats of the webhook's body
+const (
+	FormatGeneric = "generic"
+	FormatSlack   = "slack"
+	FormatTeams   = "teams"
+)
+
+const (
+	// Number of the log's last lines included in the message
+	tailLines = 20
+	// Maximum size of the log's tail, chat services limit the size of messages
+	maxTailSize = 3000
+)
+
+// DefaultTemplate is the body of generic webhooks when no template is provided.
+const DefaultTemplate = `{"event": {{ json .Event }}, "router": {{ json .Router }}, "title": {{ json .Title }}, "text": {{ json .Text }}, "file_name": {{ json .FileName }}, "log_url": {{ json .LogURL }}, "commands": {{ .Commands }}, "size": {{ .Size }}}`
+
+// Message is the data available to the body template of generic webhooks.
+type Message struct {
+	Event     string
+	Router    string
+	Iteration int
+	Cmd       string
+	TestIDs   []int
+	Title     string
+	Text      string
+	FileName  string
+	LogURL    string
+	Commands  int
+	Size      int
+	Tail      string
+	Log       []byte
+}
+
+var _ messenger.Notifier = &wMessenger{}
+
+type wMessenger struct {
+	url     string
+	format  string
+	headers http.Header
+	tmpl    *template.Template
+	logURL  string
+	client  *http.Client
+}
+
+func (wm *wMessenger) Notify(fn string, b []byte) error {
+	return wm.post(newMessage(fn, b, wm.logURL))
+}
+
+func (wm *wMessenger) NotifyEvent(e *messenger.Event) error {
+	var m *Message
+	if e.Type == messenger.EventRunFinished {
+		m = newMessage(e.LogFileName, e.Output, wm.logURL)
+	} else {
+		m = &Message{
+			Title:    e.Subject(),
+			Text:     e.Subject() + ": " + e.Message,
+			FileName: e.LogFileName,
+			Size:     len(e.Output),
+			Tail:     tail(e.Output),
+			Log:      e.Output,
+		}
+		if wm.logURL != "" && e.LogFileName != "" {
+			m.LogURL = strings.TrimSuffix(wm.logURL, "/") + "/" + filepath.Base(e.LogFileName)
+		}
+	}
+	m.Event = string(e.Type)
+	m.Router = e.Router
+	m.Iteration = e.Iteration
+	m.Cmd = e.Cmd
+	m.TestIDs = e.TestIDs
+
+	return wm.post(m)
+}
+
+func (wm *wMessenger) post(m *Message) error {
+	body, err := wm.body(m)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, wm.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request with error: %+v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range wm.headers {
+		req.Header[k] = v
+	}
+	resp, err := wm.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed with error: %+v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		rb, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned status %s: %s", resp.Status, strings.TrimSpace(string(rb)))
+	}
+
+	return nil
+}
+
+func (wm *wMessenger) body(m *Message) ([]byte, error) {
+	var payload interface{}
+	switch wm.format {
+	case FormatSlack:
+		text := m.Text
+		if m.LogURL != "" {
+			text += fmt.Sprintf("\n<%s|%s>", m.LogURL, m.FileName)
+		}
+		if m.Tail != "" {
+			text += "\n```\n" + m.Tail + "\n```"
+		}
+		payload = map[string]interface{}{
+			"text": text,
+		}
+	case FormatTeams:
+		card := map[string]interface{}{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  m.Title,
+			"title":    m.Title,
+			"text":     m.Text,
+		}
+		if m.Tail != "" {
+			card["text"] = m.Text + "\n\n```\n" + m.Tail + "\n```"
+		}
+		if m.LogURL != "" {
+			card["potentialAction"] = []interface{}{
+				map[string]interface{}{
+					"@type": "OpenUri",
+					"name":  "Open log",
+					"targets": []interface{}{
+						map[string]interface{}{"os": "default", "uri": m.LogURL},
+					},
+				},
+			}
+		}
+		payload = card
+	default:
+		var buf bytes.Buffer
+		if err := wm.tmpl.Execute(&buf, m); err != nil {
+			return nil, fmt.Errorf("failed to execute webhook body template with error: %+v", err)
+		}
+		if !json.Valid(buf.Bytes()) {
+			return nil, fmt.Errorf("webhook body template produced invalid JSON: %s", buf.String())
+		}
+		return buf.Bytes(), nil
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook body with error: %+v", err)
+	}
+
+	return b, nil
+}
+
+func newMessage(fn string, b []byte, logURL string) *Message {
+	m := &Message{
+		Event:    string(messenger.EventRunFinished),
+		Title:    fmt.Sprintf("routercommander log %s", fn),
+		FileName: fn,
+		Commands: bytes.Count(b, []byte(types.CommandMarker)),
+		Size:     len(b),
+		Tail:     tail(b),
+		Log:      b,
+	}
+	if logURL != "" {
+		m.LogURL = strings.TrimSuffix(logURL, "/") + "/" + filepath.Base(fn)
+	}
+	m.Text = fmt.Sprintf("routercommander finished, log %s: %d command(s), %d bytes", fn, m.Commands, m.Size)
+
+	return m
+}
+
+// tail returns the last lines of the log
+func tail(b []byte) string {
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	if len(lines) > tailLines {
+		lines = lines[len(lines)-tailLines:]
+	}
+	s := strings.Join(lines, "\n")
+	if len(s) > maxTailSize {
+		s = s[len(s)-maxTailSize:]
+	}
+	return s
+}
+
+// NewWebhookNotifier returns a notifier posting a message to the webhook url, headers are in the form of "Name: value",
+// bodyTemplate is a text/template producing the JSON body of generic webhooks, when empty DefaultTemplate is used.
+// When logURL is not empty, the link to the log is built by appending the log's file name to it.
+func NewWebhookNotifier(url string, format string, headers []string, bodyTemplate string, logURL string) (messenger.Notifier, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook url cannot be empty")
+	}
+	if format == "" {
+		format = FormatGeneric
+	}
+	wm := &wMessenger{
+		url:     url,
+		format:  format,
+		headers: make(http.Header),
+		logURL:  logURL,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+	switch format {
+	case FormatGeneric:
+		if bodyTemplate == "" {
+			bodyTemplate = DefaultTemplate
+		}
+		t, err := template.New("webhook").Funcs(template.FuncMap{
+			"json": func(v interface{}) (string, error) {
+				b, err := json.Marshal(v)
+				return string(b), err
+			},
+			"base64": func(b []byte) string {
+				return base64.StdEncoding.EncodeToString(b)
+			},
+		}).Parse(bodyTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse webhook body template with error: %+v", err)
+		}
+		wm.tmpl = t
+	case FormatSlack, FormatTeams:
+		if bodyTemplate != "" {
+			return nil, fmt.Errorf("body template is not supported by %s webhook format", format)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported webhook format %q, supported formats: %s, %s, %s", format, FormatGeneric, FormatSlack, FormatTeams)
+	}
+	for _, h := range headers {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid webhook header %q, expected format is \"Name: value\"", h)
+		}
+		wm.headers.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+
+	glog.Infof("webhook notifier has been instantiated successfully.")
+	return wm, nil
+}
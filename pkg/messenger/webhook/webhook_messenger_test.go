@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sbezverk/routercommander/pkg/messenger"
+)
+
+const testLog = `=========> show version
+Cisco IOS XR Software, Version 7.5.2
+
+
+=========> show platform
+0/RP0/CPU0        NCS-55A1-24H(Active)      IOS XR RUN        NSHUT
+
+
+`
+
+func TestWebhookNotifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		headers  []string
+		template string
+		logURL   string
+		expect   map[string]interface{}
+		header   string
+	}{
+		{
+			name:    "generic default template",
+			format:  FormatGeneric,
+			headers: []string{"Authorization: Bearer secret"},
+			logURL:  "https://logs.example.com/rc/",
+			expect: map[string]interface{}{
+				"file_name": "r1.log",
+				"log_url":   "https://logs.example.com/rc/r1.log",
+				"commands":  float64(2),
+			},
+			header: "Bearer secret",
+		},
+		{
+			name:     "generic custom template",
+			format:   FormatGeneric,
+			template: `{"msg": {{ json .Text }}, "attachment": {{ json (base64 .Log) }}}`,
+			expect: map[string]interface{}{
+				"msg": "routercommander finished, log r1.log: 2 command(s), 158 bytes",
+			},
+		},
+		{
+			name:   "slack",
+			format: FormatSlack,
+			logURL: "https://logs.example.com/rc",
+		},
+		{
+			name:   "teams",
+			format: FormatTeams,
+			logURL: "https://logs.example.com/rc",
+			expect: map[string]interface{}{
+				"@type": "MessageCard",
+				"title": "routercommander log r1.log",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]interface{}
+			var gotHeader string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotHeader = r.Header.Get("Authorization")
+				b, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(b, &got); err != nil {
+					t.Errorf("webhook body is not a valid JSON: %s", string(b))
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+			n, err := NewWebhookNotifier(srv.URL, tt.format, tt.headers, tt.template, tt.logURL)
+			if err != nil {
+				t.Fatalf("failed to create webhook notifier with error: %+v", err)
+			}
+			if err := n.Notify("r1.log", []byte(testLog)); err != nil {
+				t.Fatalf("failed to notify with error: %+v", err)
+			}
+			for k, v := range tt.expect {
+				if got[k] != v {
+					t.Fatalf("expected %q to be %v but got %v", k, v, got[k])
+				}
+			}
+			if tt.format == FormatSlack {
+				text, _ := got["text"].(string)
+				if !strings.Contains(text, "<https://logs.example.com/rc/r1.log|r1.log>") || !strings.Contains(text, "NCS-55A1-24H") {
+					t.Fatalf("unexpected slack text: %s", text)
+				}
+			}
+			if gotHeader != tt.header {
+				t.Fatalf("expected authorization header %q but got %q", tt.header, gotHeader)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifierErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	n, err := NewWebhookNotifier(srv.URL, FormatSlack, nil, "", "")
+	if err != nil {
+		t.Fatalf("failed to create webhook notifier with error: %+v", err)
+	}
+	if err := n.Notify("r1.log", []byte(testLog)); err == nil || !strings.Contains(err.Error(), "invalid_token") {
+		t.Fatalf("expected the error returned by the webhook but got: %+v", err)
+	}
+	n, err = NewWebhookNotifier(srv.URL, FormatGeneric, nil, `{"text": {{ .Text }}}`, "")
+	if err != nil {
+		t.Fatalf("failed to create webhook notifier with error: %+v", err)
+	}
+	if err := n.Notify("r1.log", []byte(testLog)); err == nil || !strings.Contains(err.Error(), "invalid JSON") {
+		t.Fatalf("expected invalid JSON error but got: %+v", err)
+	}
+	for _, c := range []struct {
+		format   string
+		headers  []string
+		template string
+	}{
+		{format: "discord"},
+		{format: FormatGeneric, headers: []string{"no separator"}},
+		{format: FormatSlack, template: `{}`},
+		{format: FormatGeneric, template: `{{ .Text`},
+	} {
+		if _, err := NewWebhookNotifier(srv.URL, c.format, c.headers, c.template, ""); err == nil {
+			t.Fatalf("expected to fail for format %q headers %v template %q", c.format, c.headers, c.template)
+		}
+	}
+}
+
+func TestWebhookNotifyEvent(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("webhook body is not a valid JSON: %s", string(b))
+		}
+	}))
+	defer srv.Close()
+	n, err := NewWebhookNotifier(srv.URL, FormatGeneric, nil, "", "https://logs.example.com")
+	if err != nil {
+		t.Fatalf("failed to create webhook notifier with error: %+v", err)
+	}
+	err = n.NotifyEvent(&messenger.Event{
+		Type:        messenger.EventReproTriggered,
+		Router:      "r1",
+		Cmd:         "show cef drops",
+		TestIDs:     []int{7},
+		Message:     "command \"show cef drops\" triggered test ids: [7]",
+		Output:      []byte("Discard drops packets : 500\n"),
+		LogFileName: "/tmp/r1_2024-01-02_03-04-05.log",
+	})
+	if err != nil {
+		t.Fatalf("failed to notify event with error: %+v", err)
+	}
+	expect := map[string]interface{}{
+		"event":   "repro_triggered",
+		"router":  "r1",
+		"title":   "routercommander: repro triggered on router r1",
+		"log_url": "https://logs.example.com/r1_2024-01-02_03-04-05.log",
+	}
+	for k, v := range expect {
+		if got[k] != v {
+			t.Fatalf("expected %q to be %v but got %v", k, v, got[k])
+		}
+	}
+}
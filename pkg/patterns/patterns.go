@@ -44,7 +44,13 @@ var SysadminPrompt = regexp.MustCompile(`(?m)sysadmin-vm:[0-1]_RP[0-1]#(\n|$|\s*
 
 var RunShellPrompt = regexp.MustCompile(`(?m)\[[a-zA-Z0-9_\-.]*:~\]\$`)
 
-var NXOSPrompt = regexp.MustCompile(`(?m)[0-9A-Za-z._-]+#\s*$`)
+var NXOSPrompt = regexp.MustCompile(`(?m)[0-9A-Za-z._-]+(\([0-9A-Za-z._/-]+\))?#\s*$`)
+
+var IOSXEPrompt = regexp.MustCompile(`(?m)^[0-9A-Za-z._-]+(\([0-9A-Za-z._/-]+\))?#\s*$`)
+
+var JunosPrompt = regexp.MustCompile(`(?m)^([0-9A-Za-z._-]+@)?[0-9A-Za-z._-]+[>#]\s*$`)
+
+var EOSPrompt = regexp.MustCompile(`(?m)^[0-9A-Za-z._-]+(\([0-9A-Za-z._/-]+\))?[>#]\s*$`)
 
 // // Regular expressions used for parsing  show route  output
 // var IPv4 = regexp.MustCompile(`(?m)(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\,\s+from`)
@@ -0,0 +1,22 @@
+package platform
+
+import (
+	"regexp"
+
+	"github.com/sbezverk/routercommander/pkg/patterns"
+)
+
+// EOS configuration is applied in a configuration session, the commit leaves the session
+var eos = &driver{
+	name:    EOS,
+	prompts: []*regexp.Regexp{patterns.EOSPrompt},
+	setup:   []string{"terminal width 256"},
+	paging:  "terminal length 0",
+	config: &ConfigMode{
+		Enter:        "configure session",
+		Commit:       "commit",
+		Abort:        []string{"abort"},
+		CommitFailed: regexp.MustCompile(`(?m)^\s*%\s*(Failed|Error)`),
+	},
+	errors: regexp.MustCompile(`(?m)^\s*%\s*(Invalid input|Incomplete command|Ambiguous command|Unrecognized command).*$`),
+}
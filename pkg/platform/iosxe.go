@@ -0,0 +1,21 @@
+package platform
+
+import (
+	"regexp"
+
+	"github.com/sbezverk/routercommander/pkg/patterns"
+)
+
+// IOS-XE applies configuration lines as they are entered, there is nothing to commit or to roll back
+var iosxe = &driver{
+	name:    IOSXE,
+	prompts: []*regexp.Regexp{patterns.IOSXEPrompt},
+	setup:   []string{"terminal width 256"},
+	paging:  "terminal length 0",
+	config: &ConfigMode{
+		Enter: "configure terminal",
+		Abort: []string{"end"},
+		End:   "end",
+	},
+	errors: regexp.MustCompile(`(?m)^\s*%\s*(Invalid input|Incomplete command|Ambiguous command|Unknown command).*$`),
+}
@@ -0,0 +1,75 @@
+package platform
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/sbezverk/routercommander/pkg/patterns"
+)
+
+// iosxr is the default driver, its prompts include the NX-OS prompt, so routers without the platform in the
+// inventory are not limited to IOS-XR
+var iosxr = &driver{
+	name:           IOSXR,
+	prompts:        []*regexp.Regexp{patterns.Prompt, patterns.SysadminPrompt, patterns.RunShellPrompt, patterns.NXOSPrompt},
+	setup:          []string{"terminal w 256"},
+	paging:         "terminal l 0",
+	locationsCmd:   "show platform",
+	parseLocations: parseShowPlatform,
+	config: &ConfigMode{
+		Enter:  "configure terminal",
+		Commit: "commit",
+		CommitConfirmed: func(seconds int) string {
+			return fmt.Sprintf("commit confirmed %d", seconds)
+		},
+		Abort:        []string{"abort"},
+		End:          "end",
+		ShowFailed:   "show configuration failed",
+		Rollback:     []string{"rollback configuration last 1"},
+		CommitFailed: regexp.MustCompile(`(?m)%\s*Failed to commit`),
+		NoChanges:    regexp.MustCompile(`(?m)No configuration changes to commit`),
+	},
+	errors: regexp.MustCompile(`(?m)^\s*%\s*(Invalid input|Incomplete command|Ambiguous command).*$`),
+}
+
+// parseShowPlatform returns RPs and LCs in the IOS XR RUN state found in the output of show platform
+func parseShowPlatform(b []byte) (*Locations, error) {
+	l := &Locations{}
+	rd := bufio.NewReader(bytes.NewReader(b))
+	done := false
+	for !done {
+		ln, err := rd.ReadBytes('\n')
+		if err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			done = true
+		}
+		isRP := patterns.RP.Find(ln) != nil
+		if !isRP && patterns.LC.Find(ln) == nil {
+			continue
+		}
+		parts := patterns.SubStringSeparator.Split(string(ln), -1)
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("invalid line %s in show platform", string(ln))
+		}
+		loc := strings.Trim(parts[0], " \t\n,")
+		if !isRP {
+			l.LCs = append(l.LCs, loc)
+			continue
+		}
+		l.RPs = append(l.RPs, loc)
+		if patterns.ActiveRP.Find([]byte(parts[1])) != nil {
+			l.ActiveRP = loc
+		}
+	}
+	if len(l.RPs) == 0 {
+		return nil, fmt.Errorf("no RP found")
+	}
+
+	return l, nil
+}
@@ -1,16 +1,16 @@
-package types
+package platform
 
 import (
 	"reflect"
 	"testing"
 )
 
-func TestPopulatePlatformInfo(t *testing.T) {
+func TestParseShowPlatform(t *testing.T) {
 	tests := []struct {
-		name     string
-		input    []byte
-		platform *platform
-		fail     bool
+		name      string
+		input     []byte
+		locations *Locations
+		fail      bool
 	}{
 		{
 			name: "asr9k",
@@ -22,29 +22,10 @@ Node            Type                      State            Config State
 0/0/CPU0        A9K-8X100GE-TR            IOS XR RUN       PWR,NSHUT,MON
 0/2/CPU0        A9K-8X100GE-TR            IOS XR RUN       PWR,NSHUT,MON
 `),
-			platform: &platform{
-				rps: &rps{
-					rps: map[string]*rp{
-						"0/RSP0/CPU0": {
-							isActive: true,
-							location: "0/RSP0/CPU0",
-						},
-						"0/RSP1/CPU0": {
-							isActive: false,
-							location: "0/RSP1/CPU0",
-						},
-					},
-				},
-				lcs: &lcs{
-					lcs: map[string]*lc{
-						"0/0/CPU0": {
-							location: "0/0/CPU0",
-						},
-						"0/2/CPU0": {
-							location: "0/2/CPU0",
-						},
-					},
-				},
+			locations: &Locations{
+				RPs:      []string{"0/RSP0/CPU0", "0/RSP1/CPU0"},
+				ActiveRP: "0/RSP0/CPU0",
+				LCs:      []string{"0/0/CPU0", "0/2/CPU0"},
 			},
 		},
 		{
@@ -93,38 +74,10 @@ Node              Type                       State             Config state
 0/SC0             NC55-SC                    OPERATIONAL       NSHUT
 0/SC1             NC55-SC                    OPERATIONAL       NSHUT
 `),
-			platform: &platform{
-				rps: &rps{
-					rps: map[string]*rp{
-						"0/RP0/CPU0": {
-							isActive: true,
-							location: "0/RP0/CPU0",
-						},
-						"0/RP1/CPU0": {
-							isActive: false,
-							location: "0/RP1/CPU0",
-						},
-					},
-				},
-				lcs: &lcs{
-					lcs: map[string]*lc{
-						"0/0/CPU0": {
-							location: "0/0/CPU0",
-						},
-						"0/1/CPU0": {
-							location: "0/1/CPU0",
-						},
-						"0/2/CPU0": {
-							location: "0/2/CPU0",
-						},
-						"0/3/CPU0": {
-							location: "0/3/CPU0",
-						},
-						"0/4/CPU0": {
-							location: "0/4/CPU0",
-						},
-					},
-				},
+			locations: &Locations{
+				LCs:      []string{"0/0/CPU0", "0/1/CPU0", "0/2/CPU0", "0/3/CPU0", "0/4/CPU0"},
+				RPs:      []string{"0/RP0/CPU0", "0/RP1/CPU0"},
+				ActiveRP: "0/RP0/CPU0",
 			},
 		},
 		{
@@ -141,23 +94,16 @@ Node              Type                       State             Config state
 0/FT4             FAN-1RU-PI               OPERATIONAL              NSHUT
 0/FT5             FAN-1RU-PI               OPERATIONAL              NSHUT
 `),
-			platform: &platform{
-				rps: &rps{
-					rps: map[string]*rp{
-						"0/RP0/CPU0": {
-							isActive: true,
-							location: "0/RP0/CPU0",
-						},
-					},
-				},
-				lcs: nil,
+			locations: &Locations{
+				RPs:      []string{"0/RP0/CPU0"},
+				ActiveRP: "0/RP0/CPU0",
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			p, err := populatePlatformInfo(tt.input)
+			l, err := parseShowPlatform(tt.input)
 			if err != nil && !tt.fail {
 				t.Fatalf("test suppoed to succeed but failed with error: %+v", err)
 			}
@@ -167,8 +113,8 @@ Node              Type                       State             Config state
 			if err != nil {
 				return
 			}
-			if !reflect.DeepEqual(p, tt.platform) {
-				t.Fatalf("computed and expected platforms do not match")
+			if !reflect.DeepEqual(l, tt.locations) {
+				t.Fatalf("computed and expected locations do not match, computed: %+v", l)
 			}
 		})
 	}
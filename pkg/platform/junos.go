@@ -0,0 +1,28 @@
+package platform
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/sbezverk/routercommander/pkg/patterns"
+)
+
+var junos = &driver{
+	name:    Junos,
+	prompts: []*regexp.Regexp{patterns.JunosPrompt},
+	setup:   []string{"set cli screen-width 1024"},
+	paging:  "set cli screen-length 0",
+	config: &ConfigMode{
+		Enter:  "configure",
+		Commit: "commit",
+		CommitConfirmed: func(seconds int) string {
+			// Junos counts the confirmation time in minutes
+			return fmt.Sprintf("commit confirmed %d", (seconds+59)/60)
+		},
+		Abort:        []string{"rollback 0", "exit configuration-mode"},
+		End:          "exit configuration-mode",
+		Rollback:     []string{"configure", "rollback 1", "commit", "exit configuration-mode"},
+		CommitFailed: regexp.MustCompile(`(?m)^\s*error: (commit failed|configuration check-out failed)`),
+	},
+	errors: regexp.MustCompile(`(?m)^\s*(unknown command\.|syntax error.*|error: .*)$`),
+}
@@ -0,0 +1,68 @@
+package platform
+
+// Locations are route processors and line cards of the router.
+type Locations struct {
+	RPs      []string
+	ActiveRP string
+	LCs      []string
+}
+
+// IsExisting returns true if the location is one of the router's route processors or line cards.
+func (l *Locations) IsExisting(location string) bool {
+	if l == nil {
+		return false
+	}
+	for _, loc := range l.RPs {
+		if loc == location {
+			return true
+		}
+	}
+	for _, loc := range l.LCs {
+		if loc == location {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllLCs returns all line cards, on a fixed platform without line cards RPs are returned.
+func (l *Locations) AllLCs() []string {
+	if l == nil {
+		return nil
+	}
+	if len(l.LCs) == 0 {
+		return l.AllRPs()
+	}
+
+	return append([]string{}, l.LCs...)
+}
+
+// AllRPs returns all route processors.
+func (l *Locations) AllRPs() []string {
+	if l == nil || len(l.RPs) == 0 {
+		return nil
+	}
+
+	return append([]string{}, l.RPs...)
+}
+
+// All returns all route processors followed by all line cards.
+func (l *Locations) All() []string {
+	locations := make([]string, 0)
+	if l == nil {
+		return locations
+	}
+	locations = append(locations, l.RPs...)
+	locations = append(locations, l.LCs...)
+
+	return locations
+}
+
+// Active returns the active route processor.
+func (l *Locations) Active() string {
+	if l == nil {
+		return ""
+	}
+	return l.ActiveRP
+}
@@ -0,0 +1,21 @@
+package platform
+
+import (
+	"regexp"
+
+	"github.com/sbezverk/routercommander/pkg/patterns"
+)
+
+// NX-OS applies configuration lines as they are entered, there is nothing to commit or to roll back
+var nxos = &driver{
+	name:    NXOS,
+	prompts: []*regexp.Regexp{patterns.NXOSPrompt},
+	setup:   []string{"terminal width 256"},
+	paging:  "terminal length 0",
+	config: &ConfigMode{
+		Enter: "configure terminal",
+		Abort: []string{"end"},
+		End:   "end",
+	},
+	errors: regexp.MustCompile(`(?m)^\s*%\s*(Invalid (command|input)|Incomplete command|Ambiguous command).*$`),
+}
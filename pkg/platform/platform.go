@@ -0,0 +1,174 @@
+package platform
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// Names of supported platforms as used by the inventory
+const (
+	IOSXR = "iosxr"
+	IOSXE = "iosxe"
+	NXOS  = "nxos"
+	Junos = "junos"
+	EOS   = "eos"
+)
+
+// Platform is the driver of a router's operating system, it describes how to talk to the router's CLI.
+type Platform interface {
+	// Name returns the name of the platform
+	Name() string
+	// Prompts returns patterns matching the router's prompts in all modes
+	Prompts() []*regexp.Regexp
+	// SessionSetup returns commands preparing the session, they are sent before the paging is disabled
+	SessionSetup() []string
+	// DisablePaging returns the command disabling the paging of the output
+	DisablePaging() string
+	// LocationsCommand returns the command discovering the router's locations, empty when the platform
+	// does not support locations
+	LocationsCommand() string
+	// ParseLocations parses the output of the locations command
+	ParseLocations(b []byte) (*Locations, error)
+	// ConfigMode returns commands of the configuration mode
+	ConfigMode() *ConfigMode
+	// FindError returns the line of the output reporting the command was rejected by the router,
+	// nil is returned when the command was accepted
+	FindError(b []byte) []byte
+}
+
+// ConfigMode defines how the configuration is applied on the platform.
+type ConfigMode struct {
+	// Enter enters the configuration mode
+	Enter string
+	// Commit commits the configuration, empty when configuration lines are applied as they are entered
+	Commit string
+	// CommitConfirmed returns the command committing the configuration which is rolled back automatically
+	// when it is not confirmed in the number of seconds, nil when commit confirmed is not supported
+	CommitConfirmed func(seconds int) string
+	// Abort discards the configuration which is not committed yet and leaves the configuration mode
+	Abort []string
+	// End leaves the configuration mode after the commit, empty when the commit leaves it
+	End string
+	// ShowFailed shows the reason of the failed commit, empty when the commit's output shows it
+	ShowFailed string
+	// Rollback rolls back the last committed configuration from the exec mode
+	Rollback []string
+	// CommitFailed matches the output of the failed commit
+	CommitFailed *regexp.Regexp
+	// NoChanges matches the output of the commit which has nothing to commit, nil when the platform confirms
+	// the commit confirmed without changes
+	NoChanges *regexp.Regexp
+}
+
+var _ Platform = &driver{}
+
+// driver is a platform defined by its CLI's properties
+type driver struct {
+	name           string
+	prompts        []*regexp.Regexp
+	setup          []string
+	paging         string
+	locationsCmd   string
+	parseLocations func(b []byte) (*Locations, error)
+	config         *ConfigMode
+	errors         *regexp.Regexp
+}
+
+func (d *driver) Name() string {
+	return d.name
+}
+
+func (d *driver) Prompts() []*regexp.Regexp {
+	return d.prompts
+}
+
+func (d *driver) SessionSetup() []string {
+	return d.setup
+}
+
+func (d *driver) DisablePaging() string {
+	return d.paging
+}
+
+func (d *driver) LocationsCommand() string {
+	return d.locationsCmd
+}
+
+func (d *driver) ParseLocations(b []byte) (*Locations, error) {
+	if d.parseLocations == nil {
+		return nil, fmt.Errorf("platform %s does not support locations", d.name)
+	}
+	return d.parseLocations(b)
+}
+
+func (d *driver) ConfigMode() *ConfigMode {
+	return d.config
+}
+
+func (d *driver) FindError(b []byte) []byte {
+	return d.errors.Find(b)
+}
+
+var (
+	drivers = map[string]Platform{
+		IOSXR: iosxr,
+		IOSXE: iosxe,
+		NXOS:  nxos,
+		Junos: junos,
+		EOS:   eos,
+	}
+	aliases = map[string]string{
+		"":       IOSXR,
+		"xr":     IOSXR,
+		"xe":     IOSXE,
+		"ios":    IOSXE,
+		"nx-os":  NXOS,
+		"arista": EOS,
+	}
+)
+
+// Get returns the driver of the platform, the name is case insensitive, IOS-XR is returned for the empty name.
+// The default driver is returned for an unknown platform, so free-form platforms of inventories keep working.
+func Get(name string) Platform {
+	n := strings.ToLower(strings.TrimSpace(name))
+	if a, ok := aliases[n]; ok {
+		n = a
+	}
+	d, ok := drivers[n]
+	if !ok {
+		glog.Warningf("unsupported platform %q, using the default platform %s, supported platforms: %s", name, Default().Name(), strings.Join(Names(), ", "))
+		return Default()
+	}
+
+	return d
+}
+
+// Default returns the driver used when the router's platform is not specified.
+func Default() Platform {
+	return iosxr
+}
+
+// Names returns names of supported platforms.
+func Names() []string {
+	names := make([]string, 0, len(drivers))
+	for n := range drivers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// All returns drivers of all supported platforms.
+func All() []Platform {
+	all := make([]Platform, 0, len(drivers))
+	for _, n := range Names() {
+		all = append(all, drivers[n])
+	}
+
+	return all
+}
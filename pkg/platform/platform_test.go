@@ -0,0 +1,106 @@
+package platform
+
+import (
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect string
+	}{
+		{name: "", expect: IOSXR},
+		{name: "XR", expect: IOSXR},
+		{name: " iosxe ", expect: IOSXE},
+		{name: "NX-OS", expect: NXOS},
+		{name: "junos", expect: Junos},
+		{name: "arista", expect: EOS},
+		{name: "vrp", expect: IOSXR},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if p := Get(tt.name); p.Name() != tt.expect {
+				t.Fatalf("expected platform %s, got %s", tt.expect, p.Name())
+			}
+		})
+	}
+}
+
+func TestPrompts(t *testing.T) {
+	tests := []struct {
+		platform string
+		prompts  []string
+		output   string
+	}{
+		{
+			platform: IOSXR,
+			prompts:  []string{"RP/0/RP0/CPU0:r1#", "RP/0/RSP0/CPU0:r1(config)#", "sysadmin-vm:0_RP0#", "n9k-1#"},
+			output:   "Cisco IOS XR Software, Version 7.5.2\n",
+		},
+		{
+			platform: IOSXE,
+			prompts:  []string{"r1#", "r1(config)#", "r1(config-if)#"},
+			output:   "Cisco IOS XE Software, Version 17.09.04a\n",
+		},
+		{
+			platform: NXOS,
+			prompts:  []string{"n9k-1#", "n9k-1(config)#", "n9k-1(config-if)# "},
+			output:   "  NXOS: version 10.2(5)\n",
+		},
+		{
+			platform: Junos,
+			prompts:  []string{"admin@mx1>", "admin@mx1# ", "mx1>"},
+			output:   "Junos: 22.4R1.10\n",
+		},
+		{
+			platform: EOS,
+			prompts:  []string{"leaf1>", "leaf1#", "leaf1(config-s-sess1)#"},
+			output:   "Software image version: 4.30.1F\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			p := Get(tt.platform)
+			match := func(s string) bool {
+				for _, re := range p.Prompts() {
+					if re.MatchString(s) {
+						return true
+					}
+				}
+				return false
+			}
+			for _, prompt := range tt.prompts {
+				if !match(tt.output + prompt) {
+					t.Fatalf("prompt %q is not matched", prompt)
+				}
+			}
+			if match(tt.output) {
+				t.Fatalf("output %q is matched as a prompt", tt.output)
+			}
+		})
+	}
+}
+
+func TestFindError(t *testing.T) {
+	tests := []struct {
+		platform string
+		output   string
+		expect   string
+	}{
+		{platform: IOSXR, output: "                ^\n% Invalid input detected at '^' marker.\n", expect: "% Invalid input detected at '^' marker."},
+		{platform: IOSXR, output: "Cisco IOS XR Software, Version 7.5.2\n"},
+		{platform: IOSXE, output: "% Incomplete command.\n", expect: "% Incomplete command."},
+		{platform: NXOS, output: "% Invalid command at '^' marker.\n", expect: "% Invalid command at '^' marker."},
+		{platform: Junos, output: "                 ^\nunknown command.\n", expect: "unknown command."},
+		{platform: Junos, output: "Physical interface: xe-0/0/0, Enabled, Physical link is Up\n"},
+		{platform: EOS, output: "% Invalid input\n", expect: "% Invalid input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			p := Get(tt.platform)
+			if found := string(p.FindError([]byte(tt.output))); found != tt.expect {
+				t.Fatalf("expected error %q, got %q", tt.expect, found)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package results
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// FormatJSON stores all records of a router in a single JSON document, records are written as they are recorded
+	// and the document is completed when the recorder is closed.
+	FormatJSON = "json"
+	// FormatJSONLines stores every record as a separate JSON line as soon as it is recorded.
+	FormatJSONLines = "jsonl"
+)
+
+// Record is a machine readable representation of a single command execution.
+type Record struct {
+	Router     string    `json:"router"`
+	Command    string    `json:"command"`
+	Location   string    `json:"location,omitempty"`
+	Iteration  int       `json:"iteration"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	DurationMs int64     `json:"duration_ms"`
+	Output     string    `json:"output"`
+	// Error is the line of the output reporting the command was rejected by the router
+	Error          string        `json:"error,omitempty"`
+	PatternMatch   []string      `json:"pattern_match,omitempty"`
+	TriggeredTests []int         `json:"triggered_tests,omitempty"`
+	Fields         []*FieldValue `json:"fields,omitempty"`
+}
+
+// FieldValue is a value extracted by a test's field.
+type FieldValue struct {
+	TestID      int         `json:"test_id"`
+	FieldNumber int         `json:"field_number"`
+	Group       string      `json:"group,omitempty"`
+	Operation   string      `json:"operation,omitempty"`
+	Value       interface{} `json:"value"`
+}
+
+// Document is the structure of a results file in FormatJSON.
+type Document struct {
+	Router  string    `json:"router"`
+	Results []*Record `json:"results"`
+}
+
+type Recorder interface {
+	Record(*Record) error
+	GetFileName() string
+	Close() error
+}
+
+var _ Recorder = &recorder{}
+
+type recorder struct {
+	mx     sync.Mutex
+	router string
+	format string
+	f      *os.File
+	w      *bufio.Writer
+	// records is the number of records written
+	records int
+}
+
+func (r *recorder) Record(rec *Record) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if rec.Router == "" {
+		rec.Router = r.router
+	}
+	if rec.DurationMs == 0 && !rec.End.IsZero() {
+		rec.DurationMs = rec.End.Sub(rec.Start).Milliseconds()
+	}
+	var b []byte
+	var err error
+	if r.format == FormatJSON {
+		// Records are elements of the document's results array
+		b, err = json.MarshalIndent(rec, "    ", "  ")
+		if err == nil {
+			sep := ",\n    "
+			if r.records == 0 {
+				sep = "\n    "
+			}
+			b = append([]byte(sep), b...)
+		}
+	} else {
+		b, err = json.Marshal(rec)
+		b = append(b, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal result of command %q with error: %+v", rec.Command, err)
+	}
+	if _, err := r.w.Write(b); err != nil {
+		return fmt.Errorf("failed to write result of command %q with error: %+v", rec.Command, err)
+	}
+	r.records++
+	// Flushing every line, so the file can be consumed while the run is still in progress
+	return r.w.Flush()
+}
+
+func (r *recorder) GetFileName() string {
+	return r.f.Name()
+}
+
+func (r *recorder) Close() error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	defer r.f.Close()
+	if r.format == FormatJSON {
+		end := "\n  ]\n}\n"
+		if r.records == 0 {
+			end = "]\n}\n"
+		}
+		if _, err := r.w.WriteString(end); err != nil {
+			return fmt.Errorf("failed to write results file %s with error: %+v", r.f.Name(), err)
+		}
+	}
+	return r.w.Flush()
+}
+
+// NewRecorder creates the results file next to the router's log file, the name of the results file is
+// the log file name with the extension matching the format.
+func NewRecorder(router string, logLoc string, logFileName string, format string) (Recorder, error) {
+	switch format {
+	case FormatJSON, FormatJSONLines:
+	default:
+		return nil, fmt.Errorf("unsupported results format %q, supported formats: %s, %s", format, FormatJSON, FormatJSONLines)
+	}
+	if logLoc == "" {
+		logLoc = "."
+	}
+	fileName := filepath.Join(logLoc, strings.TrimSuffix(logFileName, filepath.Ext(logFileName))+"."+format)
+	f, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	glog.Infof("results for router: %s will be stored at %s location", router, fileName)
+	r := &recorder{
+		router: router,
+		format: format,
+		f:      f,
+		w:      bufio.NewWriter(f),
+	}
+	if format == FormatJSON {
+		// The document is started right away, so records are not kept in memory until the recorder is closed
+		b, _ := json.Marshal(router)
+		if _, err := fmt.Fprintf(r.w, "{\n  \"router\": %s,\n  \"results\": [", b); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to write results file %s with error: %+v", fileName, err)
+		}
+	}
+
+	return r, nil
+}
@@ -0,0 +1,150 @@
+package results
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func testRecords() []*Record {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []*Record{
+		{
+			Command:   "show cef drops location 0/0/CPU0",
+			Location:  "0/0/CPU0",
+			Iteration: 0,
+			Start:     start,
+			End:       start.Add(1500 * time.Millisecond),
+			Output:    "Discard drops packets : 10\n",
+			PatternMatch: []string{
+				"Discard drops packets : 10",
+			},
+			TriggeredTests: []int{1},
+			Fields: []*FieldValue{
+				{TestID: 1, FieldNumber: 4, Operation: "compare_with_value_neq", Value: "10"},
+			},
+		},
+		{
+			Command:   "show platform",
+			Iteration: 1,
+			Start:     start,
+			End:       start.Add(time.Second),
+			Output:    "0/RSP0/CPU0     A9K-RSP880-TR(Active)     IOS XR RUN\n",
+		},
+	}
+}
+
+func TestRecorderJSON(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewRecorder("r1", dir, "r1_2024-01-02_03-04-05.log", FormatJSON)
+	if err != nil {
+		t.Fatalf("failed to create recorder with error: %+v", err)
+	}
+	for _, r := range testRecords() {
+		if err := rec.Record(r); err != nil {
+			t.Fatalf("failed to record with error: %+v", err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("failed to close recorder with error: %+v", err)
+	}
+	b, err := os.ReadFile(rec.GetFileName())
+	if err != nil {
+		t.Fatalf("failed to read results file with error: %+v", err)
+	}
+	doc := &Document{}
+	if err := json.Unmarshal(b, doc); err != nil {
+		t.Fatalf("failed to unmarshal results file with error: %+v", err)
+	}
+	if doc.Router != "r1" || len(doc.Results) != 2 {
+		t.Fatalf("unexpected results document: %s", string(b))
+	}
+	first := doc.Results[0]
+	if first.Router != "r1" || first.DurationMs != 1500 || first.Location != "0/0/CPU0" {
+		t.Fatalf("unexpected first record: %+v", first)
+	}
+	if len(first.TriggeredTests) != 1 || len(first.Fields) != 1 || first.Fields[0].Value != "10" {
+		t.Fatalf("unexpected tests results in the first record: %+v", first)
+	}
+}
+
+func TestRecorderJSONStreamed(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []*Record
+	}{
+		{name: "no records", records: []*Record{}},
+		{name: "records", records: testRecords()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, err := NewRecorder("r1", t.TempDir(), "r1.log", FormatJSON)
+			if err != nil {
+				t.Fatalf("failed to create recorder with error: %+v", err)
+			}
+			for _, r := range tt.records {
+				if err := rec.Record(r); err != nil {
+					t.Fatalf("failed to record with error: %+v", err)
+				}
+			}
+			if err := rec.Close(); err != nil {
+				t.Fatalf("failed to close recorder with error: %+v", err)
+			}
+			b, err := os.ReadFile(rec.GetFileName())
+			if err != nil {
+				t.Fatalf("failed to read results file with error: %+v", err)
+			}
+			// The streamed document is the same as the indented document of all records
+			exp, err := json.MarshalIndent(&Document{Router: "r1", Results: tt.records}, "", "  ")
+			if err != nil {
+				t.Fatalf("failed to marshal document with error: %+v", err)
+			}
+			if string(b) != string(exp)+"\n" {
+				t.Fatalf("expected document:\n%s\ngot:\n%s", string(exp), string(b))
+			}
+		})
+	}
+}
+
+func TestRecorderJSONLines(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewRecorder("r1", dir, "r1_2024-01-02_03-04-05.log", FormatJSONLines)
+	if err != nil {
+		t.Fatalf("failed to create recorder with error: %+v", err)
+	}
+	defer rec.Close()
+	for _, r := range testRecords() {
+		if err := rec.Record(r); err != nil {
+			t.Fatalf("failed to record with error: %+v", err)
+		}
+	}
+	// Lines are expected to be available before the recorder is closed
+	f, err := os.Open(rec.GetFileName())
+	if err != nil {
+		t.Fatalf("failed to open results file with error: %+v", err)
+	}
+	defer f.Close()
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		r := &Record{}
+		if err := json.Unmarshal(scanner.Bytes(), r); err != nil {
+			t.Fatalf("failed to unmarshal line %d with error: %+v", lines, err)
+		}
+		if r.Router != "r1" {
+			t.Fatalf("expected router r1, got %q", r.Router)
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Fatalf("expected 2 lines, got %d", lines)
+	}
+}
+
+func TestNewRecorderUnsupportedFormat(t *testing.T) {
+	if _, err := NewRecorder("r1", t.TempDir(), "r1.log", "xml"); err == nil {
+		t.Fatalf("expected unsupported format to fail")
+	}
+}
@@ -1,9 +1,10 @@
-package main
+package runner
 
 import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-test/deep"
 	"github.com/sbezverk/routercommander/pkg/types"
@@ -353,7 +354,7 @@ HR - Hard Reset,     DC  - Disconnect signal,  DL - DownLoad
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			triggered, err := runTest(tt.input, tt.test, tt.iteration)
+			triggered, err := runTest(tt.input, tt.test, tt.iteration, nil)
 			if err != nil {
 				t.Fatalf("failed with error: %+v", err)
 			}
@@ -363,3 +364,232 @@ HR - Hard Reset,     DC  - Disconnect signal,  DL - DownLoad
 		})
 	}
 }
+
+func TestCheckNumeric(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		op        string
+		value     string
+		previous  interface{}
+		current   interface{}
+		elapsed   time.Duration
+		triggered bool
+	}{
+		{
+			name:      "cpu above threshold",
+			op:        types.OpCompareWithValueGt,
+			value:     "80",
+			current:   int64(85),
+			triggered: true,
+		},
+		{
+			name:      "cpu below threshold",
+			op:        types.OpCompareWithValueGt,
+			value:     "80",
+			current:   int64(5),
+			triggered: false,
+		},
+		{
+			name:      "float less than value",
+			op:        types.OpCompareWithValueLt,
+			value:     "0.5",
+			current:   0.25,
+			triggered: true,
+		},
+		{
+			name:      "drops increased by more than 100",
+			op:        types.OpDeltaWithPreviousGt,
+			value:     "100",
+			previous:  int64(1000),
+			current:   int64(1101),
+			triggered: true,
+		},
+		{
+			name:      "drops increased by 100",
+			op:        types.OpDeltaWithPreviousGt,
+			value:     "100",
+			previous:  int64(1000),
+			current:   int64(1100),
+			triggered: false,
+		},
+		{
+			name:      "counter decreased",
+			op:        types.OpDeltaWithPreviousLt,
+			value:     "0",
+			previous:  int64(1000),
+			current:   int64(10),
+			triggered: true,
+		},
+		{
+			name:      "rate above threshold",
+			op:        types.OpRateWithPreviousGt,
+			value:     "10",
+			previous:  int64(0),
+			current:   int64(1000),
+			elapsed:   10 * time.Second,
+			triggered: true,
+		},
+		{
+			name:      "rate below threshold",
+			op:        types.OpRateWithPreviousGt,
+			value:     "10",
+			previous:  int64(0),
+			current:   int64(1000),
+			elapsed:   200 * time.Second,
+			triggered: false,
+		},
+		{
+			name:      "percent change above threshold",
+			op:        types.OpPercentChangeWithPreviousGt,
+			value:     "10",
+			previous:  int64(200),
+			current:   int64(170),
+			triggered: true,
+		},
+		{
+			name:      "percent change below threshold",
+			op:        types.OpPercentChangeWithPreviousGt,
+			value:     "10",
+			previous:  int64(200),
+			current:   int64(210),
+			triggered: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field := &types.Field{FieldNumber: 1, Operation: tt.op, Value: tt.value}
+			store := map[int]map[int]interface{}{}
+			times := map[int]time.Time{}
+			iteration := 0
+			if tt.previous != nil {
+				store[iteration] = map[int]interface{}{0: tt.previous}
+				times[iteration] = start
+				iteration++
+			}
+			store[iteration] = map[int]interface{}{0: tt.current}
+			times[iteration] = start.Add(tt.elapsed)
+			triggered, err := check(tt.op, iteration, 0, field, store, times)
+			if err != nil {
+				t.Fatalf("failed with error: %+v", err)
+			}
+			if triggered != tt.triggered {
+				t.Fatalf("expect triggered to be %t but got %t", tt.triggered, triggered)
+			}
+		})
+	}
+}
+
+func TestRunTestNumericValues(t *testing.T) {
+	test := &types.Test{
+		ValuesStore: make(map[int]map[int]interface{}),
+		Pattern: &types.Pattern{
+			PatternString: "input drops",
+		},
+		Fields: []*types.Field{
+			{
+				FieldNumber: 0,
+				Operation:   types.OpDeltaWithPreviousGt,
+				Value:       "100",
+			},
+		},
+	}
+	outputs := []string{"1,000 input drops", "1,250 input drops"}
+	for i, o := range outputs {
+		triggered, err := runTest([]*types.CmdResult{{Cmd: "show interface", Result: []byte(o)}}, test, i, nil)
+		if err != nil {
+			t.Fatalf("failed with error: %+v", err)
+		}
+		if triggered != (i == 1) {
+			t.Fatalf("iteration %d: unexpected triggered %t", i, triggered)
+		}
+	}
+	if v, ok := test.ValuesStore[1][0].(int64); !ok || v != 1250 {
+		t.Fatalf("expected stored value to be int64 1250 but got %T %v", test.ValuesStore[1][0], test.ValuesStore[1][0])
+	}
+}
+
+func TestRunTestCaptureGroup(t *testing.T) {
+	test := &types.Test{
+		ValuesStore: make(map[int]map[int]interface{}),
+		Pattern: &types.Pattern{
+			PatternString: `Discard drops packets\s*:\s*(?P<drops>[0-9,]+)`,
+			RegExp:        regexp.MustCompile(`Discard drops packets\s*:\s*(?P<drops>[0-9,]+)`),
+		},
+		Fields: []*types.Field{
+			{
+				Group:     "drops",
+				Operation: types.OpCompareWithValueGt,
+				Value:     "100",
+			},
+		},
+	}
+	tests := []struct {
+		name      string
+		output    string
+		value     int64
+		triggered bool
+	}{
+		{
+			name:      "below threshold",
+			output:    "CEF Drop Statistics\nDiscard drops packets :   10\n",
+			value:     10,
+			triggered: false,
+		},
+		{
+			name:      "above threshold with shifted columns",
+			output:    "CEF Drop Statistics\n  Discard drops packets     : 1,500   \n",
+			value:     1500,
+			triggered: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			triggered, err := runTest([]*types.CmdResult{{Cmd: "show cef drops", Result: []byte(tt.output)}}, test, i, nil)
+			if err != nil {
+				t.Fatalf("failed with error: %+v", err)
+			}
+			if triggered != tt.triggered {
+				t.Fatalf("expect triggered to be %t but got %t", tt.triggered, triggered)
+			}
+			if v := test.ValuesStore[i][0]; v != tt.value {
+				t.Fatalf("expected value %d but got %v", tt.value, v)
+			}
+		})
+	}
+}
+
+func TestRunTestFieldName(t *testing.T) {
+	re := regexp.MustCompile(`Discard drops packets\s*:\s*(?P<drops>[0-9,]+)`)
+	tests := []struct {
+		name   string
+		field  *types.Field
+		expect string
+	}{
+		{
+			name:   "capture group",
+			field:  &types.Field{Group: "drops", Operation: types.OpCompareWithValueGt, Value: "100"},
+			expect: `field "drops"`,
+		},
+		{
+			name:   "field number",
+			field:  &types.Field{FieldNumber: 1, Operation: types.OpCompareWithValueGt, Value: "100"},
+			expect: "field 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := &types.Test{
+				ID:          1,
+				ValuesStore: make(map[int]map[int]interface{}),
+				Pattern:     &types.Pattern{PatternString: re.String(), RegExp: re},
+				Separator:   ":",
+				Fields:      []*types.Field{tt.field},
+			}
+			_, err := runTest([]*types.CmdResult{{Cmd: "show cef drops", Result: []byte("Discard drops packets : ,\n")}}, test, 0, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.expect) {
+				t.Fatalf("expected error about %s, got: %+v", tt.expect, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,877 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/sbezverk/routercommander/pkg/messenger"
+	"github.com/sbezverk/routercommander/pkg/results"
+	"github.com/sbezverk/routercommander/pkg/summary"
+	"github.com/sbezverk/routercommander/pkg/types"
+)
+
+func process(ctx context.Context, r types.Router, commander *types.Commander, vars map[string]string, n messenger.Notifier, rec *recorder, s *summary.RouterSummary) error {
+	iterations := 1
+	interval := 0
+	stopWhenTriggered := true
+	if commander.Repro != nil {
+		if commander.Repro.Times > 0 {
+			iterations = commander.Repro.Times
+		}
+		if commander.Repro.Interval > 0 {
+			interval = commander.Repro.Interval
+		}
+		stopWhenTriggered = commander.Repro.StopWhenTriggered
+	}
+	glog.Infof("router %s: command set will be executed %d time(s) with the interval of %d seconds", r.GetName(), iterations, interval)
+	// Setting up the notification to be sent at the end of execution
+	defer func() {
+		li := r.GetLogger()
+		if n != nil {
+			glog.Infof("notification requested, attempting to send out the log for router %s", r.GetName())
+			e := &messenger.Event{
+				Type:    messenger.EventRunFinished,
+				Router:  r.GetName(),
+				Time:    time.Now(),
+				Message: fmt.Sprintf("processing of router %s has finished", r.GetName()),
+			}
+			// Routers embedded by other tools might not have a logger, the event is sent without the log
+			if li != nil {
+				e.Output = li.GetLog()
+				e.LogFileName = li.GetLogFileName()
+			}
+			if err := n.NotifyEvent(e); err != nil {
+				glog.Errorf("failed to Notify with error: %+v", err)
+			} else {
+				glog.Infof("routercommander sent log for router: %s", r.GetName())
+			}
+		}
+		if li != nil {
+			li.Close()
+		}
+		if rec != nil {
+			if err := rec.close(); err != nil {
+				glog.Errorf("router %s: failed to close results file with error: %+v", r.GetName(), err)
+			}
+		}
+		r.Close()
+	}()
+	if err := commander.Expand(vars); err != nil {
+		return fmt.Errorf("router %s: failed to expand variables with error: %+v", r.GetName(), err)
+	}
+	facts := types.NewFacts(r, commander, vars)
+	triggered := false
+	var err error
+	for it := 0; it < iterations; it++ {
+		if ctx.Err() != nil {
+			// Interrupted, not scheduling new iterations
+			return fmt.Errorf("router %s: interrupted after %d/%d iteration(s) with error: %w", r.GetName(), it, iterations, ctx.Err())
+		}
+		if iterations > 1 {
+			glog.Infof("router %s: executing iteration - %d/%d", r.GetName(), it+1, iterations)
+		}
+		if triggered, err = processMainGroupOfCommands(ctx, r, commander, facts, it, rec, s, n); err != nil {
+			notifyIfConnectionLost(n, r, it, err)
+			return fmt.Errorf("router %s: reported repro failure with error: %+v", r.GetName(), err)
+		}
+		if triggered && commander.Repro != nil {
+			// If the issue was triggered, collecting common Repro.PostMortemCommandGroup commands needed to troubleshooting
+			glog.Infof("repro process on router %s succeeded triggering the failure condition, collecting post-mortem commands...", r.GetName())
+			for _, c := range commander.Repro.PostMortemCommandGroup {
+				rs, err := execute(ctx, r, c, true, facts, nil)
+				if recovered(n, r, it, c, s, err) {
+					continue
+				}
+				if err != nil {
+					s.CommandFailed(c.Cmd)
+					recordRejected(rec, err, it)
+					notifyIfConnectionLost(n, r, it, err)
+					return fmt.Errorf("router %s: failed to process command %q with error %+v", r.GetName(), c.Cmd, err)
+				}
+				s.CommandExecuted(len(rs))
+				reportRejected(c, rs, s)
+				recordResults(rec, rs, it, nil, nil, nil)
+			}
+			if stopWhenTriggered {
+				break
+			}
+		}
+		glog.Infof("router %s: iteration - %d/%d completed,", r.GetName(), it+1, iterations)
+		if it < iterations-1 {
+			if err := types.Delay(ctx, interval); err != nil {
+				return fmt.Errorf("router %s: interrupted after %d/%d iteration(s) with error: %w", r.GetName(), it+1, iterations, err)
+			}
+		}
+	}
+	if commander.Repro != nil {
+		s.SetReproTriggered(triggered)
+		if triggered {
+			glog.Infof("repro process on router %s succeeded triggering the failure condition", r.GetName())
+		} else {
+			glog.Infof("router %s: repro process has not succeeded triggering the failure condition", r.GetName())
+		}
+	}
+	return nil
+}
+
+func processMainGroupOfCommands(ctx context.Context, r types.Router, commander *types.Commander, facts *types.Facts, iteration int, rec *recorder, s *summary.RouterSummary, n messenger.Notifier) (bool, error) {
+	pr := false
+	stopWhenTriggered := false
+	if commander.Collect != nil {
+		pr = commander.Collect.ProcessResult
+	}
+	if commander.Repro != nil {
+		pr = true
+		stopWhenTriggered = commander.Repro.StopWhenTriggered
+	}
+	triggered := false
+	// Tests triggered in the previous iteration are not facts of this one
+	facts.Triggered = make(map[string][]int)
+	for _, c := range commander.MainCommandGroup {
+		processResult := pr || c.ProcessResult
+		tests := commander.CommandsWithTests[c.Cmd]
+		// When results are recorded, the output is collected even if it is not processed
+		results, err := execute(ctx, r, c, processResult || rec.collects(), facts, testPatterns(tests, c.TestIDs))
+		if recovered(n, r, iteration, c, s, err) {
+			continue
+		}
+		if err != nil {
+			s.CommandFailed(c.Cmd)
+			recordRejected(rec, err, iteration)
+			return false, fmt.Errorf("router %s: failed to process command %q with error %+v", r.GetName(), c.Cmd, err)
+		}
+		s.CommandExecuted(len(results))
+		reportRejected(c, results, s)
+		if !processResult {
+			recordResults(rec, results, iteration, nil, nil, nil)
+			continue
+		}
+		matches, err := matchPatterns(results, c.Patterns)
+		if err != nil {
+			glog.Errorf("router %s: %+v", r.GetName(), err)
+		} else {
+			c.CommandResult.PatternMatch = matches
+			s.PatternsMatched(c.Cmd, matches)
+		}
+		if glog.V(5) {
+			if len(c.CommandResult.PatternMatch) != 0 {
+				glog.Infof("router %s: command %q matches:", r.GetName(), c.Cmd)
+				for _, ms := range c.CommandResult.PatternMatch {
+					glog.Infof("\t%s", ms)
+				}
+			}
+		}
+		// If no tests to do, just continue pattern matching
+		if commander.Tests == nil {
+			recordResults(rec, results, iteration, c.Patterns, nil, nil)
+			continue
+		}
+		// Check if there are tests for the current command
+		if tests == nil {
+			recordResults(rec, results, iteration, c.Patterns, nil, nil)
+			continue
+		}
+		triggers, err := runTests(ctx, r, results, c.TestIDs, tests, facts, iteration, stopWhenTriggered, rec, s, n)
+		if err != nil {
+			return false, fmt.Errorf("router %s: failed to execute tests for command %q with error %+v", r.GetName(), c.Cmd, err)
+		}
+		c.CommandResult.TriggeredTest = triggers
+		if len(triggers) != 0 {
+			facts.Triggered[c.Cmd] = triggers
+		}
+		s.TestsTriggered(triggers)
+		recordResults(rec, results, iteration, c.Patterns, triggers, fieldValues(tests, c.TestIDs, iteration))
+		if len(triggers) > 0 {
+			triggered = true
+			// Notifying at the moment of the trigger, the repro can keep running for a long time after it
+			e := &messenger.Event{
+				Type:      messenger.EventTestTriggered,
+				Iteration: iteration,
+				Cmd:       c.Cmd,
+				TestIDs:   triggers,
+				Message:   fmt.Sprintf("command %q triggered test ids: %v", c.Cmd, triggers),
+				Output:    joinOutputs(results),
+			}
+			if commander.Repro != nil {
+				e.Type = messenger.EventReproTriggered
+				e.Message = fmt.Sprintf("repro iteration %d: %s", iteration+1, e.Message)
+			}
+			notifyEvent(n, r, e)
+		}
+		if len(c.CommandResult.TriggeredTest) != 0 {
+			glog.Infof("router %s: command %q triggered test ids: %v", r.GetName(), c.Cmd, c.CommandResult.TriggeredTest)
+		}
+		if stopWhenTriggered && triggered {
+			return true, nil
+		}
+	}
+
+	return triggered, nil
+}
+
+// execute processes the command with references to captured values replaced, a command with foreach is processed
+// for every captured value. Values captured by the command's patterns and by the patterns passed in are stored
+// before the command's tests are run, so commands triggered by tests can reference them. A command with when
+// evaluating to false or referencing a value which is not captured is not processed and no results are returned.
+func execute(ctx context.Context, r types.Router, c *types.Command, collect bool, facts *types.Facts, patterns []*types.Pattern) ([]*types.CmdResult, error) {
+	ok, err := facts.IsExecuted(c)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		glog.Infof("router %s: when %q is false, command %q is skipped", r.GetName(), c.When, c.Cmd)
+		return []*types.CmdResult{}, nil
+	}
+	captures := facts.Captures
+	cmds, err := captures.ExpandCommand(c)
+	if errors.Is(err, types.ErrNotCaptured) {
+		glog.Warningf("router %s: %+v, command %q is skipped", r.GetName(), err, c.Cmd)
+		return []*types.CmdResult{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.Foreach != "" && len(cmds) == 0 {
+		glog.Infof("router %s: nothing is captured for foreach %q, command %q is skipped", r.GetName(), c.Foreach, c.Cmd)
+	}
+	patterns = append(patterns, c.Patterns...)
+	capture := false
+	for _, p := range patterns {
+		if p != nil && p.StoreCaptures {
+			capture = true
+			break
+		}
+	}
+	results := make([]*types.CmdResult, 0)
+	for _, cmd := range cmds {
+		rs, err := r.ProcessCommand(ctx, cmd, collect || capture)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, rs...)
+	}
+	if capture {
+		if err := captures.Capture(results, patterns); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// testPatterns returns patterns of the tests executed for the command
+func testPatterns(tests *types.Tests, toRun []int) []*types.Pattern {
+	if tests == nil {
+		return nil
+	}
+	patterns := make([]*types.Pattern, 0, len(toRun))
+	for _, id := range toRun {
+		if t, ok := tests.Tests[id]; ok {
+			patterns = append(patterns, t.Pattern)
+		}
+	}
+
+	return patterns
+}
+
+// notifyEvent sends the router's event to the notifier, failures to notify are logged and do not stop the processing
+func notifyEvent(n messenger.Notifier, r types.Router, e *messenger.Event) {
+	if n == nil {
+		return
+	}
+	e.Router = r.GetName()
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if li := r.GetLogger(); li != nil {
+		e.LogFileName = li.GetLogFileName()
+	}
+	if err := n.NotifyEvent(e); err != nil {
+		glog.Errorf("router %s: failed to send %s notification with error: %+v", r.GetName(), e.Type, err)
+	}
+}
+
+// recovered returns true when the command failed because the router's connection was lost and re-established,
+// the command is counted as failed and the processing resumes with the next command.
+func recovered(n messenger.Notifier, r types.Router, iteration int, c *types.Command, s *summary.RouterSummary, err error) bool {
+	var re *types.ReconnectedError
+	if !errors.As(err, &re) {
+		return false
+	}
+	s.CommandFailed(c.Cmd)
+	glog.Warningf("router %s: command %q is skipped, %s", r.GetName(), c.Cmd, re.Error())
+	notifyEvent(n, r, &messenger.Event{
+		Type:      messenger.EventConnectionLost,
+		Iteration: iteration,
+		Cmd:       c.Cmd,
+		Message:   re.Error(),
+	})
+	return true
+}
+
+// reportRejected reports commands rejected by the router as failed, unless the command's on_error policy is continue.
+func reportRejected(c *types.Command, rs []*types.CmdResult, s *summary.RouterSummary) {
+	if c.OnError == types.OnErrorContinue {
+		return
+	}
+	for _, re := range rs {
+		if re.Error != "" {
+			s.CommandFailed(re.Cmd)
+		}
+	}
+}
+
+// recordRejected records the result of the command which failed the processing because it was rejected by the router.
+func recordRejected(rec *recorder, err error, iteration int) {
+	var ce *types.CommandError
+	if !errors.As(err, &ce) {
+		return
+	}
+	recordResults(rec, []*types.CmdResult{ce.Result}, iteration, nil, nil, nil)
+}
+
+func notifyIfConnectionLost(n messenger.Notifier, r types.Router, iteration int, err error) {
+	if !types.IsConnectionLost(err) {
+		return
+	}
+	notifyEvent(n, r, &messenger.Event{
+		Type:      messenger.EventConnectionLost,
+		Iteration: iteration,
+		Message:   err.Error(),
+	})
+}
+
+func joinOutputs(rs []*types.CmdResult) []byte {
+	var b bytes.Buffer
+	for _, re := range rs {
+		b.Write(re.Result)
+	}
+	return b.Bytes()
+}
+
+func runTests(ctx context.Context, r types.Router, results []*types.CmdResult, toRun []int, tests *types.Tests, facts *types.Facts, iteration int, stopWhenTriggered bool, rec *recorder, s *summary.RouterSummary, n messenger.Notifier) ([]int, error) {
+	triggers := make([]int, 0)
+
+out:
+	for _, tr := range toRun {
+		t, ok := tests.Tests[tr]
+		if !ok {
+			continue
+		}
+		triggered, err := runTest(results, t, iteration, facts.Vars)
+		if err != nil {
+			return nil, err
+		}
+		if triggered {
+			// Since test id is trigger, executing the list of commands for the test ID
+			if len(t.IfTriggeredCommands) != 0 {
+				if err := processCommandsIfTriggered(ctx, r, t.IfTriggeredCommands, facts, iteration, rec, s, n); err != nil {
+					return nil, err
+				}
+			}
+			triggers = append(triggers, tr)
+			if stopWhenTriggered {
+				break out
+			}
+			triggered = false
+		}
+	}
+
+	return triggers, nil
+}
+
+func runTest(results []*types.CmdResult, t *types.Test, iteration int, vars map[string]string) (bool, error) {
+	if len(results) == 0 {
+		return false, nil
+	}
+	for ri, re := range results {
+		if glog.V(5) {
+			glog.Infof("Executing Test ID %d for Command: %q", t.ID, re.Cmd)
+		}
+		// Test found, executing it against all instances of Result, the command can return
+		// several instances of Result, when `times` keyword is more than 1
+		if t.Pattern == nil {
+			// Pattern is nil, nothing can be done, continue
+			glog.Warningf("Pattern for command %q test id %d is nil", re.Cmd, t.ID)
+			continue
+		}
+		if t.Pattern.RegExp == nil {
+			// By some reason regular expression has not been initialized, attempting to compile it
+			p, err := regexp.Compile(t.Pattern.PatternString)
+			if err != nil {
+				glog.Warningf("Fail to compile regular experssion for command %s test id %d with error: %+v", re.Cmd, t.ID, err)
+				continue
+			}
+			t.Pattern.RegExp = p
+		}
+		p := t.Pattern.RegExp
+		// Submatch indexes start with the indexes of the whole match, followed by the indexes of capture groups
+		matches := p.FindAllSubmatchIndex(re.Result, -1)
+		if matches == nil {
+			glog.Warningf("Test ID: %d Command: %q pattern %q is not found", t.ID, re.Cmd, p.String())
+			continue
+		}
+		// Test the number of hits of the patter, if does not match, considered the issue triggered
+		if t.NumberOfOccurences != nil {
+			if *t.NumberOfOccurences != len(matches) {
+				return true, nil
+			}
+		}
+		if len(matches) <= t.Occurrence-1 {
+			// Since requested occurence number does not exists, returning false
+			glog.Warningf("Test ID: %d Command: %q requested occurence %d is more than number of matches %d", t.ID, re.Cmd, t.Occurrence, len(matches))
+			return false, nil
+		}
+		if len(t.Fields) == 0 && t.Condition == "" {
+			// No fields related tests, but the match was found
+			return true, nil
+		}
+		// When multiple instances of match exists and not specific occurence number is requested, then
+		// all instances must be checked for triggerring conditions. If check_all_results is true then all checks must
+		// return "triggered"
+		nm := len(matches)
+		indx := 0
+		if t.Occurrence != 0 {
+			nm = t.Occurrence
+			indx = t.Occurrence - 1
+		}
+		perOccurenceTrigger := 0
+		for ; indx < nm; indx++ {
+			// When test has one or more fields and all fields' checks should produce a true condition, check_all_results is set to True
+			// number variable is used to calculate a number of "true" condirtions
+			if t.Condition != "" {
+				at := re.End
+				if at.IsZero() {
+					at = time.Now()
+				}
+				trgrd, err := t.EvaluateCondition(re.Result, matches[indx], types.ConditionMatch{Result: ri, Match: indx}, len(matches), iteration, vars, at)
+				if err != nil {
+					return false, fmt.Errorf("command %q: %+v", re.Cmd, err)
+				}
+				if trgrd {
+					perOccurenceTrigger++
+				}
+				continue
+			}
+			perFieldTrigger := 0
+			for fi, field := range t.Fields {
+				var vm string
+				var err error
+				if field.Group != "" {
+					vm, err = getGroupValue(re.Result, matches[indx], p, field)
+				} else {
+					vm, err = getValue(re.Result, matches[indx], field, t.Separator)
+				}
+				if err != nil {
+					return false, fmt.Errorf("failed to extract value field %s for command %q test id %d with error: %+v", fieldName(field), re.Cmd, t.ID, err)
+				}
+				var v interface{} = vm
+				if types.IsNumericOperation(field.Operation) {
+					// Numeric operations keep the parsed number, so it can be compared with other iterations
+					if v, err = types.ParseNumber(vm); err != nil {
+						return false, fmt.Errorf("failed to parse value of field %s for command %q test id %d with error: %+v", fieldName(field), re.Cmd, t.ID, err)
+					}
+				}
+				// Storing extracted fields in pattern's Values per iterations map, fields are keyed by their index
+				// as several fields can extract values from different capture groups.
+				if _, ok := t.ValuesStore[iteration]; !ok {
+					t.ValuesStore[iteration] = make(map[int]interface{})
+				}
+				t.ValuesStore[iteration][fi] = v
+				if t.TimeStore == nil {
+					t.TimeStore = make(map[int]time.Time)
+				}
+				t.TimeStore[iteration] = re.End
+				if re.End.IsZero() {
+					t.TimeStore[iteration] = time.Now()
+				}
+				field.Result = v
+				trgrd, err := check(field.Operation, iteration, fi, field, t.ValuesStore, t.TimeStore)
+				if err != nil {
+					return false, err
+				}
+				if trgrd {
+					perFieldTrigger++
+				}
+			}
+			if t.CheckAllResults {
+				// Need to check all fields, only then the match[indx] considered as a trigger
+				if perFieldTrigger == len(t.Fields) {
+					perOccurenceTrigger++
+				}
+			} else {
+				// Not all checks are required, a single field trigger is sufficient
+				if perFieldTrigger > 0 {
+					perOccurenceTrigger++
+				}
+			}
+		}
+		if t.CheckAllResults {
+			// Need to check all matches, only then the test considered as the trigger
+			if perOccurenceTrigger == len(matches) {
+				return true, nil
+			}
+		} else {
+			if perOccurenceTrigger > 0 {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func processCommandsIfTriggered(ctx context.Context, r types.Router, commands []*types.Command, facts *types.Facts, iteration int, rec *recorder, s *summary.RouterSummary, n messenger.Notifier) error {
+	for _, c := range commands {
+		rs, err := execute(ctx, r, c, rec.collects(), facts, nil)
+		if recovered(n, r, iteration, c, s, err) {
+			continue
+		}
+		if err != nil {
+			s.CommandFailed(c.Cmd)
+			recordRejected(rec, err, iteration)
+			return err
+		}
+		s.CommandExecuted(len(rs))
+		reportRejected(c, rs, s)
+		recordResults(rec, rs, iteration, nil, nil, nil)
+	}
+	return nil
+}
+
+// recorder records results of the router's commands to the results file and passes them to the observer, both are optional.
+type recorder struct {
+	router string
+	rec    results.Recorder
+	o      Observer
+}
+
+// collects returns true when outputs of all commands are needed, to record them or for the observer
+func (rc *recorder) collects() bool {
+	return rc != nil && (rc.rec != nil || rc.o != nil)
+}
+
+func (rc *recorder) record(record *results.Record) error {
+	if rc.o != nil {
+		rc.o.CommandExecuted(rc.router, record)
+	}
+	if rc.rec == nil {
+		return nil
+	}
+	return rc.rec.Record(record)
+}
+
+func (rc *recorder) close() error {
+	if rc.rec == nil {
+		return nil
+	}
+	return rc.rec.Close()
+}
+
+// recordResults stores a record per command result, pattern matches are computed for every result individually.
+func recordResults(rec *recorder, rs []*types.CmdResult, iteration int, patterns []*types.Pattern, triggers []int, fields []*results.FieldValue) {
+	if !rec.collects() {
+		return
+	}
+	for _, re := range rs {
+		record := &results.Record{
+			Command:        re.Cmd,
+			Location:       re.Location,
+			Iteration:      iteration,
+			Start:          re.Start,
+			End:            re.End,
+			Output:         string(re.Result),
+			Error:          re.Error,
+			TriggeredTests: triggers,
+			Fields:         fields,
+		}
+		if len(patterns) != 0 {
+			matches, err := matchPatterns([]*types.CmdResult{re}, patterns)
+			if err != nil {
+				glog.Errorf("failed to match patterns for results of command %q with error: %+v", re.Cmd, err)
+			} else {
+				record.PatternMatch = matches
+			}
+		}
+		if err := rec.record(record); err != nil {
+			glog.Errorf("failed to record results of command %q with error: %+v", re.Cmd, err)
+		}
+	}
+}
+
+// fieldValues returns values extracted by the tests' fields in the iteration.
+func fieldValues(tests *types.Tests, toRun []int, iteration int) []*results.FieldValue {
+	values := make([]*results.FieldValue, 0)
+	for _, id := range toRun {
+		t, ok := tests.Tests[id]
+		if !ok {
+			continue
+		}
+		if t.Condition != "" {
+			values = append(values, conditionValues(t, iteration)...)
+			continue
+		}
+		store, ok := t.ValuesStore[iteration]
+		if !ok {
+			continue
+		}
+		for fi, field := range t.Fields {
+			v, ok := store[fi]
+			if !ok {
+				continue
+			}
+			values = append(values, &results.FieldValue{
+				TestID:      t.ID,
+				FieldNumber: field.FieldNumber,
+				Group:       field.Group,
+				Operation:   field.Operation,
+				Value:       v,
+			})
+		}
+	}
+
+	return values
+}
+
+// conditionValues returns values of capture groups of all matches evaluated by the test's condition in the iteration,
+// ordered by the command's result and the match.
+func conditionValues(t *types.Test, iteration int) []*results.FieldValue {
+	store := t.ConditionStore[iteration]
+	matches := make([]types.ConditionMatch, 0, len(store))
+	for m := range store {
+		matches = append(matches, m)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Result != matches[j].Result {
+			return matches[i].Result < matches[j].Result
+		}
+		return matches[i].Match < matches[j].Match
+	})
+	values := make([]*results.FieldValue, 0)
+	for _, m := range matches {
+		for _, name := range t.Pattern.RegExp.SubexpNames() {
+			v, ok := store[m].Values[name]
+			if name == "" || !ok {
+				continue
+			}
+			values = append(values, &results.FieldValue{
+				TestID: t.ID,
+				Group:  name,
+				Value:  v,
+			})
+		}
+	}
+
+	return values
+}
+
+func matchPatterns(results []*types.CmdResult, patterns []*types.Pattern) ([]string, error) {
+	matches := make([]string, 0)
+	for _, re := range results {
+		reader := bufio.NewReader(bytes.NewReader(re.Result))
+		done := false
+		for !done {
+			b, err := reader.ReadBytes('\n')
+			if err != nil {
+				if err != io.EOF {
+					return nil, fmt.Errorf("failed to read command: %q result buffer with error: %v", re.Cmd, err)
+				}
+				done = true
+			}
+			for _, p := range patterns {
+				if i := p.RegExp.FindIndex(b); i != nil {
+					matches = append(matches, strings.Trim(string(b), "\n\r\t"))
+				}
+			}
+		}
+	}
+
+	return matches, nil
+}
+
+func check(op string, iteration int, key int, field *types.Field, store map[int]map[int]interface{}, times map[int]time.Time) (bool, error) {
+	switch op {
+	case types.OpCompareWithPreviousNeq:
+		if iteration == 0 {
+			return false, nil
+		}
+		glog.Infof("Previous value: %v current value: %v", store[iteration-1][key], store[iteration][key])
+		if store[iteration][key] != store[iteration-1][key] {
+			return true, nil
+		}
+	case types.OpCompareWithPreviousEq:
+		if iteration == 0 {
+			return false, nil
+		}
+		glog.Infof("Previous value: %v current value: %v", store[iteration-1][key], store[iteration][key])
+		if store[iteration][key] == store[iteration-1][key] {
+			return true, nil
+		}
+	case types.OpCompareWithValueNeq:
+		glog.Infof("Expected value: %s current value: %v", field.Value, store[iteration][key])
+		if store[iteration][key] != field.Value {
+			return true, nil
+		}
+	case types.OpCompareWithValueEq:
+		glog.Infof("Expected value: %s current value: %v", field.Value, store[iteration][key])
+		if store[iteration][key] == field.Value {
+			return true, nil
+		}
+	case types.OpContainSubstring:
+		sv, ok := store[iteration][key].(string)
+		if !ok {
+			return false, fmt.Errorf("field %s value is not a string for operation %s", fieldName(field), op)
+		}
+		glog.Infof("substring value: %s current value: %s", field.Value, sv)
+		if !strings.Contains(sv, field.Value) {
+			return true, nil
+		}
+	case types.OpNotContainSubstring:
+		sv, ok := store[iteration][key].(string)
+		if !ok {
+			return false, fmt.Errorf("field %s value is not a string for operation %s", fieldName(field), op)
+		}
+		glog.Infof("substring value: %s current value: %s", field.Value, sv)
+		if strings.Contains(sv, field.Value) {
+			return true, nil
+		}
+	case types.OpCompareWithValueGt, types.OpCompareWithValueLt:
+		cur, threshold, err := numericOperands(op, field, store[iteration][key])
+		if err != nil {
+			return false, err
+		}
+		glog.Infof("Threshold value: %v current value: %v", threshold, cur)
+		if op == types.OpCompareWithValueGt {
+			return cur > threshold, nil
+		}
+		return cur < threshold, nil
+	case types.OpDeltaWithPreviousGt, types.OpDeltaWithPreviousLt, types.OpRateWithPreviousGt, types.OpPercentChangeWithPreviousGt:
+		if iteration == 0 {
+			return false, nil
+		}
+		prev, ok := store[iteration-1][key]
+		if !ok {
+			return false, nil
+		}
+		_, threshold, err := numericOperands(op, field, store[iteration][key])
+		if err != nil {
+			return false, err
+		}
+		delta, ok := types.Delta(store[iteration][key], prev)
+		if !ok {
+			return false, fmt.Errorf("field %s previous value %v is not a number for operation %s", fieldName(field), prev, op)
+		}
+		glog.Infof("Previous value: %v current value: %v threshold: %v", prev, store[iteration][key], threshold)
+		switch op {
+		case types.OpDeltaWithPreviousGt:
+			return delta > threshold, nil
+		case types.OpDeltaWithPreviousLt:
+			return delta < threshold, nil
+		case types.OpRateWithPreviousGt:
+			elapsed := times[iteration].Sub(times[iteration-1]).Seconds()
+			if elapsed <= 0 {
+				glog.Warningf("field %s: no time elapsed between iterations %d and %d, rate cannot be calculated", fieldName(field), iteration-1, iteration)
+				return false, nil
+			}
+			return delta/elapsed > threshold, nil
+		case types.OpPercentChangeWithPreviousGt:
+			p, _ := types.ToFloat64(prev)
+			if p == 0 {
+				// Any change from zero is considered as infinite percentage change
+				return delta != 0, nil
+			}
+			return math.Abs(delta/p*100) > threshold, nil
+		}
+	default:
+		return false, fmt.Errorf("unknown operation: %s for field: %s",
+			field.Operation, fieldName(field))
+	}
+
+	return false, nil
+}
+
+// numericOperands returns the field's current value and the threshold value of a numeric operation
+func numericOperands(op string, field *types.Field, value interface{}) (float64, float64, error) {
+	cur, ok := types.ToFloat64(value)
+	if !ok {
+		return 0, 0, fmt.Errorf("field %s value %v is not a number for operation %s", fieldName(field), value, op)
+	}
+	t, err := types.ParseNumber(field.Value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("field %s threshold for operation %s is invalid: %+v", fieldName(field), op, err)
+	}
+	threshold, _ := types.ToFloat64(t)
+
+	return cur, threshold, nil
+}
+
+func getValue(b []byte, index []int, field *types.Field, separator string) (string, error) {
+	if separator == "" {
+		separator = " "
+	}
+	endLine, err := regexp.Compile(`(?m)$`)
+	if err != nil {
+		return "", err
+	}
+	startLine, err := regexp.Compile(`(?m)^`)
+	if err != nil {
+		return "", err
+	}
+	// First, find the end of the line with matching pattern
+	eIndex := endLine.FindIndex(b[index[0]:])
+	if eIndex == nil {
+		return "", fmt.Errorf("failed to find the end of line in data: %s", string(b[index[0]:]))
+	}
+	en := index[0] + eIndex[0]
+	// Second, find the start of the string with matching pattern
+	sIndex := startLine.FindAllIndex(b[:en-1], -1)
+	if sIndex == nil {
+		return "", fmt.Errorf("failed to find the start of line in data: %s", string(b[:index[0]]))
+	}
+	st := sIndex[len(sIndex)-1][0]
+	s := string(b[st:en])
+	// Splitting the resulting string using provided separator
+	sepreg, err := regexp.Compile("[" + separator + "]+")
+	if err != nil {
+		return "", err
+	}
+	parts := sepreg.Split(s, -1)
+	if len(parts) <= field.FieldNumber {
+		return "", fmt.Errorf("failed to split string %s, separator: %s, field number: %d", s, separator, field.FieldNumber)
+	}
+
+	return strings.Trim(parts[field.FieldNumber], " \n\t,"), nil
+}
+
+// fieldName returns the name of the field's capture group, or its number when the field is not a capture group
+func fieldName(field *types.Field) string {
+	if field.Group != "" {
+		return strconv.Quote(field.Group)
+	}
+	return strconv.Itoa(field.FieldNumber)
+}
+
+// getGroupValue returns the value of the field's named capture group in the match
+func getGroupValue(b []byte, match []int, p *regexp.Regexp, field *types.Field) (string, error) {
+	gi := p.SubexpIndex(field.Group)
+	if gi == -1 {
+		return "", fmt.Errorf("capture group %q is not found in pattern %q", field.Group, p.String())
+	}
+	if 2*gi+1 >= len(match) || match[2*gi] == -1 {
+		// The group did not participate in the match
+		return "", nil
+	}
+
+	return strings.TrimSpace(string(b[match[2*gi]:match[2*gi+1]])), nil
+}
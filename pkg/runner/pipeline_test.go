@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sbezverk/routercommander/pkg/messenger"
+	"github.com/sbezverk/routercommander/pkg/results"
+	"github.com/sbezverk/routercommander/pkg/types"
+)
+
+type testNotifier struct {
+	mx     sync.Mutex
+	events []*messenger.Event
+}
+
+func (tn *testNotifier) Notify(string, []byte) error {
+	return nil
+}
+
+func (tn *testNotifier) NotifyEvent(e *messenger.Event) error {
+	tn.mx.Lock()
+	defer tn.mx.Unlock()
+	tn.events = append(tn.events, e)
+	return nil
+}
+
+func writeTestFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	fn := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(fn, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s with error: %+v", fn, err)
+	}
+	return fn
+}
+
+func TestProcessNotifiesEvents(t *testing.T) {
+	captured := ""
+	for _, drops := range []int{10, 20, 500} {
+		captured += fmt.Sprintf("%sshow cef drops\nDiscard drops packets : %d\n\n\n", types.CommandMarker, drops)
+	}
+	captured += types.CommandMarker + "show logging\nlink down\n\n\n"
+	commands, err := types.GetCommands(writeTestFile(t, "repro.yaml", `repro:
+  times: 5
+  if_triggered_commands:
+  - command: "show logging"
+tests:
+- command: "show cef drops"
+  command_tests:
+  - id: 7
+    pattern:
+      pattern_string: 'Discard drops packets\s*:\s*(?P<drops>\d+)'
+    fields:
+    - group: drops
+      operation: "delta_with_previous_gt"
+      value: "100"
+commands:
+- command: "show cef drops"
+  command_test_ids: [7]
+`))
+	if err != nil {
+		t.Fatalf("failed to get commands with error: %+v", err)
+	}
+	commands.Repro.StopWhenTriggered = true
+	r, err := types.NewReplayRouter("r1", writeTestFile(t, "r1.log", captured), nil)
+	if err != nil {
+		t.Fatalf("failed to create replay router with error: %+v", err)
+	}
+	n := &testNotifier{}
+	if err := process(context.Background(), r, commands, nil, n, nil, nil); err != nil {
+		t.Fatalf("process failed with error: %+v", err)
+	}
+	// The trigger is followed by the end of the run
+	if len(n.events) != 2 || n.events[1].Type != messenger.EventRunFinished {
+		t.Fatalf("expected 2 events but got %d", len(n.events))
+	}
+	e := n.events[0]
+	if e.Type != messenger.EventReproTriggered || e.Router != "r1" || e.Iteration != 2 || e.Cmd != "show cef drops" {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if len(e.TestIDs) != 1 || e.TestIDs[0] != 7 || !strings.Contains(string(e.Output), "500") {
+		t.Fatalf("event does not carry the triggering test id and output: %+v", e)
+	}
+}
+
+// collectingRouter records whether outputs of commands are collected
+type collectingRouter struct {
+	types.Router
+	collected []bool
+}
+
+func (cr *collectingRouter) ProcessCommand(ctx context.Context, cmd *types.Command, collectResult bool) ([]*types.CmdResult, error) {
+	cr.collected = append(cr.collected, collectResult)
+	return cr.Router.ProcessCommand(ctx, cmd, collectResult)
+}
+
+func TestProcessCollectsOutputs(t *testing.T) {
+	captured := types.CommandMarker + "show version\nCisco IOS XR Software, Version 7.5.2\n\n\n"
+	tests := []struct {
+		name    string
+		rec     *recorder
+		collect bool
+	}{
+		{
+			name: "no recorder",
+		},
+		{
+			name: "neither results file nor observer",
+			rec:  &recorder{router: "r1"},
+		},
+		{
+			name:    "observer",
+			rec:     &recorder{router: "r1", o: &testObserver{records: make(map[string][]*results.Record)}},
+			collect: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands, err := types.GetCommands(writeTestFile(t, "collect.yaml", `commands:
+- command: "show version"
+`))
+			if err != nil {
+				t.Fatalf("failed to get commands with error: %+v", err)
+			}
+			rr, err := types.NewReplayRouter("r1", writeTestFile(t, "r1.log", captured), nil)
+			if err != nil {
+				t.Fatalf("failed to create replay router with error: %+v", err)
+			}
+			r := &collectingRouter{Router: rr}
+			if err := process(context.Background(), r, commands, nil, nil, tt.rec, nil); err != nil {
+				t.Fatalf("process failed with error: %+v", err)
+			}
+			if len(r.collected) != 1 || r.collected[0] != tt.collect {
+				t.Fatalf("expected output collected %t, got %v", tt.collect, r.collected)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// stopError is returned by a router's job when routers which have not been started yet must not be processed.
+type stopError struct {
+	error
+}
+
+func (e *stopError) Unwrap() error {
+	return e.error
+}
+
+// progress keeps track of routers processed by the worker pool.
+type progress struct {
+	mx         sync.Mutex
+	start      time.Time
+	total      int
+	inProgress int
+	succeeded  int
+	failed     []string
+	skipped    int
+}
+
+func (p *progress) started() {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.inProgress++
+}
+
+func (p *progress) finished(router string, err error) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.inProgress--
+	if err != nil {
+		p.failed = append(p.failed, router)
+	} else {
+		p.succeeded++
+	}
+	glog.Infof("progress: %d/%d routers finished, %d failed, %d in progress, elapsed %s",
+		p.succeeded+len(p.failed), p.total, len(p.failed), p.inProgress, time.Since(p.start).Round(time.Second))
+}
+
+func (p *progress) skip() {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.skipped++
+}
+
+func (p *progress) summary() string {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	s := fmt.Sprintf("processed %d routers in %s: %d succeeded, %d failed, %d skipped",
+		p.total, time.Since(p.start).Round(time.Second), p.succeeded, len(p.failed), p.skipped)
+	if len(p.failed) != 0 {
+		failed := append([]string{}, p.failed...)
+		sort.Strings(failed)
+		s += ", failed routers: " + strings.Join(failed, ", ")
+	}
+
+	return s
+}
+
+// runPool runs the job for every router, at most parallel routers are processed at the same time, when parallel
+// is 0, all routers are processed at once. When a job returns stopError or the context is cancelled, routers which
+// have not been started yet are skipped. The last error returned by jobs is returned.
+func runPool(ctx context.Context, routers []string, parallel int, job func(router string) error) error {
+	if parallel <= 0 || parallel > len(routers) {
+		parallel = len(routers)
+	}
+	p := &progress{
+		start: time.Now(),
+		total: len(routers),
+	}
+	var mx sync.Mutex
+	var lastErr error
+	stop := false
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for router := range queue {
+				mx.Lock()
+				if stop || ctx.Err() != nil {
+					mx.Unlock()
+					p.skip()
+					continue
+				}
+				mx.Unlock()
+				p.started()
+				err := job(router)
+				p.finished(router, err)
+				if err == nil {
+					continue
+				}
+				glog.Errorf("processing of router %s finished with error: %+v", router, err)
+				mx.Lock()
+				lastErr = err
+				var se *stopError
+				if errors.As(err, &se) {
+					stop = true
+				}
+				mx.Unlock()
+			}
+		}()
+	}
+	for _, router := range routers {
+		queue <- router
+	}
+	close(queue)
+	wg.Wait()
+	glog.Info(p.summary())
+
+	return lastErr
+}
@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunPool(t *testing.T) {
+	tests := []struct {
+		name      string
+		routers   int
+		parallel  int
+		failAt    int
+		stop      bool
+		maxActive int32
+		processed int32
+		fail      bool
+	}{
+		{
+			name:      "bounded parallelism",
+			routers:   10,
+			parallel:  3,
+			failAt:    -1,
+			maxActive: 3,
+			processed: 10,
+		},
+		{
+			name:      "unbounded parallelism",
+			routers:   5,
+			parallel:  0,
+			failAt:    -1,
+			maxActive: 5,
+			processed: 5,
+		},
+		{
+			name:      "failure does not stop",
+			routers:   6,
+			parallel:  1,
+			failAt:    1,
+			maxActive: 1,
+			processed: 6,
+			fail:      true,
+		},
+		{
+			name:      "failure stops remaining routers",
+			routers:   6,
+			parallel:  1,
+			failAt:    1,
+			stop:      true,
+			maxActive: 1,
+			processed: 2,
+			fail:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routers := make([]string, tt.routers)
+			for i := range routers {
+				routers[i] = fmt.Sprintf("router%d", i)
+			}
+			var active, maxActive, processed int32
+			var mx sync.Mutex
+			err := runPool(context.Background(), routers, tt.parallel, func(router string) error {
+				n := atomic.AddInt32(&active, 1)
+				defer atomic.AddInt32(&active, -1)
+				mx.Lock()
+				if n > maxActive {
+					maxActive = n
+				}
+				mx.Unlock()
+				i := atomic.AddInt32(&processed, 1) - 1
+				time.Sleep(20 * time.Millisecond)
+				if int(i) == tt.failAt {
+					if tt.stop {
+						return &stopError{fmt.Errorf("router %s failed", router)}
+					}
+					return fmt.Errorf("router %s failed", router)
+				}
+				return nil
+			})
+			if err != nil && !tt.fail {
+				t.Fatalf("supposed to succeed but failed with error: %+v", err)
+			}
+			if err == nil && tt.fail {
+				t.Fatalf("supposed to fail but succeeded")
+			}
+			if maxActive != tt.maxActive {
+				t.Fatalf("expected at most %d routers in progress but got %d", tt.maxActive, maxActive)
+			}
+			if processed != tt.processed {
+				t.Fatalf("expected %d processed routers but got %d", tt.processed, processed)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sbezverk/routercommander/pkg/messenger"
+	"github.com/sbezverk/routercommander/pkg/results"
+	"github.com/sbezverk/routercommander/pkg/summary"
+	"github.com/sbezverk/routercommander/pkg/types"
+)
+
+// ErrDuplicateTarget is returned by Run when more than one target has the same name, no target is processed
+var ErrDuplicateTarget = errors.New("duplicate router")
+
+// Target is a router processed by the runner, the router is connected when its processing starts.
+type Target struct {
+	Name string
+	// Connect returns the connected router and the recorder of the router's results, the recorder can be nil.
+	// When the returned error is wrapped by NewStopError, routers which have not been started yet are not processed.
+	Connect func() (types.Router, results.Recorder, error)
+	// Vars defines variables of the router, they override variables of the commands file
+	Vars map[string]string
+}
+
+// RouterTarget returns the target of the already connected router.
+func RouterTarget(r types.Router) *Target {
+	return &Target{
+		Name: r.GetName(),
+		Connect: func() (types.Router, results.Recorder, error) {
+			return r, nil, nil
+		},
+	}
+}
+
+// Observer receives the progress of the run, methods are called concurrently for different routers.
+type Observer interface {
+	// RouterStarted is called before the router is connected
+	RouterStarted(router string)
+	// CommandExecuted is called for every result of an executed command
+	CommandExecuted(router string, record *results.Record)
+	// Event is called for every event of the router, regardless of the notifier's events filter
+	Event(e *messenger.Event)
+	// RouterFinished is called with the router's summary when the router's processing is finished
+	RouterFinished(s *summary.RouterSummary)
+}
+
+// Options control the run, all options are optional.
+type Options struct {
+	// Parallel is the maximum number of routers processed at the same time, 0 processes all routers at once
+	Parallel int
+	// StopOnFailure stops processing of routers which have not been started yet when a router fails to connect
+	StopOnFailure bool
+	// Notifier receives events and logs of routers
+	Notifier messenger.Notifier
+	// Observer receives the progress of the run, outputs of all commands are collected for it
+	Observer Observer
+	// Vars defines variables which override variables of the commands file and of routers
+	Vars map[string]string
+}
+
+// Runner executes the commander's commands and tests on the set of routers.
+type Runner interface {
+	// Run processes all targets and returns the report with the summary of every router, the returned error
+	// is the last error returned by routers' processing. When the context is cancelled, routers which have not
+	// been started yet are skipped, routers in progress abort the in-flight command and stop after sending
+	// their notifications. Targets with the same name are rejected before any target is processed.
+	Run(ctx context.Context) (*summary.Report, error)
+}
+
+var _ Runner = &runner{}
+
+type runner struct {
+	commander *types.Commander
+	targets   []*Target
+	opts      Options
+}
+
+// New returns the runner of the commander on the targets, every target gets its own copy of the commander,
+// so the commander can be reused.
+func New(commander *types.Commander, targets []*Target, opts *Options) Runner {
+	r := &runner{
+		commander: commander,
+		targets:   targets,
+	}
+	if opts != nil {
+		r.opts = *opts
+	}
+
+	return r
+}
+
+func (r *runner) Run(ctx context.Context) (*summary.Report, error) {
+	report := summary.NewReport()
+	routers := make([]string, len(r.targets))
+	targets := make(map[string]*Target, len(r.targets))
+	for i, t := range r.targets {
+		// Routers' summaries, logs and results are named after the router, so names must be unique
+		if _, ok := targets[t.Name]; ok {
+			return report, fmt.Errorf("router %q: %w", t.Name, ErrDuplicateTarget)
+		}
+		routers[i] = t.Name
+		targets[t.Name] = t
+	}
+	err := runPool(ctx, routers, r.opts.Parallel, func(router string) error {
+		s := summary.NewRouterSummary(router)
+		report.Add(s)
+		err := r.processTarget(ctx, targets[router], s)
+		if r.opts.Observer != nil {
+			r.opts.Observer.RouterFinished(s)
+		}
+		return err
+	})
+	for _, router := range routers {
+		if !report.Has(router) {
+			s := summary.NewRouterSummary(router)
+			s.Finish(summary.StatusSkipped, nil)
+			report.Add(s)
+			if r.opts.Observer != nil {
+				r.opts.Observer.RouterFinished(s)
+			}
+		}
+	}
+
+	return report, err
+}
+
+func (r *runner) processTarget(ctx context.Context, t *Target, s *summary.RouterSummary) error {
+	if r.opts.Observer != nil {
+		r.opts.Observer.RouterStarted(t.Name)
+	}
+	rtr, rec, err := t.Connect()
+	if err != nil {
+		if r.opts.StopOnFailure {
+			err = NewStopError(err)
+		}
+		s.Finish(summary.StatusConnectionFailed, err)
+		return err
+	}
+	n := r.opts.Notifier
+	if r.opts.Observer != nil {
+		n = &observedNotifier{n: n, o: r.opts.Observer}
+	}
+	// Every router gets its own copy of commands, as commands' results and tests' values are stored in it,
+	// the copy is expanded with the router's variables
+	if err := process(ctx, rtr, r.commander.Clone(), r.vars(t, rtr), n, &recorder{router: t.Name, rec: rec, o: r.opts.Observer}, s); err != nil {
+		if ctx.Err() != nil {
+			s.Finish(summary.StatusInterrupted, err)
+		} else {
+			s.Finish(summary.StatusFailed, err)
+		}
+		return err
+	}
+	s.Finish(summary.StatusOK, nil)
+
+	return nil
+}
+
+// vars returns variables of the connected target, predefined variables are overridden by the target's variables
+// and the target's variables by variables of the run.
+func (r *runner) vars(t *Target, rtr types.Router) map[string]string {
+	vars := map[string]string{
+		types.VarHostname: t.Name,
+	}
+	if p := rtr.GetPlatform(); p != nil {
+		vars[types.VarPlatform] = p.Name()
+	}
+	for k, v := range t.Vars {
+		vars[k] = v
+	}
+	for k, v := range r.opts.Vars {
+		vars[k] = v
+	}
+
+	return vars
+}
+
+// NewStopError wraps the error returned by the target's Connect, when the target fails with it, routers
+// which have not been started yet are not processed.
+func NewStopError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*stopError); ok {
+		return err
+	}
+	return &stopError{err}
+}
+
+var _ messenger.Notifier = &observedNotifier{}
+
+// observedNotifier passes events to the observer before sending them to the notifier
+type observedNotifier struct {
+	n messenger.Notifier
+	o Observer
+}
+
+func (on *observedNotifier) Notify(fn string, b []byte) error {
+	if on.n == nil {
+		return nil
+	}
+	return on.n.Notify(fn, b)
+}
+
+func (on *observedNotifier) NotifyEvent(e *messenger.Event) error {
+	on.o.Event(e)
+	if on.n == nil {
+		return nil
+	}
+	return on.n.NotifyEvent(e)
+}
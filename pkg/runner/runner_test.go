@@ -0,0 +1,487 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sbezverk/routercommander/pkg/messenger"
+	"github.com/sbezverk/routercommander/pkg/results"
+	"github.com/sbezverk/routercommander/pkg/summary"
+	"github.com/sbezverk/routercommander/pkg/types"
+)
+
+type testObserver struct {
+	mx       sync.Mutex
+	started  []string
+	records  map[string][]*results.Record
+	events   []*messenger.Event
+	finished map[string]string
+}
+
+func (o *testObserver) RouterStarted(router string) {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	o.started = append(o.started, router)
+}
+
+func (o *testObserver) CommandExecuted(router string, record *results.Record) {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	o.records[router] = append(o.records[router], record)
+}
+
+func (o *testObserver) Event(e *messenger.Event) {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	o.events = append(o.events, e)
+}
+
+func (o *testObserver) hasEvent(router string, et messenger.EventType) bool {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	for _, e := range o.events {
+		if e.Router == router && e.Type == et {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *testObserver) RouterFinished(s *summary.RouterSummary) {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	o.finished[s.Router] = s.Status
+}
+
+func TestRunner(t *testing.T) {
+	commands, err := types.GetCommands(writeTestFile(t, "collect.yaml", `commands:
+- command: "show version"
+  process_result: true
+  patterns:
+  - pattern_string: "Version"
+`))
+	if err != nil {
+		t.Fatalf("failed to get commands with error: %+v", err)
+	}
+	captured := types.CommandMarker + "show version\nCisco IOS XR Software, Version 7.5.2\n\n\n"
+	replay := func(name string) *Target {
+		return &Target{
+			Name: name,
+			Connect: func() (types.Router, results.Recorder, error) {
+				r, err := types.NewReplayRouter(name, writeTestFile(t, name+".log", captured), nil)
+				return r, nil, err
+			},
+		}
+	}
+	failed := &Target{
+		Name: "r3",
+		Connect: func() (types.Router, results.Recorder, error) {
+			return nil, nil, fmt.Errorf("connection refused")
+		},
+	}
+	tests := []struct {
+		name          string
+		targets       []*Target
+		stopOnFailure bool
+		expect        map[string]string
+		fail          bool
+	}{
+		{
+			name:    "all routers succeed",
+			targets: []*Target{replay("r1"), replay("r2")},
+			expect:  map[string]string{"r1": summary.StatusOK, "r2": summary.StatusOK},
+		},
+		{
+			name:    "connection failure does not stop",
+			targets: []*Target{failed, replay("r1"), replay("r2")},
+			expect:  map[string]string{"r1": summary.StatusOK, "r2": summary.StatusOK, "r3": summary.StatusConnectionFailed},
+			fail:    true,
+		},
+		{
+			name:          "connection failure stops",
+			targets:       []*Target{failed, replay("r1"), replay("r2")},
+			stopOnFailure: true,
+			expect:        map[string]string{"r1": summary.StatusSkipped, "r2": summary.StatusSkipped, "r3": summary.StatusConnectionFailed},
+			fail:          true,
+		},
+		{
+			name:    "duplicate routers are rejected",
+			targets: []*Target{replay("r1"), replay("r2"), replay("r1")},
+			expect:  map[string]string{},
+			fail:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &testObserver{
+				records:  make(map[string][]*results.Record),
+				finished: make(map[string]string),
+			}
+			report, err := New(commands, tt.targets, &Options{
+				Parallel:      1,
+				StopOnFailure: tt.stopOnFailure,
+				Observer:      o,
+			}).Run(context.Background())
+			if err != nil && !tt.fail {
+				t.Fatalf("supposed to succeed but failed with error: %+v", err)
+			}
+			if err == nil && tt.fail {
+				t.Fatalf("supposed to fail but succeeded")
+			}
+			for _, s := range report.Routers() {
+				if s.Status != tt.expect[s.Router] {
+					t.Fatalf("router %s: expected status %q, got %q", s.Router, tt.expect[s.Router], s.Status)
+				}
+				if o.finished[s.Router] != s.Status {
+					t.Fatalf("router %s: observer got status %q, expected %q", s.Router, o.finished[s.Router], s.Status)
+				}
+				if s.Status != summary.StatusOK {
+					continue
+				}
+				if !o.hasEvent(s.Router, messenger.EventRunFinished) {
+					t.Fatalf("router %s: observer did not get %s event", s.Router, messenger.EventRunFinished)
+				}
+				records := o.records[s.Router]
+				if len(records) != 1 || records[0].Command != "show version" || len(records[0].PatternMatch) != 1 {
+					t.Fatalf("router %s: unexpected records %+v", s.Router, records)
+				}
+			}
+			if len(report.Routers()) != len(tt.expect) {
+				t.Fatalf("expected %d routers in the report, got %d", len(tt.expect), len(report.Routers()))
+			}
+		})
+	}
+}
+
+func TestRunnerRejectedCommand(t *testing.T) {
+	captured := types.CommandMarker + "show bgp sumary\n              ^\n% Invalid input detected at '^' marker.\n\n\n" +
+		types.CommandMarker + "show version\nCisco IOS XR Software, Version 7.5.2\n\n\n"
+	tests := []struct {
+		name    string
+		input   string
+		status  string
+		failed  int
+		records int
+	}{
+		{
+			name: "warn",
+			input: `commands:
+- command: "show bgp sumary"
+- command: "show version"
+`,
+			status:  summary.StatusOK,
+			failed:  1,
+			records: 2,
+		},
+		{
+			name: "continue",
+			input: `commands:
+- command: "show bgp sumary"
+  on_error: continue
+- command: "show version"
+`,
+			status:  summary.StatusOK,
+			records: 2,
+		},
+		{
+			name: "stop on error",
+			input: `collect:
+  stop_on_error: true
+commands:
+- command: "show bgp sumary"
+- command: "show version"
+`,
+			status:  summary.StatusFailed,
+			failed:  1,
+			records: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands, err := types.GetCommands(writeTestFile(t, "commands.yaml", tt.input))
+			if err != nil {
+				t.Fatalf("failed to get commands with error: %+v", err)
+			}
+			r, err := types.NewReplayRouter("r1", writeTestFile(t, "r1.log", captured), nil)
+			if err != nil {
+				t.Fatalf("failed to create replay router with error: %+v", err)
+			}
+			o := &testObserver{
+				records:  make(map[string][]*results.Record),
+				finished: make(map[string]string),
+			}
+			report, _ := New(commands, []*Target{RouterTarget(r)}, &Options{Observer: o}).Run(context.Background())
+			s := report.Routers()[0]
+			if s.Status != tt.status {
+				t.Fatalf("expected status %q, got %q", tt.status, s.Status)
+			}
+			if len(s.FailedCommands) != tt.failed {
+				t.Fatalf("expected %d failed commands, got %+v", tt.failed, s.FailedCommands)
+			}
+			records := o.records["r1"]
+			if len(records) != tt.records {
+				t.Fatalf("expected %d records, got %d", tt.records, len(records))
+			}
+			if records[0].Error != "% Invalid input detected at '^' marker." {
+				t.Fatalf("rejected command is not reflected in the record: %+v", records[0])
+			}
+			if len(records) > 1 && records[1].Error != "" {
+				t.Fatalf("accepted command is recorded with error: %+v", records[1])
+			}
+		})
+	}
+}
+
+func TestRunnerVars(t *testing.T) {
+	commands, err := types.GetCommands(writeTestFile(t, "commands.yaml", `vars:
+  VRF: GI
+commands:
+- command: "show bgp vrf {{.VRF}} summary"
+- command: "show running-config hostname {{.Hostname}}"
+`))
+	if err != nil {
+		t.Fatalf("failed to get commands with error: %+v", err)
+	}
+	captured := ""
+	for _, vrf := range []string{"GI", "BLUE", "RED"} {
+		captured += types.CommandMarker + "show bgp vrf " + vrf + " summary\nVRF: " + vrf + "\n\n\n"
+	}
+	captured += types.CommandMarker + "show running-config hostname r1\nhostname r1\n\n\n"
+	tests := []struct {
+		name       string
+		targetVars map[string]string
+		runVars    map[string]string
+		expect     string
+	}{
+		{
+			name:   "variables of the commands file",
+			expect: "show bgp vrf GI summary",
+		},
+		{
+			name:       "variables of the router",
+			targetVars: map[string]string{"VRF": "BLUE"},
+			expect:     "show bgp vrf BLUE summary",
+		},
+		{
+			name:       "variables of the run",
+			targetVars: map[string]string{"VRF": "BLUE"},
+			runVars:    map[string]string{"VRF": "RED"},
+			expect:     "show bgp vrf RED summary",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := types.NewReplayRouter("r1", writeTestFile(t, "r1.log", captured), nil)
+			if err != nil {
+				t.Fatalf("failed to create replay router with error: %+v", err)
+			}
+			target := RouterTarget(r)
+			target.Vars = tt.targetVars
+			o := &testObserver{
+				records:  make(map[string][]*results.Record),
+				finished: make(map[string]string),
+			}
+			if _, err := New(commands, []*Target{target}, &Options{Observer: o, Vars: tt.runVars}).Run(context.Background()); err != nil {
+				t.Fatalf("supposed to succeed but failed with error: %+v", err)
+			}
+			records := o.records["r1"]
+			if len(records) != 2 || records[0].Command != tt.expect || records[1].Command != "show running-config hostname r1" {
+				t.Fatalf("unexpected records: %+v", records)
+			}
+			// The commander is expanded per router, the original keeps references to variables
+			if commands.MainCommandGroup[0].Cmd != "show bgp vrf {{.VRF}} summary" {
+				t.Fatalf("original command is expanded: %q", commands.MainCommandGroup[0].Cmd)
+			}
+		})
+	}
+}
+
+func TestRunnerCaptures(t *testing.T) {
+	commands, err := types.GetCommands(writeTestFile(t, "commands.yaml", `tests:
+- command: "show processes memory"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: '(?P<pid>\d+)\s+(?P<process>\S+)\s+(?P<memory>\d+)'
+      store_captures: true
+    fields:
+    - group: memory
+      operation: "compare_with_value_gt"
+      value: "1000"
+    if_triggered_commands:
+    - command: "show processes {{.process}}"
+commands:
+- command: "show interfaces summary"
+  patterns:
+  - pattern_string: '(?P<intf>\S+) has input errors'
+    store_captures: true
+  - pattern_string: '(?P<down>\S+) is down'
+    store_captures: true
+- command: "show interface {{.intf}}"
+  foreach: intf
+- command: "show interface {{.down}} detail"
+- command: "show processes memory"
+  process_result: true
+  command_test_ids: [1]
+`))
+	if err != nil {
+		t.Fatalf("failed to get commands with error: %+v", err)
+	}
+	captured := types.CommandMarker + "show interfaces summary\nHu0/0/0/0 has input errors\nHu0/0/0/2 has input errors\n\n\n" +
+		types.CommandMarker + "show interface Hu0/0/0/0\n10 input errors\n\n\n" +
+		types.CommandMarker + "show interface Hu0/0/0/2\n5 input errors\n\n\n" +
+		types.CommandMarker + "show processes memory\n1234 bgp 5000\n\n\n" +
+		types.CommandMarker + "show processes bgp\nJob Id: 1234\n\n\n"
+	r, err := types.NewReplayRouter("r1", writeTestFile(t, "r1.log", captured), nil)
+	if err != nil {
+		t.Fatalf("failed to create replay router with error: %+v", err)
+	}
+	o := &testObserver{
+		records:  make(map[string][]*results.Record),
+		finished: make(map[string]string),
+	}
+	if _, err := New(commands, []*Target{RouterTarget(r)}, &Options{Observer: o}).Run(context.Background()); err != nil {
+		t.Fatalf("supposed to succeed but failed with error: %+v", err)
+	}
+	expect := []string{
+		"show interfaces summary",
+		"show interface Hu0/0/0/0",
+		"show interface Hu0/0/0/2",
+		"show processes bgp",
+		"show processes memory",
+	}
+	records := o.records["r1"]
+	if len(records) != len(expect) {
+		t.Fatalf("expected %d records, got %d: %+v", len(expect), len(records), records)
+	}
+	for i, re := range records {
+		if re.Command != expect[i] {
+			t.Fatalf("expected command %q, got %q", expect[i], re.Command)
+		}
+	}
+}
+
+func TestRunnerWhen(t *testing.T) {
+	commands, err := types.GetCommands(writeTestFile(t, "commands.yaml", `vars:
+  ROLE: pe
+tests:
+- command: "show cef drops"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: 'drops\s+(?P<drops>\d+)'
+    fields:
+    - group: drops
+      operation: "compare_with_value_gt"
+      value: "0"
+commands:
+- command: "show cef drops"
+  process_result: true
+  command_test_ids: [1]
+- command: "show controllers fabric plane all"
+  when: 'Platform == "nxos"'
+- command: "show bgp summary"
+  when: 'Vars.ROLE == "pe" && Hostname == "r1"'
+- command: "show cef drops detail"
+  when: '"show cef drops" in Triggered'
+- command: "show route summary"
+  when: 'Vars.ROLE == "p"'
+`))
+	if err != nil {
+		t.Fatalf("failed to get commands with error: %+v", err)
+	}
+	captured := types.CommandMarker + "show cef drops\ndrops 10\n\n\n" +
+		types.CommandMarker + "show bgp summary\nBGP router identifier 10.0.0.1\n\n\n" +
+		types.CommandMarker + "show cef drops detail\nNo route drops 10\n\n\n"
+	r, err := types.NewReplayRouter("r1", writeTestFile(t, "r1.log", captured), nil)
+	if err != nil {
+		t.Fatalf("failed to create replay router with error: %+v", err)
+	}
+	o := &testObserver{
+		records:  make(map[string][]*results.Record),
+		finished: make(map[string]string),
+	}
+	if _, err := New(commands, []*Target{RouterTarget(r)}, &Options{Observer: o}).Run(context.Background()); err != nil {
+		t.Fatalf("supposed to succeed but failed with error: %+v", err)
+	}
+	expect := []string{"show cef drops", "show bgp summary", "show cef drops detail"}
+	records := o.records["r1"]
+	if len(records) != len(expect) {
+		t.Fatalf("expected %d records, got %d: %+v", len(expect), len(records), records)
+	}
+	for i, re := range records {
+		if re.Command != expect[i] {
+			t.Fatalf("expected command %q, got %q", expect[i], re.Command)
+		}
+	}
+}
+
+// cancellingObserver cancels the run when the first command is executed
+type cancellingObserver struct {
+	testObserver
+	cancel context.CancelFunc
+}
+
+func (o *cancellingObserver) CommandExecuted(router string, record *results.Record) {
+	o.testObserver.CommandExecuted(router, record)
+	o.cancel()
+}
+
+func TestRunnerInterrupted(t *testing.T) {
+	commands, err := types.GetCommands(writeTestFile(t, "repro.yaml", `repro:
+  times: 1000
+  interval: 60
+commands:
+- command: "show clock"
+`))
+	if err != nil {
+		t.Fatalf("failed to get commands with error: %+v", err)
+	}
+	captured := ""
+	for i := 0; i < 3; i++ {
+		captured += types.CommandMarker + "show clock\n10:00:00.000 UTC\n\n\n"
+	}
+	r, err := types.NewReplayRouter("r1", writeTestFile(t, "r1.log", captured), nil)
+	if err != nil {
+		t.Fatalf("failed to create replay router with error: %+v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	o := &cancellingObserver{
+		testObserver: testObserver{
+			records:  make(map[string][]*results.Record),
+			finished: make(map[string]string),
+		},
+		cancel: cancel,
+	}
+	done := make(chan struct{})
+	var report *summary.Report
+	go func() {
+		defer close(done)
+		report, err = New(commands, []*Target{RouterTarget(r), {Name: "r2"}}, &Options{Parallel: 1, Observer: o}).Run(ctx)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the run was not interrupted")
+	}
+	if err == nil {
+		t.Fatal("expected interruption error")
+	}
+	if o.finished["r1"] != summary.StatusInterrupted || o.finished["r2"] != summary.StatusSkipped {
+		t.Fatalf("unexpected routers' statuses: %+v", o.finished)
+	}
+	if len(o.records["r1"]) != 1 {
+		t.Fatalf("expected a single iteration, got %d records", len(o.records["r1"]))
+	}
+	// The end of the run is notified even when the run is interrupted
+	if !o.hasEvent("r1", messenger.EventRunFinished) {
+		t.Fatalf("observer did not get %s event", messenger.EventRunFinished)
+	}
+	if len(report.Routers()) != 2 {
+		t.Fatalf("expected 2 routers in the report, got %d", len(report.Routers()))
+	}
+}
@@ -0,0 +1,575 @@
+package simulator
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/sbezverk/routercommander/pkg/platform"
+	"github.com/sbezverk/routercommander/pkg/types"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	invalidInput      = "                ^\n% Invalid input detected at '^' marker."
+	junosInvalidInput = "                ^\nunknown command."
+	morePrompt        = " --More-- "
+)
+
+var (
+	disablePaging = regexp.MustCompile(`^terminal\s+(l|le|len|length)\s+0$`)
+	configMode    = regexp.MustCompile(`^conf(igure)?(\s+t(erminal)?)?$`)
+	exitConfig    = regexp.MustCompile(`^(end|abort)$`)
+)
+
+// defaultShowPlatform is served for "show platform" of XR routers which do not define it
+const defaultShowPlatform = `Node              Type                       State             Config state
+--------------------------------------------------------------------------------
+0/RP0/CPU0        8800-RP(Active)            IOS XR RUN        NSHUT
+0/RP1/CPU0        8800-RP(Standby)           IOS XR RUN        NSHUT
+0/0/CPU0          88-LC0-36FH-M              IOS XR RUN        NSHUT
+0/1/CPU0          88-LC0-36FH-M              IOS XR RUN        NSHUT`
+
+// Response is the canned response of the simulated router to a command.
+type Response struct {
+	Command string `yaml:"command"`
+	// Output is returned every time the command is received
+	Output string `yaml:"output"`
+	// Outputs are returned in order when the command is repeated, the last output is returned once all are served
+	Outputs []string `yaml:"outputs"`
+	// Latency is the number of milliseconds the router takes to respond to the command
+	Latency int `yaml:"latency"`
+	// Drop closes the connection instead of responding to the command
+	Drop bool `yaml:"drop"`
+	// raw are byte streams recorded for the command, written as they are, without the echo and the prompt
+	raw []string
+}
+
+// Config defines the simulated router.
+type Config struct {
+	Hostname string `yaml:"hostname"`
+	// Platform is one of the supported platforms, iosxr by default
+	Platform string `yaml:"platform"`
+	// Prompt overrides the prompt derived from the platform and the hostname
+	Prompt   string `yaml:"prompt"`
+	Banner   string `yaml:"banner"`
+	Username string `yaml:"username"`
+	// Password is checked when set, otherwise any password is accepted
+	Password string `yaml:"password"`
+	// Latency is the number of milliseconds the router takes to respond to every command
+	Latency int `yaml:"latency"`
+	// PageLength is the number of lines of the output shown before --More--, until the paging is disabled
+	// by "terminal length 0", 0 disables paging
+	PageLength int `yaml:"page_length"`
+	// DropAfter closes every connection after the number of commands, 0 never closes connections
+	DropAfter int `yaml:"drop_after"`
+	// Log is a routercommander log, outputs of the logged commands are served in the order they were captured
+	Log string `yaml:"log"`
+	// Fixture is a session recorded by --record, recorded byte streams of commands are served exactly as they
+	// were captured, in the order they were captured, the prompt is taken from the recorded banner
+	Fixture   string      `yaml:"fixture"`
+	Responses []*Response `yaml:"responses"`
+}
+
+// LoadConfig reads the simulated router's configuration from the YAML file, the log and the fixture files
+// are relative to the configuration file unless their paths are absolute.
+func LoadConfig(fn string) (*Config, error) {
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read simulator configuration file %s with error: %+v", fn, err)
+	}
+	c := &Config{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal simulator configuration file %s with error: %+v", fn, err)
+	}
+	c.Log = relativeTo(fn, c.Log)
+	c.Fixture = relativeTo(fn, c.Fixture)
+
+	return c, nil
+}
+
+// relativeTo resolves the path relative to the directory of the configuration file.
+func relativeTo(config, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(config), path)
+}
+
+// Simulator is a SSH server emulating the shell of a router.
+type Simulator interface {
+	// Addr returns the address the simulator listens on
+	Addr() string
+	// Port returns the port the simulator listens on
+	Port() int
+	// Close stops the simulator and closes all connections
+	Close() error
+}
+
+var _ Simulator = &simulator{}
+
+type simulator struct {
+	config    *Config
+	driver    platform.Platform
+	prompt    string
+	ln        net.Listener
+	sshConfig *ssh.ServerConfig
+	banner    string
+	mx        sync.Mutex
+	responses map[string]*Response
+	served    map[string]int
+	conns     map[net.Conn]struct{}
+	closed    bool
+}
+
+// NewSimulator starts the simulated router listening on the address, for example 127.0.0.1:0.
+func NewSimulator(address string, config *Config) (Simulator, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	s := &simulator{
+		config:    config,
+		responses: make(map[string]*Response),
+		served:    make(map[string]int),
+		conns:     make(map[net.Conn]struct{}),
+	}
+	if err := s.loadFixture(); err != nil {
+		return nil, err
+	}
+	s.driver = platform.Get(config.Platform)
+	if err := s.loadResponses(); err != nil {
+		return nil, err
+	}
+	if s.prompt == "" {
+		s.prompt = config.Prompt
+	}
+	if s.prompt == "" {
+		s.prompt = defaultPrompt(s.driver.Name(), config.Hostname)
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate host key with error: %+v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create host key signer with error: %+v", err)
+	}
+	s.sshConfig = &ssh.ServerConfig{
+		PasswordCallback: s.checkPassword,
+		KeyboardInteractiveCallback: func(c ssh.ConnMetadata, client ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			answers, err := client("", "", []string{"Password: "}, []bool{false})
+			if err != nil {
+				return nil, err
+			}
+			return s.checkPassword(c, []byte(answers[0]))
+		},
+		NoClientAuth: config.Password == "",
+	}
+	s.sshConfig.AddHostKey(signer)
+	s.ln, err = net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s with error: %+v", address, err)
+	}
+	go s.accept()
+
+	return s, nil
+}
+
+func (s *simulator) loadFixture() error {
+	if s.config.Fixture == "" {
+		return nil
+	}
+	f, err := types.LoadFixture(s.config.Fixture)
+	if err != nil {
+		return err
+	}
+	if s.config.Platform == "" {
+		s.config.Platform = f.Platform
+	}
+	// The recorded banner ends with the router's prompt
+	s.banner = f.Banner
+	if s.config.Prompt == "" {
+		lines := strings.Split(strings.ReplaceAll(f.Banner, "\r", ""), "\n")
+		s.prompt = strings.TrimSpace(lines[len(lines)-1])
+	}
+	for _, e := range f.Exchanges {
+		key := normalizeCommand(e.Command)
+		r, ok := s.responses[key]
+		if !ok {
+			r = &Response{Command: key}
+			s.responses[key] = r
+		}
+		r.raw = append(r.raw, e.Raw)
+	}
+
+	return nil
+}
+
+func (s *simulator) loadResponses() error {
+	if s.config.Log != "" {
+		f, err := os.Open(s.config.Log)
+		if err != nil {
+			return fmt.Errorf("failed to open log %s with error: %+v", s.config.Log, err)
+		}
+		defer f.Close()
+		captured, err := types.ParseCapturedLog(f)
+		if err != nil {
+			return err
+		}
+		for _, c := range captured {
+			key := normalizeCommand(c.Cmd)
+			r, ok := s.responses[key]
+			if !ok {
+				r = &Response{Command: key}
+				s.responses[key] = r
+			}
+			r.Outputs = append(r.Outputs, string(c.Output))
+		}
+	}
+	// Responses defined in the configuration take precedence over the logged and recorded ones
+	for _, r := range s.config.Responses {
+		if r.Command == "" {
+			return fmt.Errorf("response without a command")
+		}
+		s.responses[normalizeCommand(r.Command)] = r
+	}
+	if _, ok := s.responses["show platform"]; !ok && s.driver.LocationsCommand() == "show platform" {
+		s.responses["show platform"] = &Response{Command: "show platform", Output: defaultShowPlatform}
+	}
+
+	return nil
+}
+
+// defaultPrompt returns the exec mode prompt of the platform's router
+func defaultPrompt(p string, hostname string) string {
+	if hostname == "" {
+		hostname = "router"
+	}
+	switch p {
+	case platform.IOSXR:
+		return "RP/0/RP0/CPU0:" + hostname + "#"
+	case platform.Junos:
+		return "admin@" + hostname + ">"
+	}
+
+	return hostname + "#"
+}
+
+func (s *simulator) checkPassword(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+	if s.config.Username != "" && c.User() != s.config.Username {
+		return nil, fmt.Errorf("unknown user %s", c.User())
+	}
+	if s.config.Password != "" && string(pass) != s.config.Password {
+		return nil, fmt.Errorf("invalid password for user %s", c.User())
+	}
+	return nil, nil
+}
+
+func (s *simulator) Addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *simulator) Port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *simulator) Close() error {
+	s.mx.Lock()
+	s.closed = true
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mx.Unlock()
+
+	return s.ln.Close()
+}
+
+func (s *simulator) accept() {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mx.Lock()
+		if s.closed {
+			s.mx.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+		s.mx.Unlock()
+		go s.serve(c)
+	}
+}
+
+func (s *simulator) serve(c net.Conn) {
+	defer func() {
+		c.Close()
+		s.mx.Lock()
+		delete(s.conns, c)
+		s.mx.Unlock()
+	}()
+	sc, chans, reqs, err := ssh.NewServerConn(c, s.sshConfig)
+	if err != nil {
+		glog.Warningf("simulator: failed to establish SSH connection with %s with error: %+v", c.RemoteAddr(), err)
+		return
+	}
+	defer sc.Close()
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		ch, chReqs, err := nc.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range chReqs {
+				req.Reply(req.Type == "pty-req" || req.Type == "shell", nil)
+			}
+		}()
+		// A connection serves a single shell session, the connection goes away with the session
+		s.shell(ch)
+		return
+	}
+}
+
+// session is the state of a shell session
+type session struct {
+	rw       *bufio.ReadWriter
+	paging   bool
+	config   bool
+	commands int
+}
+
+func (ss *session) write(s string) error {
+	return ss.writeRaw(strings.ReplaceAll(s, "\n", "\r\n"))
+}
+
+func (ss *session) writeRaw(s string) error {
+	if _, err := ss.rw.WriteString(s); err != nil {
+		return err
+	}
+	return ss.rw.Flush()
+}
+
+func (s *simulator) shell(ch ssh.Channel) {
+	defer ch.Close()
+	ss := &session{
+		rw:     bufio.NewReadWriter(bufio.NewReader(ch), bufio.NewWriter(ch)),
+		paging: s.config.PageLength > 0,
+	}
+	if s.banner != "" {
+		if err := ss.writeRaw(s.banner); err != nil {
+			return
+		}
+	} else {
+		banner := s.config.Banner
+		if banner != "" && !strings.HasSuffix(banner, "\n") {
+			banner += "\n"
+		}
+		if err := ss.write(banner + s.sessionPrompt(ss)); err != nil {
+			return
+		}
+	}
+	for {
+		line, err := ss.rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := normalizeCommand(line)
+		ss.commands++
+		if s.config.DropAfter > 0 && ss.commands > s.config.DropAfter {
+			glog.Infof("simulator: dropping connection after %d commands", s.config.DropAfter)
+			return
+		}
+		rp := s.respond(ss, cmd)
+		if rp.drop {
+			glog.Infof("simulator: dropping connection on command %q", cmd)
+			return
+		}
+		if rp.latency > 0 {
+			time.Sleep(time.Duration(rp.latency) * time.Millisecond)
+		}
+		if rp.raw {
+			// Recorded byte stream already carries the echo and the prompt
+			if err := ss.writeRaw(rp.output); err != nil {
+				return
+			}
+			continue
+		}
+		// The command is echoed back before its output
+		if err := ss.write(strings.TrimRight(line, "\r\n") + "\n"); err != nil {
+			return
+		}
+		if err := s.writeOutput(ss, rp.output); err != nil {
+			return
+		}
+		if err := ss.write(s.sessionPrompt(ss)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *simulator) sessionPrompt(ss *session) string {
+	if !ss.config {
+		return s.prompt
+	}
+	if p, ok := strings.CutSuffix(s.prompt, ">"); ok {
+		return p + "#"
+	}
+	p := strings.TrimSuffix(s.prompt, "#")
+	return p + "(config)#"
+}
+
+// isSessionCommand returns true if the command sets up the session
+func (s *simulator) isSessionCommand(cmd string) bool {
+	if strings.HasPrefix(cmd, "terminal ") || cmd == s.driver.DisablePaging() {
+		return true
+	}
+	for _, c := range s.driver.SessionSetup() {
+		if cmd == c {
+			return true
+		}
+	}
+	return false
+}
+
+// entersConfig returns true if the command enters the configuration mode
+func (s *simulator) entersConfig(cmd string) bool {
+	return cmd == s.driver.ConfigMode().Enter || configMode.MatchString(cmd)
+}
+
+// leavesConfig returns true if the command leaves the configuration mode
+func (s *simulator) leavesConfig(cmd string) bool {
+	mode := s.driver.ConfigMode()
+	if exitConfig.MatchString(cmd) || (mode.End != "" && cmd == mode.End) {
+		return true
+	}
+	if len(mode.Abort) != 0 && cmd == mode.Abort[len(mode.Abort)-1] {
+		return true
+	}
+	// Platforms without the command leaving the configuration mode leave it with the commit
+	return mode.End == "" && cmd == mode.Commit
+}
+
+// reply is the response of the simulated router to a command
+type reply struct {
+	output string
+	// raw is true when the output is a recorded byte stream
+	raw     bool
+	drop    bool
+	latency int
+}
+
+// respond returns the reply of the simulated router to the command
+func (s *simulator) respond(ss *session, cmd string) *reply {
+	rp := &reply{latency: s.config.Latency}
+	switch {
+	case cmd == "":
+		return &reply{}
+	case disablePaging.MatchString(cmd) || cmd == s.driver.DisablePaging():
+		ss.paging = false
+	case !ss.config && s.entersConfig(cmd):
+		ss.config = true
+		// The command entering the configuration mode is accepted
+		return rp
+	case ss.config && s.leavesConfig(cmd):
+		ss.config = false
+		return rp
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	r, ok := s.responses[cmd]
+	if !ok {
+		// Configuration lines and session commands are accepted silently
+		if !ss.config && !s.isSessionCommand(cmd) {
+			rp.output = invalidInput
+			if s.driver.Name() == platform.Junos {
+				rp.output = junosInvalidInput
+			}
+		}
+		return rp
+	}
+	if r.Latency > 0 {
+		rp.latency = r.Latency
+	}
+	if r.Drop {
+		return &reply{drop: true}
+	}
+	outputs := r.Outputs
+	if len(r.raw) != 0 {
+		outputs = r.raw
+		rp.raw = true
+	}
+	if len(outputs) == 0 {
+		rp.output = r.Output
+		return rp
+	}
+	i := s.served[cmd]
+	if i >= len(outputs) {
+		i = len(outputs) - 1
+	} else {
+		s.served[cmd]++
+	}
+	rp.output = outputs[i]
+
+	return rp
+}
+
+// writeOutput writes the output, when paging is enabled the output is split in pages and the next page
+// is written after any key but q is received.
+func (s *simulator) writeOutput(ss *session, output string) error {
+	if output == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if !ss.paging || len(lines) <= s.config.PageLength {
+		return ss.write(strings.Join(lines, "\n") + "\n")
+	}
+	for len(lines) != 0 {
+		n := s.config.PageLength
+		if n > len(lines) {
+			n = len(lines)
+		}
+		if err := ss.write(strings.Join(lines[:n], "\n") + "\n"); err != nil {
+			return err
+		}
+		lines = lines[n:]
+		if len(lines) == 0 {
+			break
+		}
+		if err := ss.write(morePrompt); err != nil {
+			return err
+		}
+		b, err := ss.rw.ReadByte()
+		if err != nil {
+			return err
+		}
+		// Erasing --More-- before the next page
+		if err := ss.write("\r" + strings.Repeat(" ", len(morePrompt)) + "\r"); err != nil {
+			return err
+		}
+		if b == 'q' || b == 'Q' {
+			break
+		}
+	}
+
+	return nil
+}
+
+func normalizeCommand(cmd string) string {
+	return strings.Join(strings.Fields(cmd), " ")
+}
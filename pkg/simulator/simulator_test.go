@@ -0,0 +1,298 @@
+package simulator
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbezverk/routercommander/pkg/platform"
+	"github.com/sbezverk/routercommander/pkg/types"
+	"golang.org/x/crypto/ssh"
+)
+
+func clientConfig(password string) *ssh.ClientConfig {
+	return &ssh.ClientConfig{
+		User:            "cisco",
+		Auth:            []ssh.AuthMethod{ssh.Password(password)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	}
+}
+
+func TestSimulatorRouter(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "router.log")
+	captured := types.CommandMarker + "show clock\n10:00:00.000 UTC\n\n\n" +
+		types.CommandMarker + "show clock\n10:00:05.000 UTC\n\n\n"
+	if err := os.WriteFile(log, []byte(captured), 0644); err != nil {
+		t.Fatalf("failed to write log with error: %+v", err)
+	}
+	sim, err := NewSimulator("127.0.0.1:0", &Config{
+		Hostname:   "r1",
+		Banner:     "Authorized access only",
+		Password:   "secret",
+		PageLength: 2,
+		Log:        log,
+		Responses: []*Response{
+			{Command: "show version", Output: "Cisco IOS XR Software, Version 7.5.2\nline 2\nline 3"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to start simulator with error: %+v", err)
+	}
+	defer sim.Close()
+	if _, err := types.NewRouter("127.0.0.1", sim.Port(), "", clientConfig("wrong"), nil); err == nil {
+		t.Fatalf("supposed to fail with invalid password but succeeded")
+	}
+	r, err := types.NewRouter("127.0.0.1", sim.Port(), "", clientConfig("secret"), nil)
+	if err != nil {
+		t.Fatalf("failed to connect with error: %+v", err)
+	}
+	defer r.Close()
+	if r.GetActiveRP() != "0/RP0/CPU0" || len(r.GetAllLCs()) != 2 {
+		t.Fatalf("unexpected platform, active RP: %s, line cards: %+v", r.GetActiveRP(), r.GetAllLCs())
+	}
+	tests := []struct {
+		cmd    string
+		expect string
+	}{
+		// Paging is disabled by the session setup, so the whole output is returned
+		{cmd: "show version", expect: "line 3"},
+		{cmd: "show clock", expect: "10:00:00.000"},
+		{cmd: "show clock", expect: "10:00:05.000"},
+		// The last logged output is repeated
+		{cmd: "show clock", expect: "10:00:05.000"},
+		{cmd: "show bogus", expect: "% Invalid input detected"},
+	}
+	for _, tt := range tests {
+		b, err := r.GetData(context.Background(), tt.cmd, false, 5)
+		if err != nil {
+			t.Fatalf("command %q failed with error: %+v", tt.cmd, err)
+		}
+		if !strings.Contains(string(b), tt.expect) {
+			t.Fatalf("command %q: expected output to contain %q, got %q", tt.cmd, tt.expect, string(b))
+		}
+	}
+}
+
+func TestSimulatorPaging(t *testing.T) {
+	sim, err := NewSimulator("127.0.0.1:0", &Config{
+		Platform:   platform.NXOS,
+		PageLength: 2,
+		Responses: []*Response{
+			{Command: "show version", Output: "line 1\nline 2\nline 3"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to start simulator with error: %+v", err)
+	}
+	defer sim.Close()
+	c, err := ssh.Dial("tcp", sim.Addr(), clientConfig(""))
+	if err != nil {
+		t.Fatalf("failed to dial simulator with error: %+v", err)
+	}
+	defer c.Close()
+	s, err := c.NewSession()
+	if err != nil {
+		t.Fatalf("failed to create session with error: %+v", err)
+	}
+	stdin, _ := s.StdinPipe()
+	stdout, _ := s.StdoutPipe()
+	if err := s.Shell(); err != nil {
+		t.Fatalf("failed to start shell with error: %+v", err)
+	}
+	rd := bufio.NewReader(stdout)
+	readUntil := func(marker string) string {
+		var sb strings.Builder
+		for !strings.HasSuffix(sb.String(), marker) {
+			b, err := rd.ReadByte()
+			if err != nil {
+				t.Fatalf("failed to read %q with error: %+v, got: %q", marker, err, sb.String())
+			}
+			sb.WriteByte(b)
+		}
+		return sb.String()
+	}
+	readUntil("router#")
+	io.WriteString(stdin, "show version\n")
+	if out := readUntil(morePrompt); strings.Contains(out, "line 3") {
+		t.Fatalf("expected first page only, got %q", out)
+	}
+	io.WriteString(stdin, " ")
+	if out := readUntil("router#"); !strings.Contains(out, "line 3") {
+		t.Fatalf("expected second page, got %q", out)
+	}
+}
+
+func TestSimulatorDrop(t *testing.T) {
+	sim, err := NewSimulator("127.0.0.1:0", &Config{
+		Responses: []*Response{
+			{Command: "show clock", Output: "10:00:00.000 UTC"},
+			{Command: "reload", Drop: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to start simulator with error: %+v", err)
+	}
+	defer sim.Close()
+	r, err := types.NewRouterWithOptions("127.0.0.1", sim.Port(), "", clientConfig(""), nil, &types.RouterOptions{
+		Reconnect: &types.ReconnectPolicy{MaxAttempts: 1},
+	})
+	if err != nil {
+		t.Fatalf("failed to connect with error: %+v", err)
+	}
+	defer r.Close()
+	_, err = r.GetData(context.Background(), "reload", false, 5)
+	if _, ok := err.(*types.ReconnectedError); !ok {
+		t.Fatalf("expected reconnected error, got: %+v", err)
+	}
+	b, err := r.GetData(context.Background(), "show clock", false, 5)
+	if err != nil {
+		t.Fatalf("command after reconnect failed with error: %+v", err)
+	}
+	if !strings.Contains(string(b), "10:00:00") {
+		t.Fatalf("unexpected output after reconnect: %q", string(b))
+	}
+}
+
+func TestSimulatorFixture(t *testing.T) {
+	sim, err := NewSimulator("127.0.0.1:0", &Config{
+		Hostname: "r1",
+		Banner:   "Authorized access only",
+		Responses: []*Response{
+			{Command: "show clock", Outputs: []string{"10:00:00.000 UTC", "10:00:05.000 UTC"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to start simulator with error: %+v", err)
+	}
+	defer sim.Close()
+	fn := filepath.Join(t.TempDir(), "r1.yaml")
+	r, err := types.NewRouterWithOptions("127.0.0.1", sim.Port(), "", clientConfig(""), nil, &types.RouterOptions{Record: fn})
+	if err != nil {
+		t.Fatalf("failed to connect with error: %+v", err)
+	}
+	cmds := []string{"show clock", "show clock", "show bogus"}
+	expect := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		b, err := r.GetData(context.Background(), cmd, false, 5)
+		if err != nil {
+			t.Fatalf("command %q failed with error: %+v", cmd, err)
+		}
+		expect = append(expect, string(b))
+	}
+	r.Close()
+	f, err := types.LoadFixture(fn)
+	if err != nil {
+		t.Fatalf("failed to load recorded fixture with error: %+v", err)
+	}
+	// Session setup commands and show platform are recorded too
+	if len(f.Exchanges) != len(cmds)+3 || !strings.HasSuffix(f.Banner, "RP/0/RP0/CPU0:r1#") {
+		t.Fatalf("unexpected fixture, banner: %q, exchanges: %d", f.Banner, len(f.Exchanges))
+	}
+	if last := f.Exchanges[len(f.Exchanges)-1]; !strings.HasPrefix(last.Raw, "show bogus\r\n") {
+		t.Fatalf("recorded stream must start with the echo, got: %q", last.Raw)
+	}
+	replay, err := NewSimulator("127.0.0.1:0", &Config{Fixture: fn})
+	if err != nil {
+		t.Fatalf("failed to start simulator with fixture with error: %+v", err)
+	}
+	defer replay.Close()
+	r, err = types.NewRouter("127.0.0.1", replay.Port(), "", clientConfig(""), nil)
+	if err != nil {
+		t.Fatalf("failed to connect to simulator with fixture with error: %+v", err)
+	}
+	defer r.Close()
+	if len(r.GetAllLCs()) != 2 {
+		t.Fatalf("unexpected line cards from recorded show platform: %+v", r.GetAllLCs())
+	}
+	for i, cmd := range cmds {
+		b, err := r.GetData(context.Background(), cmd, false, 5)
+		if err != nil {
+			t.Fatalf("recorded command %q failed with error: %+v", cmd, err)
+		}
+		if string(b) != expect[i] {
+			t.Fatalf("recorded command %q: expected %q, got %q", cmd, expect[i], string(b))
+		}
+	}
+}
+
+func TestSimulatorPlatforms(t *testing.T) {
+	for _, p := range platform.Names() {
+		t.Run(p, func(t *testing.T) {
+			sim, err := NewSimulator("127.0.0.1:0", &Config{
+				Platform: p,
+				Responses: []*Response{
+					{Command: "show version", Output: "Version 1.2.3"},
+				},
+			})
+			if err != nil {
+				t.Fatalf("failed to start simulator with error: %+v", err)
+			}
+			defer sim.Close()
+			r, err := types.NewRouter("127.0.0.1", sim.Port(), p, clientConfig(""), nil)
+			if err != nil {
+				t.Fatalf("failed to connect with error: %+v", err)
+			}
+			defer r.Close()
+			if r.GetPlatform().Name() != p {
+				t.Fatalf("expected platform %s, got %s", p, r.GetPlatform().Name())
+			}
+			b, err := r.GetData(context.Background(), "show version", false, 5)
+			if err != nil {
+				t.Fatalf("command failed with error: %+v", err)
+			}
+			if !strings.Contains(string(b), "Version 1.2.3") {
+				t.Fatalf("unexpected output: %q", string(b))
+			}
+			if _, err := r.ProcessCommand(context.Background(), &types.Command{
+				Cmd:    "description",
+				Config: &types.ConfigBlock{Lines: []string{"hostname test"}},
+			}, true); err != nil {
+				t.Fatalf("configuration block failed with error: %+v", err)
+			}
+			b, err = r.GetData(context.Background(), "show bogus", false, 5)
+			if err != nil {
+				t.Fatalf("command failed with error: %+v", err)
+			}
+			if r.GetPlatform().FindError(b) == nil {
+				t.Fatalf("invalid command is not detected in %q", string(b))
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		log     string
+		fixture string
+		expLog  string
+		expFix  string
+	}{
+		{name: "relative paths", log: "r1.log", fixture: "fixtures/r1.yaml", expLog: filepath.Join(dir, "r1.log"), expFix: filepath.Join(dir, "fixtures/r1.yaml")},
+		{name: "absolute paths", log: "/var/log/r1.log", fixture: "/fixtures/r1.yaml", expLog: "/var/log/r1.log", expFix: "/fixtures/r1.yaml"},
+		{name: "no paths"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := filepath.Join(dir, "simulator.yaml")
+			b := "hostname: r1\nlog: \"" + tt.log + "\"\nfixture: \"" + tt.fixture + "\"\n"
+			if err := os.WriteFile(fn, []byte(b), 0644); err != nil {
+				t.Fatalf("failed to write configuration with error: %+v", err)
+			}
+			c, err := LoadConfig(fn)
+			if err != nil {
+				t.Fatalf("failed to load configuration with error: %+v", err)
+			}
+			if c.Log != tt.expLog || c.Fixture != tt.expFix {
+				t.Fatalf("expected log %q and fixture %q, got %q and %q", tt.expLog, tt.expFix, c.Log, c.Fixture)
+			}
+		})
+	}
+}
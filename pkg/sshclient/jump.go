@@ -0,0 +1,85 @@
+package sshclient
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/golang/glog"
+	"github.com/sbezverk/routercommander/pkg/types"
+	"golang.org/x/crypto/ssh"
+)
+
+// Hop is a jump host of the proxy jump chain used to reach a router.
+type Hop struct {
+	Address  string
+	Port     int
+	Username string
+	Auth     *SSHAuth
+}
+
+// NewProxyJumpDialer returns the dial function tunnelling the router's SSH connection through the chain
+// of jump hosts, host keys of every hop are verified by the verifier.
+func NewProxyJumpDialer(v Verifier, hops []*Hop) (types.DialFunc, error) {
+	configs := make([]*ssh.ClientConfig, len(hops))
+	addrs := make([]string, len(hops))
+	for i, hop := range hops {
+		c, err := v.GetSSHConfigWithAuth(hop.Username, hop.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get SSH configuration for jump host %s with error: %+v", hop.Address, err)
+		}
+		configs[i] = c
+		addrs[i] = net.JoinHostPort(hop.Address, strconv.Itoa(hop.Port))
+	}
+
+	return func(addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+		clients := make([]*ssh.Client, 0, len(configs))
+		closeAll := func() {
+			for i := len(clients) - 1; i >= 0; i-- {
+				clients[i].Close()
+			}
+		}
+		var via *ssh.Client
+		for i := range configs {
+			c, err := dialVia(via, addrs[i], configs[i])
+			if err != nil {
+				closeAll()
+				return nil, fmt.Errorf("failed to dial jump host %s with error: %+v", addrs[i], err)
+			}
+			if glog.V(5) {
+				glog.Infof("Successfully dialed jump host: %s", addrs[i])
+			}
+			clients = append(clients, c)
+			via = c
+		}
+		c, err := dialVia(via, addr, config)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		// Jump hosts' connections are released when the router's connection goes away
+		go func() {
+			_ = c.Wait()
+			closeAll()
+		}()
+
+		return c, nil
+	}, nil
+}
+
+func dialVia(via *ssh.Client, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if via == nil {
+		return ssh.Dial("tcp", addr, config)
+	}
+	conn, err := via.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	cc, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return ssh.NewClient(cc, chans, reqs), nil
+}
@@ -0,0 +1,288 @@
+package sshclient
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Verifier verifies host keys of routers against the known hosts file, a single verifier is shared by all
+// routers, so host keys learnt concurrently are added to the file one by one.
+type Verifier interface {
+	GetSSHConfig(user, pass string) *ssh.ClientConfig
+	GetSSHConfigWithAuth(user string, auth *SSHAuth) (*ssh.ClientConfig, error)
+	Close() error
+}
+
+// SSHAuth defines the authentication methods to offer to a router, methods are offered in the order:
+// public key (private key file and ssh-agent keys), password and keyboard-interactive.
+type SSHAuth struct {
+	Password             string
+	PrivateKeyFile       string
+	PrivateKeyPassphrase string
+	UseAgent             bool
+	KeyboardInteractive  bool
+}
+
+// HasKeyAuth returns true if at least one public key based method is configured.
+func (a *SSHAuth) HasKeyAuth() bool {
+	return a != nil && (a.PrivateKeyFile != "" || a.UseAgent)
+}
+
+var _ Verifier = &verifier{}
+
+type verifier struct {
+	mx         sync.Mutex
+	f          *os.File
+	knownHosts map[string]ssh.PublicKey
+	insecure   bool
+	sshClient  *ssh.ClientConfig
+	agent      agent.ExtendedAgent
+}
+
+func (v *verifier) GetSSHConfig(user, pass string) *ssh.ClientConfig {
+	c := ssh.ClientConfig{}
+	if v.sshClient != nil {
+		c = *v.sshClient
+	}
+	c.User = user
+	c.Auth = []ssh.AuthMethod{
+		ssh.Password(pass),
+	}
+
+	return &c
+}
+
+func (v *verifier) GetSSHConfigWithAuth(user string, auth *SSHAuth) (*ssh.ClientConfig, error) {
+	if auth == nil {
+		return nil, fmt.Errorf("no SSH authentication is specified for user %s", user)
+	}
+	c := ssh.ClientConfig{}
+	if v.sshClient != nil {
+		c = *v.sshClient
+	}
+	c.User = user
+	c.Auth = make([]ssh.AuthMethod, 0)
+	signers := make([]ssh.Signer, 0)
+	if auth.PrivateKeyFile != "" {
+		s, err := loadPrivateKey(auth.PrivateKeyFile, auth.PrivateKeyPassphrase)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, s)
+	}
+	var ag agent.ExtendedAgent
+	if auth.UseAgent {
+		var err error
+		if ag, err = v.getAgent(); err != nil {
+			return nil, err
+		}
+	}
+	// All public keys must be offered by a single method, ssh client does not retry a method with the same name.
+	if len(signers) != 0 || ag != nil {
+		c.Auth = append(c.Auth, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			if ag == nil {
+				return signers, nil
+			}
+			as, err := ag.Signers()
+			if err != nil {
+				glog.Warningf("failed to get keys from ssh-agent with error: %+v", err)
+				return signers, nil
+			}
+			return append(append([]ssh.Signer{}, signers...), as...), nil
+		}))
+	}
+	if auth.Password != "" {
+		c.Auth = append(c.Auth, ssh.Password(auth.Password))
+	}
+	if auth.KeyboardInteractive {
+		if auth.Password == "" {
+			return nil, fmt.Errorf("keyboard-interactive authentication for user %s requires a password", user)
+		}
+		pass := auth.Password
+		c.Auth = append(c.Auth, ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := range questions {
+				answers[i] = pass
+			}
+			return answers, nil
+		}))
+	}
+	if len(c.Auth) == 0 {
+		return nil, fmt.Errorf("no SSH authentication method is configured for user %s", user)
+	}
+
+	return &c, nil
+}
+
+// getAgent returns the connection to the ssh-agent pointed by SSH_AUTH_SOCK, the connection
+// is established once and shared by all routers using the verifier.
+func (v *verifier) getAgent() (agent.ExtendedAgent, error) {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	if v.agent != nil {
+		return v.agent, nil
+	}
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, fmt.Errorf("ssh-agent authentication is requested but SSH_AUTH_SOCK is not set")
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ssh-agent at %s with error: %+v", sock, err)
+	}
+	v.agent = agent.NewClient(conn)
+
+	return v.agent, nil
+}
+
+func loadPrivateKey(fn string, passphrase string) (ssh.Signer, error) {
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key file %s with error: %+v", fn, err)
+	}
+	s, err := ssh.ParsePrivateKey(b)
+	var pe *ssh.PassphraseMissingError
+	if errors.As(err, &pe) && passphrase != "" {
+		// The passphrase can be the global one, it is used only for encrypted keys
+		s, err = ssh.ParsePrivateKeyWithPassphrase(b, []byte(passphrase))
+	}
+	if err != nil {
+		if errors.As(err, &pe) {
+			return nil, fmt.Errorf("private key file %s is encrypted, passphrase is required", fn)
+		}
+		return nil, fmt.Errorf("failed to parse private key file %s with error: %+v", fn, err)
+	}
+
+	return s, nil
+}
+
+func (v *verifier) remoteHostKeyCallback(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	if glog.V(5) {
+		glog.Infof("Callback is called with hostname: %s remote address: %s", strings.Split(hostname, ":")[0], remote.String())
+	}
+	if !v.insecure {
+		v.mx.Lock()
+		defer v.mx.Unlock()
+		normalizedHost := normalizeKnownHost(hostname)
+		if k, ok := v.knownHosts[normalizedHost]; ok {
+			if bytes.Equal(k.Marshal(), key.Marshal()) {
+				return nil
+			}
+			return fmt.Errorf("host key mismatch for host %s, expected: %s, got: %s", normalizedHost, ssh.FingerprintSHA256(k), ssh.FingerprintSHA256(key))
+		} else {
+			// Need to update known hosts file with the new host key
+			if v.f == nil {
+				return fmt.Errorf("failed to update known hosts file with new host key for host %s: verifier is closed", normalizedHost)
+			}
+			line := knownhosts.Line([]string{normalizedHost}, key)
+			if _, err := v.f.WriteString(line + "\n"); err != nil {
+				return fmt.Errorf("failed to update known hosts file with new host key for host %s: %+v", normalizedHost, err)
+			}
+			v.knownHosts[normalizedHost] = key
+		}
+
+	}
+
+	return nil
+}
+
+// Close closes the known hosts file.
+func (v *verifier) Close() error {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	if v.f == nil {
+		return nil
+	}
+	err := v.f.Close()
+	v.f = nil
+
+	return err
+}
+
+func normalizeKnownHost(hostname string) string {
+	host := strings.TrimSpace(hostname)
+
+	h, p, err := net.SplitHostPort(host)
+	if err != nil {
+		host = strings.TrimPrefix(host, "[")
+		host = strings.TrimSuffix(host, "]")
+		return strings.ToLower(host)
+	}
+
+	h = strings.TrimPrefix(h, "[")
+	h = strings.TrimSuffix(h, "]")
+	h = strings.ToLower(h)
+
+	if p == "22" {
+		return h
+	}
+
+	return h + ":" + p
+}
+
+func NewVerifier(knownHostsFile string, isInsecure bool) (Verifier, error) {
+	v := &verifier{
+		sshClient: nil,
+	}
+	v.insecure = isInsecure
+	var err error
+	// New host keys are always appended, even when the file is modified by another process
+	v.f, err = os.OpenFile(knownHostsFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open known hosts file %s with error: %+v", knownHostsFile, err)
+	}
+	v.knownHosts = make(map[string]ssh.PublicKey)
+	scanner := bufio.NewScanner(v.f)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		_, hosts, key, _, _, err := ssh.ParseKnownHosts(line)
+		if err != nil {
+			v.f.Close()
+			return nil, fmt.Errorf("failed to parse known hosts file %s with error: %+v", knownHostsFile, err)
+		}
+		for _, host := range hosts {
+			v.knownHosts[normalizeKnownHost(host)] = key
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		v.f.Close()
+		return nil, fmt.Errorf("failed to read known hosts file %s with error: %+v", knownHostsFile, err)
+	}
+
+	c := ssh.Config{}
+	c.SetDefaults()
+	c.KeyExchanges = append(
+		c.KeyExchanges,
+		"diffie-hellman-group-exchange-sha256",
+		"diffie-hellman-group-exchange-sha1",
+		"diffie-hellman-group1-sha1",
+	)
+	c.Ciphers = append(
+		c.Ciphers,
+		"aes128-cbc",
+		"aes192-cbc",
+		"aes256-cbc",
+		"3des-cbc")
+
+	v.sshClient = &ssh.ClientConfig{
+		//		User: login,
+		//		Auth: []ssh.AuthMethod{
+		//			ssh.Password(pass),
+		//		},
+		Config:          c,
+		HostKeyCallback: v.remoteHostKeyCallback,
+	}
+
+	return v, nil
+}
@@ -1,12 +1,14 @@
-package main
+package sshclient
 
 import (
 	"bufio"
 	"crypto/rand"
 	"crypto/rsa"
+	"encoding/pem"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"golang.org/x/crypto/ssh"
@@ -46,9 +48,7 @@ func newTestVerifier(t *testing.T, knownHostsFile string, isInsecure bool) *veri
 		t.Fatalf("expected concrete verifier implementation")
 	}
 	t.Cleanup(func() {
-		if v.f != nil {
-			_ = v.f.Close()
-		}
+		_ = v.Close()
 	})
 
 	return v
@@ -138,6 +138,41 @@ func TestRemoteHostKeyCallbackTOFUAndMismatch(t *testing.T) {
 	}
 }
 
+func TestRemoteHostKeyCallbackConcurrent(t *testing.T) {
+	khFile := filepath.Join(t.TempDir(), "known_hosts")
+	v := newTestVerifier(t, khFile, false)
+	key := newTestPublicKey(t)
+	hosts := 20
+	var wg sync.WaitGroup
+	for i := 0; i < hosts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			host := "router" + strings.Repeat("x", i) + ":22"
+			if err := v.remoteHostKeyCallback(host, dummyAddr("192.0.2.10:22"), key); err != nil {
+				t.Errorf("failed to learn host key of %s with error: %v", host, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := v.Close(); err != nil {
+		t.Fatalf("failed to close verifier with error: %v", err)
+	}
+	// Reloading the file must find every learned host
+	v = newTestVerifier(t, khFile, false)
+	if len(v.knownHosts) != hosts {
+		t.Fatalf("expected %d hosts in known hosts file, got %d", hosts, len(v.knownHosts))
+	}
+	// A new host key is appended after existing ones
+	if err := v.remoteHostKeyCallback("router:2222", dummyAddr("192.0.2.10:2222"), key); err != nil {
+		t.Fatalf("failed to learn host key with error: %v", err)
+	}
+	v.Close()
+	if v = newTestVerifier(t, khFile, false); len(v.knownHosts) != hosts+1 {
+		t.Fatalf("expected %d hosts in known hosts file, got %d", hosts+1, len(v.knownHosts))
+	}
+}
+
 func TestNewVerifierProvidesSSHConfig(t *testing.T) {
 	tmpDir := t.TempDir()
 	khFile := filepath.Join(tmpDir, "known_hosts")
@@ -157,3 +192,75 @@ func TestNewVerifierProvidesSSHConfig(t *testing.T) {
 		t.Fatalf("expected host key callback to be configured")
 	}
 }
+
+func writeTestPrivateKey(t *testing.T, dir string, passphrase string) string {
+	t.Helper()
+	privateKey, err := rsaGenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(privateKey, "routercommander test")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(privateKey, "routercommander test", []byte(passphrase))
+	}
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	fn := filepath.Join(dir, "id_rsa")
+	if err := os.WriteFile(fn, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("failed to write private key file: %v", err)
+	}
+	return fn
+}
+
+func TestGetSSHConfigWithAuth(t *testing.T) {
+	tmpDir := t.TempDir()
+	v := newTestVerifier(t, filepath.Join(tmpDir, "known_hosts"), false)
+	plainKey := writeTestPrivateKey(t, t.TempDir(), "")
+	encryptedKey := writeTestPrivateKey(t, t.TempDir(), "secret")
+
+	tests := []struct {
+		name    string
+		auth    *SSHAuth
+		methods int
+		fail    bool
+	}{
+		{name: "password only", auth: &SSHAuth{Password: "cisco123"}, methods: 1},
+		{name: "private key only", auth: &SSHAuth{PrivateKeyFile: plainKey}, methods: 1},
+		{name: "private key and password", auth: &SSHAuth{PrivateKeyFile: plainKey, Password: "cisco123"}, methods: 2},
+		{name: "encrypted private key", auth: &SSHAuth{PrivateKeyFile: encryptedKey, PrivateKeyPassphrase: "secret"}, methods: 1},
+		{name: "encrypted private key without passphrase", auth: &SSHAuth{PrivateKeyFile: encryptedKey}, fail: true},
+		{name: "encrypted private key with wrong passphrase", auth: &SSHAuth{PrivateKeyFile: encryptedKey, PrivateKeyPassphrase: "wrong"}, fail: true},
+		{name: "plain private key with passphrase", auth: &SSHAuth{PrivateKeyFile: plainKey, PrivateKeyPassphrase: "secret"}, methods: 1},
+		{name: "missing private key file", auth: &SSHAuth{PrivateKeyFile: filepath.Join(tmpDir, "missing")}, fail: true},
+		{name: "password and keyboard-interactive", auth: &SSHAuth{Password: "cisco123", KeyboardInteractive: true}, methods: 2},
+		{name: "keyboard-interactive without password", auth: &SSHAuth{KeyboardInteractive: true}, fail: true},
+		{name: "no methods", auth: &SSHAuth{}, fail: true},
+		{name: "nil auth", auth: nil, fail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := v.GetSSHConfigWithAuth("cisco", tt.auth)
+			if err != nil && !tt.fail {
+				t.Fatalf("supposed to succeed but failed with error: %+v", err)
+			}
+			if err == nil && tt.fail {
+				t.Fatalf("supposed to fail but succeeded")
+			}
+			if err != nil {
+				return
+			}
+			if cfg.User != "cisco" {
+				t.Fatalf("expected ssh user %q, got %q", "cisco", cfg.User)
+			}
+			if len(cfg.Auth) != tt.methods {
+				t.Fatalf("expected %d auth methods, got %d", tt.methods, len(cfg.Auth))
+			}
+			if cfg.HostKeyCallback == nil {
+				t.Fatalf("expected host key callback to be configured")
+			}
+		})
+	}
+}
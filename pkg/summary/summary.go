@@ -0,0 +1,318 @@
+package summary
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Router's status at the end of the run
+const (
+	StatusOK               = "ok"
+	StatusFailed           = "failed"
+	StatusConnectionFailed = "connection failed"
+	StatusSkipped          = "skipped"
+	StatusInterrupted      = "interrupted"
+)
+
+// PatternMatch is a line of a command's output matching one of the command's patterns.
+type PatternMatch struct {
+	Cmd   string
+	Match string
+}
+
+// RouterSummary is the outcome of processing of a single router.
+type RouterSummary struct {
+	Router           string
+	Status           string
+	Error            string
+	Duration         time.Duration
+	CommandsExecuted int
+	FailedCommands   []string
+	PatternMatches   []*PatternMatch
+	TriggeredTests   []int
+	// ReproConfigured is true when the commands file defines the repro section
+	ReproConfigured bool
+	ReproTriggered  bool
+	start           time.Time
+	seen            map[string]bool
+}
+
+// NewRouterSummary creates the summary of the router, the router is considered connected and its
+// processing started.
+func NewRouterSummary(router string) *RouterSummary {
+	return &RouterSummary{
+		Router:         router,
+		Status:         StatusOK,
+		FailedCommands: make([]string, 0),
+		PatternMatches: make([]*PatternMatch, 0),
+		TriggeredTests: make([]int, 0),
+		start:          time.Now(),
+		seen:           make(map[string]bool),
+	}
+}
+
+// CommandExecuted adds the number of executed commands, a command executed at several locations or several times
+// is counted as several commands.
+func (s *RouterSummary) CommandExecuted(n int) {
+	if s == nil {
+		return
+	}
+	s.CommandsExecuted += n
+}
+
+// CommandFailed adds the command to the list of failed commands.
+func (s *RouterSummary) CommandFailed(cmd string) {
+	if s == nil {
+		return
+	}
+	s.FailedCommands = append(s.FailedCommands, cmd)
+}
+
+// PatternsMatched adds the command's pattern matches, the same match found in several iterations is added once.
+func (s *RouterSummary) PatternsMatched(cmd string, matches []string) {
+	if s == nil {
+		return
+	}
+	for _, m := range matches {
+		key := cmd + "\x00" + m
+		if s.seen[key] {
+			continue
+		}
+		s.seen[key] = true
+		s.PatternMatches = append(s.PatternMatches, &PatternMatch{Cmd: cmd, Match: m})
+	}
+}
+
+// TestsTriggered adds ids of the triggered tests.
+func (s *RouterSummary) TestsTriggered(ids []int) {
+	if s == nil {
+		return
+	}
+	for _, id := range ids {
+		found := false
+		for _, e := range s.TriggeredTests {
+			if e == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s.TriggeredTests = append(s.TriggeredTests, id)
+		}
+	}
+	sort.Ints(s.TriggeredTests)
+}
+
+// SetReproTriggered sets if the repro triggered the failure condition, it is called only when the repro is configured.
+func (s *RouterSummary) SetReproTriggered(triggered bool) {
+	if s == nil {
+		return
+	}
+	s.ReproConfigured = true
+	s.ReproTriggered = triggered
+}
+
+// Repro returns the outcome of the repro for reports, "-" when the repro is not configured.
+func (s *RouterSummary) Repro() string {
+	if !s.ReproConfigured {
+		return "-"
+	}
+	if s.ReproTriggered {
+		return "yes"
+	}
+	return "no"
+}
+
+// Finish sets the final status of the router's processing.
+func (s *RouterSummary) Finish(status string, err error) {
+	if s == nil {
+		return
+	}
+	s.Status = status
+	if err != nil {
+		s.Error = err.Error()
+	}
+	if !s.start.IsZero() {
+		s.Duration = time.Since(s.start)
+	}
+}
+
+// Report is the summary of all routers processed in the run.
+type Report struct {
+	mx      sync.Mutex
+	start   time.Time
+	routers []*RouterSummary
+}
+
+// NewReport creates an empty report, the run is considered started.
+func NewReport() *Report {
+	return &Report{
+		start:   time.Now(),
+		routers: make([]*RouterSummary, 0),
+	}
+}
+
+// Add adds the router's summary to the report, safe to be called concurrently.
+func (r *Report) Add(s *RouterSummary) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.routers = append(r.routers, s)
+}
+
+// Routers returns routers' summaries sorted by the router name.
+func (r *Report) Routers() []*RouterSummary {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	routers := append([]*RouterSummary{}, r.routers...)
+	sort.Slice(routers, func(i, j int) bool {
+		return routers[i].Router < routers[j].Router
+	})
+
+	return routers
+}
+
+// Has returns true if the router's summary is in the report.
+func (r *Report) Has(router string) bool {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	for _, s := range r.routers {
+		if s.Router == router {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the report as a text table.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROUTER\tSTATUS\tCOMMANDS\tFAILED\tMATCHES\tTRIGGERED TESTS\tREPRO\tDURATION")
+	for _, s := range r.Routers() {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%s\t%s\t%s\n", s.Router, s.Status, s.CommandsExecuted, len(s.FailedCommands),
+			len(s.PatternMatches), ids(s.TriggeredTests), s.Repro(), s.Duration.Round(time.Second))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, s := range r.Routers() {
+		if s.Error != "" {
+			fmt.Fprintf(w, "%s: %s\n", s.Router, s.Error)
+		}
+	}
+
+	return nil
+}
+
+// WriteMarkdown writes the report as a Markdown document.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# routercommander summary\n\nStarted: %s, duration: %s\n\n", r.start.Format(time.RFC3339), time.Since(r.start).Round(time.Second))
+	sb.WriteString("| Router | Status | Commands | Failed commands | Pattern matches | Triggered tests | Repro triggered | Duration |\n")
+	sb.WriteString("|---|---|---|---|---|---|---|---|\n")
+	routers := r.Routers()
+	for _, s := range routers {
+		fmt.Fprintf(&sb, "| %s | %s | %d | %s | %d | %s | %s | %s |\n", mdEscape(s.Router), mdEscape(s.Status), s.CommandsExecuted,
+			mdEscape(strings.Join(s.FailedCommands, ", ")), len(s.PatternMatches), ids(s.TriggeredTests), s.Repro(), s.Duration.Round(time.Second))
+	}
+	for _, s := range routers {
+		if s.Error == "" && len(s.PatternMatches) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n## %s\n\n", s.Router)
+		if s.Error != "" {
+			fmt.Fprintf(&sb, "Error: %s\n\n", s.Error)
+		}
+		for _, m := range s.PatternMatches {
+			fmt.Fprintf(&sb, "- `%s`: `%s`\n", m.Cmd, m.Match)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+var htmlReport = template.Must(template.New("summary").Funcs(template.FuncMap{
+	"ids":   ids,
+	"round": func(d time.Duration) time.Duration { return d.Round(time.Second) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>routercommander summary</title></head>
+<body>
+<h1>routercommander summary</h1>
+<p>Started: {{ .Start }}, duration: {{ round .Duration }}</p>
+<table border="1" cellspacing="0" cellpadding="4">
+<tr><th>Router</th><th>Status</th><th>Commands</th><th>Failed commands</th><th>Pattern matches</th><th>Triggered tests</th><th>Repro triggered</th><th>Duration</th></tr>
+{{- range .Routers }}
+<tr><td>{{ .Router }}</td><td>{{ .Status }}</td><td>{{ .CommandsExecuted }}</td><td>{{ range $i, $c := .FailedCommands }}{{ if $i }}<br>{{ end }}{{ $c }}{{ end }}</td><td>{{ len .PatternMatches }}</td><td>{{ ids .TriggeredTests }}</td><td>{{ .Repro }}</td><td>{{ round .Duration }}</td></tr>
+{{- end }}
+</table>
+{{- range .Routers }}{{ if or .Error .PatternMatches }}
+<h2>{{ .Router }}</h2>
+{{- if .Error }}
+<p>Error: {{ .Error }}</p>
+{{- end }}
+{{- if .PatternMatches }}
+<ul>
+{{- range .PatternMatches }}
+<li><code>{{ .Cmd }}</code>: <code>{{ .Match }}</code></li>
+{{- end }}
+</ul>
+{{- end }}
+{{- end }}{{ end }}
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as an HTML document.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return htmlReport.Execute(w, struct {
+		Start    string
+		Duration time.Duration
+		Routers  []*RouterSummary
+	}{
+		Start:    r.start.Format(time.RFC3339),
+		Duration: time.Since(r.start),
+		Routers:  r.Routers(),
+	})
+}
+
+// WriteFile writes the report to the file, files with .html or .htm extension get an HTML document,
+// all others a Markdown document.
+func (r *Report) WriteFile(fn string) error {
+	f, err := os.Create(fn)
+	if err != nil {
+		return fmt.Errorf("failed to create summary file %s with error: %+v", fn, err)
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(fn)) {
+	case ".html", ".htm":
+		err = r.WriteHTML(f)
+	default:
+		err = r.WriteMarkdown(f)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write summary file %s with error: %+v", fn, err)
+	}
+
+	return nil
+}
+
+func ids(l []int) string {
+	s := make([]string, len(l))
+	for i, id := range l {
+		s[i] = fmt.Sprintf("%d", id)
+	}
+	return strings.Join(s, ",")
+}
+
+func mdEscape(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
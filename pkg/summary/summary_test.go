@@ -0,0 +1,107 @@
+package summary
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testReport() *Report {
+	r := NewReport()
+	s1 := NewRouterSummary("r2")
+	s1.CommandExecuted(3)
+	s1.PatternsMatched("show cef drops", []string{"Discard drops packets : 10"})
+	// The same match found in the next iteration is not added again
+	s1.PatternsMatched("show cef drops", []string{"Discard drops packets : 10"})
+	s1.TestsTriggered([]int{2, 1})
+	s1.TestsTriggered([]int{1})
+	s1.SetReproTriggered(true)
+	s1.Finish(StatusOK, nil)
+	r.Add(s1)
+	s2 := NewRouterSummary("r1")
+	s2.Finish(StatusConnectionFailed, fmt.Errorf("connection refused"))
+	r.Add(s2)
+	s3 := NewRouterSummary("r3")
+	s3.CommandExecuted(1)
+	s3.CommandFailed("show platform | r1")
+	s3.SetReproTriggered(false)
+	s3.Finish(StatusFailed, fmt.Errorf("command timed out"))
+	r.Add(s3)
+
+	return r
+}
+
+func TestRouterSummary(t *testing.T) {
+	r := testReport()
+	routers := r.Routers()
+	if len(routers) != 3 || routers[0].Router != "r1" || routers[1].Router != "r2" || routers[2].Router != "r3" {
+		t.Fatalf("routers are not sorted by name")
+	}
+	s := routers[1]
+	if s.CommandsExecuted != 3 || len(s.PatternMatches) != 1 || !s.ReproTriggered {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+	if len(s.TriggeredTests) != 2 || s.TriggeredTests[0] != 1 || s.TriggeredTests[1] != 2 {
+		t.Fatalf("expected triggered tests [1 2] but got %v", s.TriggeredTests)
+	}
+	for i, expect := range []string{"-", "yes", "no"} {
+		if got := routers[i].Repro(); got != expect {
+			t.Fatalf("router %s: expected repro %q, got %q", routers[i].Router, expect, got)
+		}
+	}
+	if !r.Has("r3") || r.Has("r4") {
+		t.Fatalf("unexpected routers in the report")
+	}
+	// Methods of nil summary must not panic
+	var ns *RouterSummary
+	ns.CommandExecuted(1)
+	ns.CommandFailed("show version")
+	ns.Finish(StatusOK, nil)
+}
+
+func TestWriteReport(t *testing.T) {
+	r := testReport()
+	var b bytes.Buffer
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("failed to write text report with error: %+v", err)
+	}
+	for _, e := range []string{"ROUTER", "r1", "connection failed", "1,2", "r3: command timed out"} {
+		if !strings.Contains(b.String(), e) {
+			t.Fatalf("text report does not contain %q:\n%s", e, b.String())
+		}
+	}
+	dir := t.TempDir()
+	tests := []struct {
+		file   string
+		expect []string
+	}{
+		{
+			file:   "summary.md",
+			expect: []string{"| r2 | ok | 3 |", "show platform \\| r1", "- `show cef drops`: `Discard drops packets : 10`"},
+		},
+		{
+			file:   "summary.html",
+			expect: []string{"<td>r2</td><td>ok</td><td>3</td>", "<td>1,2</td><td>yes</td>", "<td></td><td>-</td>", "show platform | r1", "<code>show cef drops</code>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			fn := filepath.Join(dir, tt.file)
+			if err := r.WriteFile(fn); err != nil {
+				t.Fatalf("failed to write report with error: %+v", err)
+			}
+			b, err := os.ReadFile(fn)
+			if err != nil {
+				t.Fatalf("failed to read report with error: %+v", err)
+			}
+			for _, e := range tt.expect {
+				if !strings.Contains(string(b), e) {
+					t.Fatalf("report does not contain %q:\n%s", e, string(b))
+				}
+			}
+		})
+	}
+}
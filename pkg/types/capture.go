@@ -0,0 +1,207 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"text/template"
+)
+
+// ErrNotCaptured is returned when a command references a value which is not captured.
+var ErrNotCaptured = errors.New("value is not captured")
+
+// Captures are values of named capture groups of patterns with store_captures set, captured from outputs of executed
+// commands. Following commands of the router reference captured values as variables, {{.name}} is the first
+// captured value, a command with foreach is executed for every captured value.
+type Captures map[string][]string
+
+// NewCaptures returns empty captures of a router.
+func NewCaptures() Captures {
+	return make(Captures)
+}
+
+// Capture stores values of named capture groups of patterns with store_captures set found in the results, values
+// captured by the previous execution of the command are replaced. Repeated values are stored once.
+func (c Captures) Capture(results []*CmdResult, patterns []*Pattern) error {
+	for _, p := range patterns {
+		if p == nil || !p.StoreCaptures {
+			continue
+		}
+		re, err := p.compile()
+		if err != nil {
+			return err
+		}
+		values := make(map[string][]string)
+		for _, r := range results {
+			for _, m := range re.FindAllSubmatch(r.Result, -1) {
+				for gi, name := range re.SubexpNames() {
+					if name == "" || m[gi] == nil {
+						continue
+					}
+					v := string(bytes.TrimSpace(m[gi]))
+					if !contains(values[name], v) {
+						values[name] = append(values[name], v)
+					}
+				}
+			}
+		}
+		for _, name := range re.SubexpNames() {
+			if name != "" {
+				c[name] = values[name]
+			}
+		}
+	}
+
+	return nil
+}
+
+// ExpandCommand returns the command with references to captured values replaced, a command with foreach is
+// returned for every value of its foreach capture, no commands are returned when nothing is captured. ErrNotCaptured
+// is returned when the command references a value which is not captured.
+func (c Captures) ExpandCommand(cmd *Command) ([]*Command, error) {
+	if cmd.Foreach == "" {
+		if !isTemplate(cmd.Cmd) && !isTemplate(cmd.PipeModifier) && !hasTemplate(cmd.Location) {
+			return []*Command{cmd}, nil
+		}
+		n, err := c.expandCommand(cmd, c.data())
+		if err != nil {
+			return nil, err
+		}
+		return []*Command{n}, nil
+	}
+	cmds := make([]*Command, 0, len(c[cmd.Foreach]))
+	for _, v := range c[cmd.Foreach] {
+		data := c.data()
+		data[cmd.Foreach] = v
+		n, err := c.expandCommand(cmd, data)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, n)
+	}
+
+	return cmds, nil
+}
+
+// data returns the first captured value of every capture
+func (c Captures) data() map[string]string {
+	data := make(map[string]string, len(c)+len(reservedVars))
+	for k, v := range c {
+		if len(v) != 0 {
+			data[k] = v[0]
+		}
+	}
+	for k, v := range reservedVars {
+		data[k] = v
+	}
+
+	return data
+}
+
+func (c Captures) expandCommand(cmd *Command, data map[string]string) (*Command, error) {
+	n := *cmd
+	var err error
+	if n.Cmd, err = expandCaptured(cmd.Cmd, data); err != nil {
+		return nil, fmt.Errorf("command %q: %w", cmd.Cmd, err)
+	}
+	if n.PipeModifier, err = expandCaptured(cmd.PipeModifier, data); err != nil {
+		return nil, fmt.Errorf("command %q: %w", cmd.Cmd, err)
+	}
+	if cmd.Location != nil {
+		n.Location = make([]string, len(cmd.Location))
+		for i, l := range cmd.Location {
+			if n.Location[i], err = expandCaptured(l, data); err != nil {
+				return nil, fmt.Errorf("command %q: %w", cmd.Cmd, err)
+			}
+		}
+	}
+
+	return &n, nil
+}
+
+func expandCaptured(s string, data map[string]string) (string, error) {
+	if !isTemplate(s) {
+		return s, nil
+	}
+	tmpl, err := template.New("captures").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("%w: %+v", ErrNotCaptured, err)
+	}
+
+	return buf.String(), nil
+}
+
+// captureNames returns names of capture groups of all patterns with store_captures set
+func captureNames(c *Commander) []string {
+	names := make(map[string]bool)
+	add := func(p *Pattern) {
+		if p == nil || !p.StoreCaptures {
+			return
+		}
+		// Not stored in the pattern, variables in the pattern are not expanded yet
+		re, err := regexp.Compile(p.PatternString)
+		if err != nil {
+			return
+		}
+		for _, name := range re.SubexpNames() {
+			if name != "" {
+				names[name] = true
+			}
+		}
+	}
+	for _, cmd := range allCommands(c) {
+		for _, p := range cmd.Patterns {
+			add(p)
+		}
+	}
+	for _, t := range c.Tests {
+		for _, e := range t.Source {
+			add(e.Pattern)
+		}
+	}
+	l := make([]string, 0, len(names))
+	for name := range names {
+		l = append(l, name)
+	}
+	sort.Strings(l)
+
+	return l
+}
+
+// compile returns the pattern's regular expression, compiling it when it has not been compiled yet
+func (p *Pattern) compile() (*regexp.Regexp, error) {
+	if p.RegExp != nil {
+		return p.RegExp, nil
+	}
+	re, err := regexp.Compile(p.PatternString)
+	if err != nil {
+		return nil, fmt.Errorf("fail to compile regular expression %q with error: %+v", p.PatternString, err)
+	}
+	p.RegExp = re
+
+	return re, nil
+}
+
+func hasTemplate(l []string) bool {
+	for _, s := range l {
+		if isTemplate(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCaptures(t *testing.T) {
+	patterns := []*Pattern{
+		{PatternString: `(?P<intf>\S+) is up, (?P<errors>\d+) input errors`, StoreCaptures: true},
+		{PatternString: `(?P<ignored>\S+) is down`},
+	}
+	output := []byte("Hu0/0/0/0 is up, 10 input errors\nHu0/0/0/1 is down\nHu0/0/0/2 is up, 5 input errors\nHu0/0/0/0 is up, 10 input errors\n")
+	tests := []struct {
+		name   string
+		cmd    *Command
+		expect []string
+		fail   bool
+	}{
+		{
+			name:   "not referencing captures",
+			cmd:    &Command{Cmd: "show interfaces"},
+			expect: []string{"show interfaces"},
+		},
+		{
+			name:   "first captured value",
+			cmd:    &Command{Cmd: "show interface {{.intf}} | include {{.errors}}"},
+			expect: []string{"show interface Hu0/0/0/0 | include 10"},
+		},
+		{
+			name:   "foreach",
+			cmd:    &Command{Cmd: "show interface {{.intf}}", Foreach: "intf"},
+			expect: []string{"show interface Hu0/0/0/0", "show interface Hu0/0/0/2"},
+		},
+		{
+			name:   "foreach without captured values",
+			cmd:    &Command{Cmd: "show interface {{.ignored}}", Foreach: "ignored"},
+			expect: []string{},
+		},
+		{
+			name:   "location is kept for the executor",
+			cmd:    &Command{Cmd: "show controllers {{.intf}} location {{.Location}}"},
+			expect: []string{"show controllers Hu0/0/0/0 location {{.Location}}"},
+		},
+		{
+			name: "not captured value",
+			cmd:  &Command{Cmd: "show interface {{.ignored}}"},
+			fail: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCaptures()
+			if err := c.Capture([]*CmdResult{{Cmd: "show interfaces", Result: output}}, patterns); err != nil {
+				t.Fatalf("failed to capture with error: %+v", err)
+			}
+			cmds, err := c.ExpandCommand(tt.cmd)
+			if err != nil && !tt.fail {
+				t.Fatalf("supposed to succeed but failed with error: %+v", err)
+			}
+			if err == nil && tt.fail {
+				t.Fatalf("supposed to fail but succeeded")
+			}
+			if err != nil {
+				if !errors.Is(err, ErrNotCaptured) {
+					t.Fatalf("expected ErrNotCaptured, got %+v", err)
+				}
+				return
+			}
+			if len(cmds) != len(tt.expect) {
+				t.Fatalf("expected %d commands, got %d", len(tt.expect), len(cmds))
+			}
+			for i, cmd := range cmds {
+				if cmd.Cmd != tt.expect[i] {
+					t.Fatalf("expected command %q, got %q", tt.expect[i], cmd.Cmd)
+				}
+			}
+			if tt.cmd.Foreach == "" && len(cmds) == 1 && cmds[0] != tt.cmd && tt.cmd.Cmd == cmds[0].Cmd {
+				t.Fatalf("command without references to captures is copied")
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package types
+
+import "time"
+
+// Clone returns a copy of the commander, processing of a router stores commands' results and tests' values
+// in the commander, so every concurrently processed router needs its own copy. The commander is copied by value
+// and sections holding commands and tests are deep copied. Compiled regular expressions and expressions are safe
+// for concurrent use and they are shared between copies, as well as includes which are used only by GetCommands.
+func (c *Commander) Clone() *Commander {
+	if c == nil {
+		return nil
+	}
+	n := *c
+	n.MainCommandGroup = cloneCommands(c.MainCommandGroup)
+	if c.Vars != nil {
+		n.Vars = make(map[string]string, len(c.Vars))
+		for k, v := range c.Vars {
+			n.Vars[k] = v
+		}
+	}
+	if c.Repro != nil {
+		r := *c.Repro
+		r.PostMortemCommandGroup = cloneCommands(c.Repro.PostMortemCommandGroup)
+		n.Repro = &r
+	}
+	if c.Collect != nil {
+		cl := *c.Collect
+		n.Collect = &cl
+	}
+	if c.Tests != nil {
+		n.Tests = make([]*Tests, len(c.Tests))
+		for i, t := range c.Tests {
+			n.Tests[i] = t.clone()
+		}
+	}
+	if c.CommandsWithTests != nil {
+		n.CommandsWithTests = make(map[string]*Tests, len(c.CommandsWithTests))
+		for i, t := range c.Tests {
+			if c.CommandsWithTests[t.Cmd] == t {
+				n.CommandsWithTests[t.Cmd] = n.Tests[i]
+			}
+		}
+	}
+
+	return &n
+}
+
+func (t *Tests) clone() *Tests {
+	if t == nil {
+		return nil
+	}
+	n := *t
+	if t.Source != nil {
+		n.Source = make([]*Test, len(t.Source))
+		for i, s := range t.Source {
+			n.Source[i] = s.clone()
+		}
+	}
+	if t.Tests != nil {
+		n.Tests = make(map[int]*Test, len(t.Tests))
+		for i, s := range t.Source {
+			if t.Tests[s.ID] == s {
+				n.Tests[s.ID] = n.Source[i]
+			}
+		}
+	}
+
+	return &n
+}
+
+func (t *Test) clone() *Test {
+	if t == nil {
+		return nil
+	}
+	n := *t
+	n.Pattern = t.Pattern.clone()
+	if t.NumberOfOccurences != nil {
+		o := *t.NumberOfOccurences
+		n.NumberOfOccurences = &o
+	}
+	if t.Fields != nil {
+		n.Fields = make([]*Field, len(t.Fields))
+		for i, f := range t.Fields {
+			fc := *f
+			n.Fields[i] = &fc
+		}
+	}
+	n.IfTriggeredCommands = cloneCommands(t.IfTriggeredCommands)
+	if t.ValuesStore != nil {
+		n.ValuesStore = make(map[int]map[int]interface{}, len(t.ValuesStore))
+		for it, values := range t.ValuesStore {
+			n.ValuesStore[it] = make(map[int]interface{}, len(values))
+			for k, v := range values {
+				n.ValuesStore[it][k] = v
+			}
+		}
+	}
+	if t.ConditionStore != nil {
+		n.ConditionStore = make(map[int]map[ConditionMatch]*ConditionValues, len(t.ConditionStore))
+		for it, matches := range t.ConditionStore {
+			n.ConditionStore[it] = make(map[ConditionMatch]*ConditionValues, len(matches))
+			for m, cv := range matches {
+				c := &ConditionValues{Values: make(map[string]string, len(cv.Values)), At: cv.At}
+				for k, v := range cv.Values {
+					c.Values[k] = v
+				}
+				n.ConditionStore[it][m] = c
+			}
+		}
+	}
+	if t.TimeStore != nil {
+		n.TimeStore = make(map[int]time.Time, len(t.TimeStore))
+		for it, ts := range t.TimeStore {
+			n.TimeStore[it] = ts
+		}
+	}
+
+	return &n
+}
+
+func (p *Pattern) clone() *Pattern {
+	if p == nil {
+		return nil
+	}
+	n := *p
+
+	return &n
+}
+
+func cloneCommands(cmds []*Command) []*Command {
+	if cmds == nil {
+		return nil
+	}
+	n := make([]*Command, len(cmds))
+	for i, c := range cmds {
+		n[i] = c.clone()
+	}
+
+	return n
+}
+
+func (c *Command) clone() *Command {
+	if c == nil {
+		return nil
+	}
+	n := *c
+	if c.Location != nil {
+		n.Location = append([]string{}, c.Location...)
+	}
+	if c.Patterns != nil {
+		n.Patterns = make([]*Pattern, len(c.Patterns))
+		for i, p := range c.Patterns {
+			n.Patterns[i] = p.clone()
+		}
+	}
+	if c.TestIDs != nil {
+		n.TestIDs = append([]int{}, c.TestIDs...)
+	}
+	if c.Config != nil {
+		cfg := *c.Config
+		cfg.Lines = append([]string{}, c.Config.Lines...)
+		cfg.PostChecks = cloneCommands(c.Config.PostChecks)
+		n.Config = &cfg
+	}
+	if c.CommandResult != nil {
+		r := *c.CommandResult
+		if r.PatternMatch != nil {
+			r.PatternMatch = append([]string{}, c.CommandResult.PatternMatch...)
+		}
+		if r.TriggeredTest != nil {
+			r.TriggeredTest = append([]int{}, c.CommandResult.TriggeredTest...)
+		}
+		n.CommandResult = &r
+	}
+
+	return &n
+}
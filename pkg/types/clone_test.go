@@ -0,0 +1,193 @@
+package types
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/expr-lang/expr/vm"
+	"github.com/go-test/deep"
+)
+
+func TestCommanderClone(t *testing.T) {
+	c, err := parseCommandFile([]byte(`repro:
+  times: 2
+  interval: 1
+  if_triggered_commands:
+  - command: "show logging"
+tests:
+- command: "show cef drops"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: 'drops\s+(?P<drops>\d+)'
+    fields:
+    - group: drops
+      operation: "compare_with_value_gt"
+      value: "10"
+commands:
+- command: "show cef drops"
+  location: [ "0/0/CPU0" ]
+  command_test_ids: [1]`))
+	if err != nil {
+		t.Fatalf("failed to parse commands with error: %+v", err)
+	}
+	n := c.Clone()
+	if !reflect.DeepEqual(c, n) {
+		t.Logf("Diffs: %+v", deep.Equal(c, n))
+		t.Fatal("clone does not match the original commander")
+	}
+	// Changes of the copy must not be visible in the original
+	n.MainCommandGroup[0].CommandResult.TriggeredTest = append(n.MainCommandGroup[0].CommandResult.TriggeredTest, 1)
+	n.MainCommandGroup[0].Location[0] = "0/1/CPU0"
+	test := n.CommandsWithTests["show cef drops"].Tests[1]
+	if test != n.Tests[0].Source[0] {
+		t.Fatal("tests map of the copy does not point to the copied tests")
+	}
+	test.ValuesStore[0] = map[int]interface{}{0: int64(11)}
+	test.Fields[0].Result = int64(11)
+	n.Repro.PostMortemCommandGroup[0].Cmd = "show version"
+	orig := c.CommandsWithTests["show cef drops"].Tests[1]
+	switch {
+	case len(c.MainCommandGroup[0].CommandResult.TriggeredTest) != 0:
+		t.Fatal("triggered tests of the original commander were changed")
+	case c.MainCommandGroup[0].Location[0] != "0/0/CPU0":
+		t.Fatal("location of the original commander was changed")
+	case len(orig.ValuesStore) != 0 || orig.Fields[0].Result != nil:
+		t.Fatal("test values of the original commander were changed")
+	case c.Repro.PostMortemCommandGroup[0].Cmd != "show logging":
+		t.Fatal("post mortem commands of the original commander were changed")
+	}
+}
+
+// sharedFields are fields which Clone shares between copies, they are not modified while a router is processed.
+var sharedFields = map[reflect.Type][]string{
+	reflect.TypeOf(Commander{}): {"Include"},
+	reflect.TypeOf(Command{}):   {"WhenProgram"},
+	reflect.TypeOf(Test{}):      {"ConditionProgram"},
+	reflect.TypeOf(Pattern{}):   {"RegExp"},
+}
+
+func isShared(t reflect.Type, name string) bool {
+	for _, f := range sharedFields[t] {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fillValue sets every exported field reachable from v to a non zero value, recursive commands are filled
+// up to the depth.
+func fillValue(v reflect.Value, depth int) {
+	switch v.Type() {
+	case reflect.TypeOf(time.Time{}):
+		v.Set(reflect.ValueOf(time.Unix(1, 0)))
+		return
+	case reflect.TypeOf(&regexp.Regexp{}):
+		v.Set(reflect.ValueOf(regexp.MustCompile("x")))
+		return
+	case reflect.TypeOf(&vm.Program{}):
+		v.Set(reflect.ValueOf(&vm.Program{}))
+		return
+	case reflect.TypeOf(&Command{}):
+		if depth == 0 {
+			return
+		}
+		depth--
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Int:
+		v.SetInt(1)
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Interface:
+		v.Set(reflect.ValueOf("x"))
+	case reflect.Ptr:
+		v.Set(reflect.New(v.Type().Elem()))
+		fillValue(v.Elem(), depth)
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fillValue(v.Index(0), depth)
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		k := reflect.New(v.Type().Key()).Elem()
+		fillValue(k, depth)
+		e := reflect.New(v.Type().Elem()).Elem()
+		fillValue(e, depth)
+		v.SetMapIndex(k, e)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fillValue(v.Field(i), depth)
+			}
+		}
+	}
+}
+
+// checkNotShared fails when memory reachable from the copy is shared with the original, besides sharedFields.
+func checkNotShared(t *testing.T, path string, o, n reflect.Value) {
+	t.Helper()
+	switch o.Kind() {
+	case reflect.Ptr:
+		if o.IsNil() || n.IsNil() {
+			return
+		}
+		if o.Pointer() == n.Pointer() {
+			t.Fatalf("%s is shared by the copy", path)
+		}
+		checkNotShared(t, path, o.Elem(), n.Elem())
+	case reflect.Slice:
+		if o.Len() == 0 || n.Len() == 0 {
+			return
+		}
+		if o.Pointer() == n.Pointer() {
+			t.Fatalf("%s is shared by the copy", path)
+		}
+		for i := 0; i < o.Len() && i < n.Len(); i++ {
+			checkNotShared(t, path+"[]", o.Index(i), n.Index(i))
+		}
+	case reflect.Map:
+		if o.IsNil() || n.IsNil() {
+			return
+		}
+		if o.Pointer() == n.Pointer() {
+			t.Fatalf("%s is shared by the copy", path)
+		}
+		for _, k := range o.MapKeys() {
+			if e := n.MapIndex(k); e.IsValid() {
+				checkNotShared(t, path+"[]", o.MapIndex(k), e)
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < o.NumField(); i++ {
+			f := o.Type().Field(i)
+			if !f.IsExported() || isShared(o.Type(), f.Name) {
+				continue
+			}
+			checkNotShared(t, path+"."+f.Name, o.Field(i), n.Field(i))
+		}
+	}
+}
+
+func TestCommanderCloneFields(t *testing.T) {
+	c := &Commander{}
+	fillValue(reflect.ValueOf(c).Elem(), 2)
+	// Maps of tests point to the tests of the commander
+	c.CommandsWithTests = make(map[string]*Tests)
+	for _, tt := range c.Tests {
+		tt.Tests = make(map[int]*Test)
+		for _, s := range tt.Source {
+			tt.Tests[s.ID] = s
+		}
+		c.CommandsWithTests[tt.Cmd] = tt
+	}
+	n := c.Clone()
+	if diff := deep.Equal(c, n); diff != nil {
+		t.Fatalf("clone does not copy all fields: %+v", diff)
+	}
+	checkNotShared(t, "Commander", reflect.ValueOf(c), reflect.ValueOf(n))
+}
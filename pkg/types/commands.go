@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"regexp"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -24,11 +25,16 @@ func readCommandFile(fn string) ([]byte, error) {
 
 func parseCommandFile(b []byte) (*Commander, error) {
 	c := &Commander{}
-	var err error
 	if err := yaml.Unmarshal(b, c); err != nil {
 		return nil, fmt.Errorf("fail to unmarshal commands yaml with error: %+v", err)
 	}
 
+	return prepareCommands(c)
+}
+
+// prepareCommands compiles patterns and expressions of commands and tests and validates them.
+func prepareCommands(c *Commander) (*Commander, error) {
+	var err error
 	pr := false
 	if c.Collect != nil {
 		pr = c.Collect.ProcessResult
@@ -52,6 +58,15 @@ func parseCommandFile(b []byte) (*Commander, error) {
 			TriggeredTest: make([]int, 0),
 		}
 	}
+	if err := prepareConfigBlocks(c); err != nil {
+		return nil, err
+	}
+	if err := prepareOnError(c); err != nil {
+		return nil, err
+	}
+	if err := prepareWhen(c); err != nil {
+		return nil, err
+	}
 	if len(c.Tests) != 0 {
 		c.CommandsWithTests = make(map[string]*Tests)
 		for _, t := range c.Tests {
@@ -65,7 +80,33 @@ func parseCommandFile(b []byte) (*Commander, error) {
 						return nil, err
 					}
 				}
+				if e.Condition != "" {
+					if len(e.Fields) != 0 {
+						return nil, fmt.Errorf("test id %d defines both fields and condition", e.ID)
+					}
+					// Conditions referencing variables are compiled when variables are expanded
+					if !e.hasVars() {
+						if e.ConditionProgram, err = compileCondition(e); err != nil {
+							return nil, err
+						}
+					}
+				}
+				for _, f := range e.Fields {
+					if f.Group != "" {
+						if e.Pattern == nil || e.Pattern.RegExp.SubexpIndex(f.Group) == -1 {
+							return nil, fmt.Errorf("test id %d field uses capture group %q which is not defined in the test pattern", e.ID, f.Group)
+						}
+					}
+					if !IsNumericOperation(f.Operation) || isTemplate(f.Value) {
+						// Values referencing variables are checked when variables are expanded
+						continue
+					}
+					if _, err := ParseNumber(f.Value); err != nil {
+						return nil, fmt.Errorf("test id %d field %d operation %s requires a numeric value: %+v", e.ID, f.FieldNumber, f.Operation, err)
+					}
+				}
 				e.ValuesStore = make(map[int]map[int]interface{})
+				e.TimeStore = make(map[int]time.Time)
 				t.Tests[t.Source[i].ID] = e
 			}
 			c.CommandsWithTests[t.Cmd] = t
@@ -76,11 +117,82 @@ func parseCommandFile(b []byte) (*Commander, error) {
 
 }
 
+// allCommands returns commands of all groups, including post checks of configuration blocks.
+func allCommands(c *Commander) []*Command {
+	cmds := make([]*Command, 0)
+	cmds = append(cmds, c.MainCommandGroup...)
+	if c.Repro != nil {
+		cmds = append(cmds, c.Repro.PostMortemCommandGroup...)
+	}
+	for _, t := range c.Tests {
+		for _, e := range t.Source {
+			cmds = append(cmds, e.IfTriggeredCommands...)
+		}
+	}
+	for _, cmd := range cmds {
+		if cmd.Config != nil {
+			cmds = append(cmds, cmd.Config.PostChecks...)
+		}
+	}
+
+	return cmds
+}
+
+// prepareOnError validates on_error policies of commands, commands without the policy are warned about,
+// unless stop_on_error of the collect section is true, then they fail the processing.
+func prepareOnError(c *Commander) error {
+	stop := c.Collect != nil && c.Collect.StopOnError
+	for _, cmd := range allCommands(c) {
+		switch cmd.OnError {
+		case "":
+			if stop {
+				cmd.OnError = OnErrorFail
+			}
+		case OnErrorFail, OnErrorWarn, OnErrorContinue:
+		default:
+			return fmt.Errorf("command %q has invalid on_error %q, supported values: %s, %s, %s", cmd.Cmd, cmd.OnError, OnErrorFail, OnErrorWarn, OnErrorContinue)
+		}
+	}
+
+	return nil
+}
+
+// prepareConfigBlocks validates configuration blocks and compiles patterns of their post checks,
+// post checks' patterns are always needed to decide if the configuration is confirmed.
+func prepareConfigBlocks(c *Commander) error {
+	for _, cmd := range allCommands(c) {
+		if cmd.Config == nil {
+			continue
+		}
+		if len(cmd.Config.Lines) == 0 {
+			return fmt.Errorf("configuration block %q has no lines", cmd.Cmd)
+		}
+		if cmd.Config.CommitConfirmed < 0 {
+			return fmt.Errorf("configuration block %q has invalid commit_confirmed %d", cmd.Cmd, cmd.Config.CommitConfirmed)
+		}
+		if len(cmd.Config.PostChecks) != 0 && cmd.Config.CommitConfirmed == 0 {
+			return fmt.Errorf("configuration block %q defines post_checks which require commit_confirmed", cmd.Cmd)
+		}
+		for _, pc := range cmd.Config.PostChecks {
+			for _, p := range pc.Patterns {
+				re, err := regexp.Compile(p.PatternString)
+				if err != nil {
+					return fmt.Errorf("fail to compile regular expression %q with error: %+v", p.PatternString, err)
+				}
+				p.RegExp = re
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetCommands returns commands of the commands file merged with the files it includes.
 func GetCommands(fn string) (*Commander, error) {
-	b, err := readCommandFile(fn)
+	c, err := loadCommandFile(fn, nil, make(map[string]bool))
 	if err != nil {
 		return nil, err
 	}
 
-	return parseCommandFile(b)
+	return prepareCommands(c)
 }
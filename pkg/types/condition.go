@@ -0,0 +1,176 @@
+package types
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// Values of test conditions besides values of the pattern's capture groups
+const (
+	// CondCount is the number of matches of the test's pattern in the command's output
+	CondCount = "count"
+	// CondIteration is the iteration, starting from 0
+	CondIteration = "iteration"
+	// CondVars are variables of the router
+	CondVars = "vars"
+	// CondPrevious are values of capture groups in the previous iteration
+	CondPrevious = "previous"
+	// CondDelta are differences between numeric values of capture groups and their values in the previous iteration
+	CondDelta = "delta"
+	// CondRate are deltas per second
+	CondRate = "rate"
+	// CondNum parses the value of a capture group as a number, see ParseNumber
+	CondNum = "num"
+)
+
+// num parses the value as a number, values are compared as float64 regardless of their format
+func num(s string) (float64, error) {
+	v, err := ParseNumber(s)
+	if err != nil {
+		return 0, err
+	}
+	f, _ := ToFloat64(v)
+
+	return f, nil
+}
+
+// conditionEnv returns the environment of the test's condition, capture groups of the pattern are set to their values
+func conditionEnv(names []string, values []string, count int, iteration int, vars map[string]string) map[string]interface{} {
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	env := map[string]interface{}{
+		CondCount:     count,
+		CondIteration: iteration,
+		CondVars:      vars,
+		CondPrevious:  map[string]string{},
+		CondDelta:     map[string]float64{},
+		CondRate:      map[string]float64{},
+		CondNum:       num,
+	}
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		v := ""
+		if values != nil {
+			v = values[i]
+		}
+		env[name] = v
+	}
+
+	return env
+}
+
+// compileCondition compiles the test's condition, names of the pattern's capture groups are checked when
+// the condition is compiled
+func compileCondition(t *Test) (*vm.Program, error) {
+	if t.Pattern == nil || t.Pattern.RegExp == nil {
+		return nil, fmt.Errorf("test id %d condition requires a pattern", t.ID)
+	}
+	names := t.Pattern.RegExp.SubexpNames()
+	env := conditionEnv(nil, nil, 0, 0, nil)
+	for _, name := range names {
+		if _, ok := env[name]; ok {
+			return nil, fmt.Errorf("test id %d capture group %q is reserved in conditions", t.ID, name)
+		}
+	}
+	p, err := expr.Compile(t.Condition, expr.Env(conditionEnv(names, nil, 0, 0, nil)), expr.AsBool())
+	if err != nil {
+		return nil, fmt.Errorf("test id %d has invalid condition %q with error: %+v", t.ID, t.Condition, err)
+	}
+
+	return p, nil
+}
+
+// ConditionMatch identifies a match of the test's pattern evaluated by the test's condition in an iteration.
+type ConditionMatch struct {
+	// Result is the index of the command's result, a command executed several times or at several locations
+	// has several results in an iteration
+	Result int
+	// Match is the index of the match of the pattern in the result
+	Match int
+}
+
+// ConditionValues are values of the pattern's capture groups of a match keyed by the group's name.
+type ConditionValues struct {
+	Values map[string]string
+	// At is the time when the values were captured, used by rates
+	At time.Time
+}
+
+// EvaluateCondition evaluates the test's condition for the match of the test's pattern, match holds indexes of
+// the match and of its capture groups in b. Values of capture groups are stored in ConditionStore, they are compared
+// with values of the same match in the next iteration.
+func (t *Test) EvaluateCondition(b []byte, match []int, m ConditionMatch, count int, iteration int, vars map[string]string, at time.Time) (bool, error) {
+	re := t.Pattern.RegExp
+	names := re.SubexpNames()
+	values := make([]string, len(names))
+	if t.ConditionStore == nil {
+		t.ConditionStore = make(map[int]map[ConditionMatch]*ConditionValues)
+	}
+	if _, ok := t.ConditionStore[iteration]; !ok {
+		t.ConditionStore[iteration] = make(map[ConditionMatch]*ConditionValues)
+	}
+	current := &ConditionValues{Values: make(map[string]string), At: at}
+	t.ConditionStore[iteration][m] = current
+	for gi := 1; gi < len(names); gi++ {
+		if names[gi] == "" {
+			continue
+		}
+		if 2*gi+1 < len(match) && match[2*gi] >= 0 {
+			values[gi] = string(b[match[2*gi]:match[2*gi+1]])
+		}
+		current.Values[names[gi]] = values[gi]
+	}
+	env := conditionEnv(names, values, count, iteration, vars)
+	if prev, ok := t.ConditionStore[iteration-1][m]; ok && iteration > 0 {
+		previous := env[CondPrevious].(map[string]string)
+		delta := env[CondDelta].(map[string]float64)
+		rate := env[CondRate].(map[string]float64)
+		elapsed := at.Sub(prev.At).Seconds()
+		for gi, name := range names {
+			if name == "" {
+				continue
+			}
+			pv, ok := prev.Values[name]
+			if !ok {
+				continue
+			}
+			previous[name] = pv
+			// delta and rate are defined only for numeric values
+			cn, err := ParseNumber(values[gi])
+			if err != nil {
+				continue
+			}
+			pn, err := ParseNumber(pv)
+			if err != nil {
+				continue
+			}
+			d, ok := Delta(cn, pn)
+			if !ok {
+				continue
+			}
+			delta[name] = d
+			if elapsed > 0 {
+				rate[name] = d / elapsed
+			}
+		}
+	}
+	if t.ConditionProgram == nil {
+		p, err := compileCondition(t)
+		if err != nil {
+			return false, err
+		}
+		t.ConditionProgram = p
+	}
+	v, err := expr.Run(t.ConditionProgram, env)
+	if err != nil {
+		return false, fmt.Errorf("test id %d failed to evaluate condition %q with error: %+v", t.ID, t.Condition, err)
+	}
+
+	return v.(bool), nil
+}
@@ -0,0 +1,137 @@
+package types
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	re := regexp.MustCompile(`(?P<intf>\S+) is (?P<state>\w+), (?P<drops>[0-9,]+) drops`)
+	test := &Test{
+		ID:        1,
+		Pattern:   &Pattern{PatternString: re.String(), RegExp: re},
+		Condition: `delta.drops > num(vars.THRESHOLD) && state == "UP" && count == 2 && num(previous.drops) < num(drops)`,
+	}
+	vars := map[string]string{"THRESHOLD": "100"}
+	start := time.Now()
+	tests := []struct {
+		name      string
+		output    string
+		triggered bool
+	}{
+		{
+			name:      "first iteration without previous values",
+			output:    "Hu0/0/0/0 is UP, 10 drops\nHu0/0/0/1 is DOWN, 0 drops\n",
+			triggered: false,
+		},
+		{
+			name:      "delta above threshold",
+			output:    "Hu0/0/0/0 is UP, 1,500 drops\nHu0/0/0/1 is DOWN, 0 drops\n",
+			triggered: true,
+		},
+		{
+			name:      "delta above threshold of a down interface",
+			output:    "Hu0/0/0/0 is UP, 1,500 drops\nHu0/0/0/1 is DOWN, 500 drops\n",
+			triggered: false,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := []byte(tt.output)
+			matches := re.FindAllSubmatchIndex(b, -1)
+			triggered := false
+			for n, m := range matches {
+				trgrd, err := test.EvaluateCondition(b, m, ConditionMatch{Match: n}, len(matches), i, vars, start.Add(time.Duration(i)*time.Second))
+				if err != nil {
+					t.Fatalf("failed with error: %+v", err)
+				}
+				triggered = triggered || trgrd
+			}
+			if triggered != tt.triggered {
+				t.Fatalf("expect triggered to be %t but got %t", tt.triggered, triggered)
+			}
+		})
+	}
+}
+
+func TestEvaluateConditionResults(t *testing.T) {
+	re := regexp.MustCompile(`(?P<drops>\d+) drops`)
+	test := &Test{
+		ID:        1,
+		Pattern:   &Pattern{PatternString: re.String(), RegExp: re},
+		Condition: `delta.drops > 8`,
+	}
+	// The command is executed at two locations, every location's match is compared with its own previous value
+	iterations := [][]string{{"10 drops", "1000 drops"}, {"20 drops", "1005 drops"}}
+	expect := [][]bool{{false, false}, {true, false}}
+	for it, outputs := range iterations {
+		for ri, o := range outputs {
+			b := []byte(o)
+			m := re.FindSubmatchIndex(b)
+			triggered, err := test.EvaluateCondition(b, m, ConditionMatch{Result: ri}, 1, it, nil, time.Now())
+			if err != nil {
+				t.Fatalf("failed with error: %+v", err)
+			}
+			if triggered != expect[it][ri] {
+				t.Fatalf("iteration %d result %d: expect triggered to be %t but got %t", it, ri, expect[it][ri], triggered)
+			}
+		}
+	}
+	if v := test.ConditionStore[1][ConditionMatch{Result: 1}].Values["drops"]; v != "1005" {
+		t.Fatalf("expected stored value 1005, got %q", v)
+	}
+	if len(test.ValuesStore) != 0 {
+		t.Fatalf("values of fields must not be used by conditions: %+v", test.ValuesStore)
+	}
+}
+
+func TestCompileCondition(t *testing.T) {
+	re := regexp.MustCompile(`(?P<intf>\S+) is (?P<state>\w+), (?P<drops>[0-9,]+) drops`)
+	tests := []struct {
+		name      string
+		pattern   *regexp.Regexp
+		condition string
+		fail      bool
+	}{
+		{
+			name:      "captures are strings",
+			pattern:   re,
+			condition: `state == "UP" && intf startsWith "Hu"`,
+		},
+		{
+			name:      "numbers are parsed explicitly",
+			pattern:   re,
+			condition: `num(drops) > 100 || rate.drops > 10.5`,
+		},
+		{
+			name:      "string compared with a number",
+			pattern:   re,
+			condition: `drops > 100`,
+			fail:      true,
+		},
+		{
+			name:      "unknown capture group",
+			pattern:   re,
+			condition: `errors > 0`,
+			fail:      true,
+		},
+		{
+			name:      "reserved capture group",
+			pattern:   regexp.MustCompile(`(?P<num>\d+) drops`),
+			condition: `true`,
+			fail:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileCondition(&Test{ID: 1, Pattern: &Pattern{PatternString: tt.pattern.String(), RegExp: tt.pattern}, Condition: tt.condition})
+			if err != nil && !tt.fail {
+				t.Fatalf("supposed to succeed but failed with error: %+v", err)
+			}
+			if err == nil && tt.fail {
+				t.Fatalf("supposed to fail but succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package types
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/sbezverk/routercommander/pkg/platform"
+)
+
+// ConfigError is returned when a configuration block is rejected by the router, the configuration
+// is aborted or rolled back before the error is returned.
+type ConfigError struct {
+	// Stage is the stage of the configuration block which failed: line, commit, post check or confirm
+	Stage string
+	// Cmd is the command which failed
+	Cmd string
+	// Output is the output explaining the failure, for failed commits it is the output of "show configuration failed"
+	Output []byte
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("configuration %s %q failed: %s", e.Stage, e.Cmd, string(bytes.TrimSpace(e.Output)))
+}
+
+// configSession sends configuration commands to the router keeping the transcript of the session
+type configSession struct {
+	ctx        context.Context
+	e          *executor
+	mode       *platform.ConfigMode
+	debug      bool
+	timeout    int
+	transcript bytes.Buffer
+}
+
+func (s *configSession) send(cmd string) ([]byte, error) {
+	return s.sendWithContext(s.ctx, cmd)
+}
+
+// cleanup sends the command aborting or rolling back the configuration, it is sent even when the session's
+// context is cancelled, so the router is not left with a half applied configuration.
+func (s *configSession) cleanup(cmd string) ([]byte, error) {
+	return s.sendWithContext(context.WithoutCancel(s.ctx), cmd)
+}
+
+func (s *configSession) sendWithContext(ctx context.Context, cmd string) ([]byte, error) {
+	b, err := s.e.r.GetData(ctx, cmd, s.debug, s.timeout)
+	s.transcript.WriteString(cmd + "\n")
+	s.transcript.Write(b)
+	if len(b) != 0 && b[len(b)-1] != '\n' {
+		s.transcript.WriteString("\n")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to send configuration command %q with error: %w", cmd, err)
+	}
+
+	return b, nil
+}
+
+// abort discards the configuration which is not committed yet and leaves the configuration mode
+func (s *configSession) abort() {
+	for _, cmd := range s.mode.Abort {
+		if _, err := s.cleanup(cmd); err != nil {
+			glog.Errorf("failed to abort configuration session with error: %+v", err)
+			return
+		}
+	}
+}
+
+// commit commits the configuration and returns the commit's output, when the commit fails, the reason of the
+// failure is collected and the configuration session is aborted. Platforms applying configuration lines as
+// they are entered only leave the configuration mode.
+func (s *configSession) commit(cmd string) ([]byte, error) {
+	if cmd == "" {
+		_, err := s.send(s.mode.End)
+		return nil, err
+	}
+	b, err := s.send(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if s.mode.CommitFailed == nil || !s.mode.CommitFailed.Match(b) {
+		if s.mode.End != "" {
+			_, err = s.send(s.mode.End)
+		}
+		return b, err
+	}
+	failed := b
+	if s.mode.ShowFailed != "" {
+		if failed, err = s.cleanup(s.mode.ShowFailed); err != nil {
+			glog.Errorf("failed to collect failed configuration with error: %+v", err)
+			failed = b
+		}
+	}
+	s.abort()
+
+	return nil, &ConfigError{Stage: "commit", Cmd: cmd, Output: failed}
+}
+
+// processConfig applies the command's configuration block, when commit confirmed is requested, the post checks
+// are executed before the configuration is committed permanently and the configuration is rolled back if any of them fails.
+func (e *executor) processConfig(ctx context.Context, cmd *Command, collectResult bool, commandTimeout int) ([]*CmdResult, error) {
+	cfg := cmd.Config
+	p := e.r.GetPlatform()
+	if p == nil {
+		return nil, fmt.Errorf("router %s does not support configuration blocks", e.r.GetName())
+	}
+	mode := p.ConfigMode()
+	if cfg.CommitConfirmed > 0 && (mode.CommitConfirmed == nil || mode.Commit == "") {
+		return nil, fmt.Errorf("platform %s of router %s does not support commit confirmed", p.Name(), e.r.GetName())
+	}
+	s := &configSession{
+		ctx:     ctx,
+		e:       e,
+		mode:    mode,
+		debug:   cmd.Debug,
+		timeout: commandTimeout,
+	}
+	start := time.Now()
+	if _, err := s.send(mode.Enter); err != nil {
+		return nil, err
+	}
+	for _, l := range cfg.Lines {
+		b, err := s.send(l)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Interrupted in the middle of the configuration, the router's connection is still alive
+				s.abort()
+			}
+			return nil, err
+		}
+		if p.FindError(b) != nil {
+			s.abort()
+			return nil, &ConfigError{Stage: "line", Cmd: l, Output: b}
+		}
+	}
+	commit := mode.Commit
+	if cfg.CommitConfirmed > 0 {
+		commit = mode.CommitConfirmed(cfg.CommitConfirmed)
+	}
+	// The router rolls back the configuration when it is not confirmed in time, the time is counted from
+	// the moment the commit is sent
+	deadline := time.Now().Add(time.Duration(cfg.CommitConfirmed) * time.Second)
+	if _, err := s.commit(commit); err != nil {
+		return nil, err
+	}
+	if cfg.CommitConfirmed > 0 {
+		if err := e.postChecks(ctx, cfg.PostChecks, deadline); err != nil {
+			if !time.Now().Before(deadline) {
+				// The router has rolled back the configuration, rolling back once more would undo the previous commit
+				return nil, err
+			}
+			rollback := mode.Rollback
+			if cfg.RollbackCommand != "" {
+				rollback = []string{cfg.RollbackCommand}
+			}
+			glog.Warningf("router %s: post checks of configuration %q failed, rolling back with %q", e.r.GetName(), cmd.Cmd, rollback)
+			for _, rc := range rollback {
+				if _, rerr := s.cleanup(rc); rerr != nil {
+					glog.Errorf("router %s: failed to roll back configuration with error: %+v", e.r.GetName(), rerr)
+					break
+				}
+			}
+			return nil, err
+		}
+		// All post checks passed, confirming the commit unless the router has already rolled it back
+		if !time.Now().Before(deadline) {
+			return nil, &ConfigError{Stage: "confirm", Cmd: commit, Output: []byte(fmt.Sprintf("post checks took longer than %d seconds, the configuration is rolled back", cfg.CommitConfirmed))}
+		}
+		if _, err := s.send(mode.Enter); err != nil {
+			return nil, err
+		}
+		b, err := s.commit(mode.Commit)
+		if err != nil {
+			if ce, ok := err.(*ConfigError); ok {
+				ce.Stage = "confirm"
+			}
+			return nil, err
+		}
+		// Nothing to confirm, the configuration is already rolled back
+		if mode.NoChanges != nil && mode.NoChanges.Match(b) {
+			return nil, &ConfigError{Stage: "confirm", Cmd: mode.Commit, Output: b}
+		}
+	}
+	if !collectResult {
+		return []*CmdResult{}, nil
+	}
+	name := cmd.Cmd
+	if name == "" {
+		name = mode.Enter
+	}
+
+	return []*CmdResult{
+		{
+			Cmd:    name,
+			Start:  start,
+			End:    time.Now(),
+			Result: s.transcript.Bytes(),
+		},
+	}, nil
+}
+
+// postChecks executes the post check commands, a post check fails when the command fails, when it is
+// rejected by the router or when any of its patterns is found in the output. Post checks fail when the deadline
+// passes before all of them are executed.
+func (e *executor) postChecks(ctx context.Context, checks []*Command, deadline time.Time) error {
+	for _, c := range checks {
+		if !time.Now().Before(deadline) {
+			return &ConfigError{Stage: "post check", Cmd: c.Cmd, Output: []byte("commit confirmed time passed before the post check")}
+		}
+		rs, err := e.processCommand(ctx, c, true)
+		if err != nil {
+			return &ConfigError{Stage: "post check", Cmd: c.Cmd, Output: []byte(err.Error())}
+		}
+		for _, re := range rs {
+			if re.Error != "" && c.OnError != OnErrorContinue {
+				return &ConfigError{Stage: "post check", Cmd: re.Cmd, Output: []byte(re.Error)}
+			}
+			for _, p := range c.Patterns {
+				if p.RegExp == nil {
+					continue
+				}
+				if m := p.RegExp.Find(re.Result); m != nil {
+					return &ConfigError{Stage: "post check", Cmd: re.Cmd, Output: m}
+				}
+			}
+		}
+	}
+
+	return nil
+}
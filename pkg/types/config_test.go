@@ -0,0 +1,263 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbezverk/routercommander/pkg/log"
+	"github.com/sbezverk/routercommander/pkg/platform"
+)
+
+var _ Router = &scriptedRouter{}
+
+// scriptedRouter returns outputs defined per command and records all commands sent to it
+type scriptedRouter struct {
+	platform string
+	outputs  map[string]string
+	// delays are delays of outputs per command
+	delays map[string]time.Duration
+	sent   []string
+	// cancel is called after cancelAfter command is sent
+	cancel      context.CancelFunc
+	cancelAfter string
+}
+
+func (sr *scriptedRouter) IsExistingLocation(string) bool { return false }
+func (sr *scriptedRouter) GetAllLCs() []string            { return nil }
+func (sr *scriptedRouter) GetAllRPs() []string            { return nil }
+func (sr *scriptedRouter) GetActiveRP() string            { return "" }
+func (sr *scriptedRouter) GetAllLocations() []string      { return nil }
+func (sr *scriptedRouter) GetName() string                { return "r1" }
+func (sr *scriptedRouter) Close()                         {}
+func (sr *scriptedRouter) GetLogger() log.Logger          { return nil }
+
+func (sr *scriptedRouter) GetPlatform() platform.Platform {
+	return platform.Get(sr.platform)
+}
+
+func (sr *scriptedRouter) GetData(ctx context.Context, cmd string, debug bool, commandTimeout int) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sr.sent = append(sr.sent, cmd)
+	time.Sleep(sr.delays[cmd])
+	if sr.cancel != nil && cmd == sr.cancelAfter {
+		sr.cancel()
+	}
+	return []byte(sr.outputs[cmd]), nil
+}
+
+func (sr *scriptedRouter) ProcessCommand(ctx context.Context, cmd *Command, collectResult bool) ([]*CmdResult, error) {
+	e := &executor{r: sr, pace: false}
+	return e.processCommand(ctx, cmd, collectResult)
+}
+
+func TestProcessConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		platform string
+		config   *ConfigBlock
+		outputs  map[string]string
+		delays   map[string]time.Duration
+		sent     []string
+		stage    string
+		fail     bool
+	}{
+		{
+			name: "commit",
+			config: &ConfigBlock{
+				Lines: []string{"interface Loopback100", "description test"},
+			},
+			sent: []string{"configure terminal", "interface Loopback100", "description test", "commit", "end"},
+		},
+		{
+			name: "invalid line",
+			config: &ConfigBlock{
+				Lines: []string{"interface Loopback100", "descr test"},
+			},
+			outputs: map[string]string{
+				"descr test": "              ^\n% Invalid input detected at '^' marker.\n",
+			},
+			sent:  []string{"configure terminal", "interface Loopback100", "descr test", "abort"},
+			stage: "line",
+		},
+		{
+			name: "failed commit",
+			config: &ConfigBlock{
+				Lines: []string{"router bgp 65000", "neighbor 10.0.0.1 remote-as 65001"},
+			},
+			outputs: map[string]string{
+				"commit":                    "% Failed to commit one or more configuration items during a pseudo-atomic operation. All changes made have been reverted. Please issue 'show configuration failed [inheritance]' from this session to view the errors\n",
+				"show configuration failed": "!! SEMANTIC ERRORS: This configuration was rejected by the system\nrouter bgp 65000\n neighbor 10.0.0.1\n!!% Address family not configured\n",
+			},
+			sent:  []string{"configure terminal", "router bgp 65000", "neighbor 10.0.0.1 remote-as 65001", "commit", "show configuration failed", "abort"},
+			stage: "commit",
+		},
+		{
+			name: "commit confirmed post checks passed",
+			config: &ConfigBlock{
+				Lines:           []string{"interface Bundle-Ether1", "shutdown"},
+				CommitConfirmed: 60,
+				PostChecks: []*Command{
+					{
+						Cmd:      "show bgp summary",
+						Patterns: []*Pattern{{PatternString: "Idle", RegExp: regexp.MustCompile("Idle")}},
+					},
+				},
+			},
+			outputs: map[string]string{
+				"show bgp summary": "10.0.0.1  0 65001  100  100  10  0  0 1d00h  100\n",
+			},
+			sent: []string{"configure terminal", "interface Bundle-Ether1", "shutdown", "commit confirmed 60", "end", "show bgp summary",
+				"configure terminal", "commit", "end"},
+		},
+		{
+			name: "commit confirmed post checks failed",
+			config: &ConfigBlock{
+				Lines:           []string{"interface Bundle-Ether1", "shutdown"},
+				CommitConfirmed: 60,
+				PostChecks: []*Command{
+					{
+						Cmd:      "show bgp summary",
+						Patterns: []*Pattern{{PatternString: "Idle", RegExp: regexp.MustCompile("Idle")}},
+					},
+				},
+			},
+			outputs: map[string]string{
+				"show bgp summary": "10.0.0.1  0 65001  100  100  10  0  0 00:00:05 Idle\n",
+			},
+			sent:  []string{"configure terminal", "interface Bundle-Ether1", "shutdown", "commit confirmed 60", "end", "show bgp summary", "rollback configuration last 1"},
+			stage: "post check",
+		},
+		{
+			name: "commit confirmed nothing to confirm",
+			config: &ConfigBlock{
+				Lines:           []string{"interface Bundle-Ether1", "shutdown"},
+				CommitConfirmed: 60,
+				PostChecks:      []*Command{{Cmd: "show bgp summary"}},
+			},
+			outputs: map[string]string{
+				"commit": "No configuration changes to commit.\n",
+			},
+			sent: []string{"configure terminal", "interface Bundle-Ether1", "shutdown", "commit confirmed 60", "end", "show bgp summary",
+				"configure terminal", "commit", "end"},
+			stage: "confirm",
+		},
+		{
+			name: "commit confirmed expired",
+			config: &ConfigBlock{
+				Lines:           []string{"interface Bundle-Ether1", "shutdown"},
+				CommitConfirmed: 1,
+				PostChecks:      []*Command{{Cmd: "show bgp summary"}, {Cmd: "show isis neighbors"}},
+			},
+			delays: map[string]time.Duration{
+				"show bgp summary": time.Second,
+			},
+			// The router rolls back the configuration by itself, it is neither confirmed nor rolled back
+			sent:  []string{"configure terminal", "interface Bundle-Ether1", "shutdown", "commit confirmed 1", "end", "show bgp summary"},
+			stage: "post check",
+		},
+		{
+			name:     "nxos applies lines without commit",
+			platform: platform.NXOS,
+			config: &ConfigBlock{
+				Lines: []string{"interface Ethernet1/1", "description test"},
+			},
+			sent: []string{"configure terminal", "interface Ethernet1/1", "description test", "end"},
+		},
+		{
+			name:     "nxos does not support commit confirmed",
+			platform: platform.NXOS,
+			config: &ConfigBlock{
+				Lines:           []string{"interface Ethernet1/1", "shutdown"},
+				CommitConfirmed: 60,
+			},
+			fail: true,
+		},
+		{
+			name:     "junos invalid line",
+			platform: platform.Junos,
+			config: &ConfigBlock{
+				Lines: []string{"set interfaces xe-0/0/0 descr test"},
+			},
+			outputs: map[string]string{
+				"set interfaces xe-0/0/0 descr test": "                              ^\nsyntax error.\n",
+			},
+			sent:  []string{"configure", "set interfaces xe-0/0/0 descr test", "rollback 0", "exit configuration-mode"},
+			stage: "line",
+		},
+		{
+			name:     "junos commit confirmed post checks failed",
+			platform: platform.Junos,
+			config: &ConfigBlock{
+				Lines:           []string{"set interfaces ae0 disable"},
+				CommitConfirmed: 90,
+				PostChecks: []*Command{
+					{
+						Cmd:      "show bgp summary",
+						Patterns: []*Pattern{{PatternString: "Idle", RegExp: regexp.MustCompile("Idle")}},
+					},
+				},
+			},
+			outputs: map[string]string{
+				"show bgp summary": "10.0.0.1  65001  100  100  0  0  5 Idle\n",
+			},
+			sent: []string{"configure", "set interfaces ae0 disable", "commit confirmed 2", "exit configuration-mode", "show bgp summary",
+				"configure", "rollback 1", "commit", "exit configuration-mode"},
+			stage: "post check",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &scriptedRouter{platform: tt.platform, outputs: tt.outputs, delays: tt.delays}
+			rs, err := r.ProcessCommand(context.Background(), &Command{Cmd: "test config", Config: tt.config}, true)
+			if !reflect.DeepEqual(r.sent, tt.sent) {
+				t.Fatalf("expected commands %q but sent %q", tt.sent, r.sent)
+			}
+			if tt.fail {
+				if err == nil {
+					t.Fatal("supposed to fail but succeeded")
+				}
+				return
+			}
+			if tt.stage == "" {
+				if err != nil {
+					t.Fatalf("failed with error: %+v", err)
+				}
+				if len(rs) != 1 || rs[0].Cmd != "test config" || !strings.Contains(string(rs[0].Result), tt.config.Lines[0]) {
+					t.Fatalf("unexpected results: %+v", rs)
+				}
+				return
+			}
+			var ce *ConfigError
+			if !errors.As(err, &ce) {
+				t.Fatalf("expected configuration error but got: %+v", err)
+			}
+			if ce.Stage != tt.stage {
+				t.Fatalf("expected failure at stage %q but got %q", tt.stage, ce.Stage)
+			}
+		})
+	}
+}
+
+func TestProcessConfigInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := &scriptedRouter{cancel: cancel, cancelAfter: "interface Loopback100"}
+	_, err := r.ProcessCommand(ctx, &Command{Cmd: "test config", Config: &ConfigBlock{
+		Lines: []string{"interface Loopback100", "description test"},
+	}}, true)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error but got: %+v", err)
+	}
+	// The configuration session is aborted even though the context is cancelled
+	sent := []string{"configure terminal", "interface Loopback100", "abort"}
+	if !reflect.DeepEqual(r.sent, sent) {
+		t.Fatalf("expected commands %q but sent %q", sent, r.sent)
+	}
+}
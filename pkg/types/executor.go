@@ -0,0 +1,339 @@
+package types
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Delay waits for d seconds, the wait is interrupted when the context is cancelled.
+func Delay(ctx context.Context, d int) error {
+	t := time.NewTimer(time.Duration(d) * time.Second)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CommandError is returned when the router rejects the command and the command's on_error policy is fail.
+type CommandError struct {
+	// Result is the result of the rejected command, its Error is the line reporting the rejection
+	Result *CmdResult
+}
+
+func (e *CommandError) Error() string {
+	if e.Result.Location != "" {
+		return fmt.Sprintf("command %q at location %s is rejected by the router: %s", e.Result.Cmd, e.Result.Location, e.Result.Error)
+	}
+	return fmt.Sprintf("command %q is rejected by the router: %s", e.Result.Cmd, e.Result.Error)
+}
+
+// executor runs a command against a router according to the command's parameters: locations,
+// number of times, interval, etc. The router provides the actual transport via GetData.
+type executor struct {
+	r Router
+	// pace defines if waits and intervals defined by the command are honored, it is not needed
+	// when the output does not come from a live router.
+	pace bool
+}
+
+func (e *executor) delay(ctx context.Context, d int) error {
+	if e.pace {
+		return Delay(ctx, d)
+	}
+	return ctx.Err()
+}
+
+func (e *executor) processCommand(ctx context.Context, cmd *Command, collectResult bool) ([]*CmdResult, error) {
+	c := cmd.Cmd
+	results := make([]*CmdResult, 0)
+
+	// TODO (sbezverk) Add some sanity check for this timer
+
+	if cmd.WaitBefore != 0 {
+		if err := e.delay(ctx, cmd.WaitBefore); err != nil {
+			return nil, err
+		}
+	}
+	commandTimeout := DefaultCommandTimeout
+	if cmd.CmdTimeout != 0 && cmd.CmdTimeout > DefaultCommandTimeout {
+		commandTimeout = cmd.CmdTimeout
+	}
+	if cmd.Config != nil {
+		rs, err := e.processConfig(ctx, cmd, collectResult, commandTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if cmd.WaitAfter != 0 {
+			if err := e.delay(ctx, cmd.WaitAfter); err != nil {
+				return nil, err
+			}
+		}
+		return rs, nil
+	}
+	pipeModifier := ""
+	if cmd.PipeModifier != "" {
+		pipeModifier += " | " + cmd.PipeModifier
+	}
+	if len(cmd.Location) == 0 {
+		var err error
+		rs, err := e.sendCommand(ctx, c+pipeModifier, cmd.Times, cmd.Interval, cmd.Debug, commandTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkRejected(e.r, cmd, rs); err != nil {
+			return nil, err
+		}
+		results = append(results, collect(rs, collectResult)...)
+	} else {
+		locs, err := prepareLocations(e.r, cmd)
+		if err != nil {
+			return nil, err
+		}
+		rs, err := e.sendCommandWithLocations(ctx, cmd, locs, pipeModifier, commandTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkRejected(e.r, cmd, rs); err != nil {
+			return nil, err
+		}
+		results = append(results, collect(rs, collectResult)...)
+	}
+	if cmd.WaitAfter != 0 {
+		if err := e.delay(ctx, cmd.WaitAfter); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// checkRejected applies the command's on_error policy to the results rejected by the router.
+func checkRejected(r Router, cmd *Command, rs []*CmdResult) error {
+	for _, re := range rs {
+		if re.Error == "" {
+			continue
+		}
+		switch cmd.OnError {
+		case OnErrorFail:
+			return &CommandError{Result: re}
+		case OnErrorContinue:
+			if glog.V(5) {
+				glog.Infof("router %s: command %q is rejected: %s", r.GetName(), re.Cmd, re.Error)
+			}
+		default:
+			glog.Warningf("router %s: command %q is rejected: %s", r.GetName(), re.Cmd, re.Error)
+		}
+	}
+
+	return nil
+}
+
+// collect returns all results when they are collected, otherwise only results rejected by the router
+// are returned, so the rejection can be reported.
+func collect(rs []*CmdResult, collectResult bool) []*CmdResult {
+	if collectResult {
+		return rs
+	}
+	rejected := make([]*CmdResult, 0)
+	for _, re := range rs {
+		if re.Error != "" {
+			rejected = append(rejected, re)
+		}
+	}
+
+	return rejected
+}
+
+// findError returns the line of the output reporting the command was rejected by the router, routers
+// without the platform do not report rejected commands.
+func (e *executor) findError(b []byte) string {
+	p := e.r.GetPlatform()
+	if p == nil {
+		return ""
+	}
+
+	return string(bytes.TrimSpace(p.FindError(b)))
+}
+
+func transforLocation(tmpl *template.Template, loc string) (string, error) {
+	l := strings.Split(loc, "/")
+	if len(l) < 3 {
+		return "", fmt.Errorf("location %s is in unknown format", loc)
+	}
+	slot, err := strconv.ParseInt(l[1], 10, 0)
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, struct {
+		Slot int
+	}{
+		Slot: int(slot),
+	}); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func prepareLocations(r Router, cmd *Command) ([]string, error) {
+	locs := make([]string, 0)
+	for _, l := range cmd.Location {
+		switch l {
+		case "all":
+			locs = append(locs, r.GetAllLocations()...)
+		case "all-rp":
+			locs = append(locs, r.GetAllRPs()...)
+		case "all-lc":
+			locs = append(locs, r.GetAllLCs()...)
+		default:
+			locs = append(locs, l)
+		}
+	}
+	if cmd.LocationFmtTmpl == "" {
+		// No location customization format
+		return locs, nil
+	}
+	tmpl, err := template.New("Slot").Parse(cmd.LocationFmtTmpl)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(locs); i++ {
+		locs[i], err = transforLocation(tmpl, locs[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return locs, nil
+}
+
+func (e *executor) sendCommandWithLocations(ctx context.Context, cmd *Command, locations []string, pipeModifier string, commandTimeout int) ([]*CmdResult, error) {
+	results := make([]*CmdResult, 0)
+	var tmpl *template.Template
+	var err error
+	if cmd.LocationCustomized {
+		tmpl, err = template.New("Command").Parse(cmd.Cmd)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, l := range locations {
+		switch l {
+		case "all":
+			expanded := e.r.GetAllLocations()
+			rs, err := e.sendCommandWithLocations(ctx, cmd, expanded, pipeModifier, commandTimeout)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, rs...)
+		case "all-rp":
+			expanded := e.r.GetAllRPs()
+			rs, err := e.sendCommandWithLocations(ctx, cmd, expanded, pipeModifier, commandTimeout)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, rs...)
+		case "all-lc":
+			expanded := e.r.GetAllLCs()
+			rs, err := e.sendCommandWithLocations(ctx, cmd, expanded, pipeModifier, commandTimeout)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, rs...)
+		default:
+			var fc string
+			if !cmd.LocationCustomized {
+				fc = cmd.Cmd + " " + "location " + l + " " + pipeModifier
+
+			} else {
+				buf := new(bytes.Buffer)
+				if err := tmpl.Execute(buf, struct {
+					Location string
+				}{
+					Location: l,
+				}); err != nil {
+					return nil, err
+				}
+				fc = buf.String() + " " + pipeModifier
+			}
+			rs, err := e.sendCommand(ctx, fc, cmd.Times, cmd.Interval, cmd.Debug, commandTimeout)
+			if err != nil {
+				return nil, err
+			}
+			for _, re := range rs {
+				re.Location = l
+			}
+			results = append(results, rs...)
+		}
+	}
+
+	return results, nil
+}
+
+func (e *executor) sendCommand(ctx context.Context, cmd string, times, interval int, debug bool, commandTimeout int) ([]*CmdResult, error) {
+	if glog.V(5) {
+		if interval == 0 || times == 0 {
+			glog.Infof("Sending command: %q to router: %q, command timeout: %d seconds", cmd, e.r.GetName(), commandTimeout)
+		} else {
+			glog.Infof("Sending command: %q, %d times with interval of %d seconds to router: %q, command timeout: %d seconds", cmd, times, interval, e.r.GetName(), commandTimeout)
+		}
+	}
+	if interval == 0 || times == 0 {
+		start := time.Now()
+		b, err := e.r.GetData(ctx, cmd, debug, commandTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return []*CmdResult{
+			{
+				Cmd:    cmd,
+				Start:  start,
+				End:    time.Now(),
+				Result: b,
+				Error:  e.findError(b),
+			},
+		}, err
+	}
+	results := make([]*CmdResult, 0)
+	var tick <-chan time.Time
+	if e.pace {
+		ticker := time.NewTicker(time.Second * time.Duration(interval))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for t := 0; t < times; t++ {
+		start := time.Now()
+		b, err := e.r.GetData(ctx, cmd, debug, commandTimeout)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &CmdResult{
+			Cmd:    cmd,
+			Start:  start,
+			End:    time.Now(),
+			Result: b,
+			Error:  e.findError(b),
+		})
+		if tick == nil {
+			continue
+		}
+		select {
+		case <-tick:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,99 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sbezverk/routercommander/pkg/platform"
+)
+
+func TestProcessCommandRejected(t *testing.T) {
+	invalid := "show bgp sumary\n              ^\n% Invalid input detected at '^' marker.\n"
+	tests := []struct {
+		name     string
+		platform string
+		cmd      *Command
+		output   string
+		collect  bool
+		rejected string
+		results  int
+		fail     bool
+	}{
+		{
+			name:    "accepted",
+			cmd:     &Command{Cmd: "show bgp summary"},
+			output:  "BGP router identifier 10.0.0.1, local AS number 65000\n",
+			collect: true,
+			results: 1,
+		},
+		{
+			name:     "rejected with default policy",
+			cmd:      &Command{Cmd: "show bgp summary"},
+			output:   invalid,
+			collect:  true,
+			rejected: "% Invalid input detected at '^' marker.",
+			results:  1,
+		},
+		{
+			name:     "rejected result is returned when results are not collected",
+			cmd:      &Command{Cmd: "show bgp summary", OnError: OnErrorWarn},
+			output:   invalid,
+			rejected: "% Invalid input detected at '^' marker.",
+			results:  1,
+		},
+		{
+			name:     "rejected with continue",
+			cmd:      &Command{Cmd: "show bgp summary", OnError: OnErrorContinue},
+			output:   invalid,
+			collect:  true,
+			rejected: "% Invalid input detected at '^' marker.",
+			results:  1,
+		},
+		{
+			name:     "rejected with fail",
+			cmd:      &Command{Cmd: "show bgp summary", OnError: OnErrorFail},
+			output:   invalid,
+			rejected: "% Invalid input detected at '^' marker.",
+			fail:     true,
+		},
+		{
+			name:     "junos syntax error",
+			platform: platform.Junos,
+			cmd:      &Command{Cmd: "show bgp summary", OnError: OnErrorFail},
+			output:   "show bgp sumary\n              ^\nsyntax error, expecting <command>.\n",
+			rejected: "syntax error, expecting <command>.",
+			fail:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := &scriptedRouter{
+				platform: tt.platform,
+				outputs:  map[string]string{tt.cmd.Cmd: tt.output},
+			}
+			rs, err := sr.ProcessCommand(context.Background(), tt.cmd, tt.collect)
+			if err != nil && !tt.fail {
+				t.Fatalf("supposed to succeed but failed with error: %+v", err)
+			}
+			if err == nil && tt.fail {
+				t.Fatalf("supposed to fail but succeeded")
+			}
+			if err != nil {
+				var ce *CommandError
+				if !errors.As(err, &ce) || ce.Result.Error != tt.rejected {
+					t.Fatalf("expected command error %q, got: %+v", tt.rejected, err)
+				}
+				return
+			}
+			if len(rs) != tt.results {
+				t.Fatalf("expected %d results, got %d", tt.results, len(rs))
+			}
+			for _, re := range rs {
+				if re.Error != tt.rejected {
+					t.Fatalf("expected error %q, got %q", tt.rejected, re.Error)
+				}
+			}
+		})
+	}
+}
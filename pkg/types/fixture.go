@@ -0,0 +1,81 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture is the raw session with a router captured in the record mode, it is used by the simulated
+// router to reproduce the router's exact behaviour.
+type Fixture struct {
+	Router   string `yaml:"router"`
+	Platform string `yaml:"platform,omitempty"`
+	// Banner is the raw output received before the first prompt, including the prompt
+	Banner    string             `yaml:"banner"`
+	Exchanges []*FixtureExchange `yaml:"exchanges"`
+}
+
+// FixtureExchange is a command sent to the router and the exact byte stream received while the command
+// was executed, including the command's echo and the prompt.
+type FixtureExchange struct {
+	Command string `yaml:"command"`
+	Raw     string `yaml:"raw"`
+}
+
+// LoadFixture reads the fixture from the file.
+func LoadFixture(fn string) (*Fixture, error) {
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture file %s with error: %+v", fn, err)
+	}
+	f := &Fixture{}
+	if err := yaml.Unmarshal(b, f); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal fixture file %s with error: %+v", fn, err)
+	}
+
+	return f, nil
+}
+
+// Save writes the fixture to the file.
+func (f *Fixture) Save(fn string) error {
+	b, err := yaml.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("failed to marshal fixture of router %s with error: %+v", f.Router, err)
+	}
+	if err := os.WriteFile(fn, b, 0644); err != nil {
+		return fmt.Errorf("failed to write fixture file %s with error: %+v", fn, err)
+	}
+
+	return nil
+}
+
+// recordingReader keeps everything read from the router's stdout until it is taken
+type recordingReader struct {
+	rd  io.Reader
+	mx  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (rr *recordingReader) Read(p []byte) (int, error) {
+	n, err := rr.rd.Read(p)
+	rr.mx.Lock()
+	rr.buf.Write(p[:n])
+	rr.mx.Unlock()
+
+	return n, err
+}
+
+// take returns the bytes read since the previous call
+func (rr *recordingReader) take() string {
+	rr.mx.Lock()
+	defer rr.mx.Unlock()
+	s := rr.buf.String()
+	rr.buf.Reset()
+
+	return s
+}
@@ -0,0 +1,65 @@
+package types
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/sbezverk/routercommander/pkg/platform"
+)
+
+func TestFixtureSendCommand(t *testing.T) {
+	f, err := LoadFixture("fixture.yaml")
+	if err != nil {
+		t.Fatalf("failed to load fixture with error: %+v", err)
+	}
+	p := platform.Get(f.Platform)
+	// Terminal escape sequences are removed from outputs
+	expect := map[string]string{
+		"show clock":               "\nMon Jan  2 10:00:00.000 UTC\n",
+		"show version | i Version": "\nCisco IOS XR Software, Version 7.5.2\n",
+		"configure terminal":       "\n",
+		"end":                      "\n",
+	}
+	for _, e := range f.Exchanges {
+		t.Run(e.Command, func(t *testing.T) {
+			stdinR, stdinW := io.Pipe()
+			stdoutR, stdoutW := io.Pipe()
+			go func() {
+				defer stdoutW.Close()
+				buf := make([]byte, 4096)
+				stdinR.Read(buf) //nolint:errcheck
+				io.WriteString(stdoutW, e.Raw)
+			}()
+			b, err := sendCommand(context.Background(), stdinW, stdoutR, p.Prompts(), e.Command, false, nil, 5)
+			if err != nil {
+				t.Fatalf("failed to process recorded command with error: %+v", err)
+			}
+			if string(b) != expect[e.Command] {
+				t.Fatalf("expected %q, got %q", expect[e.Command], string(b))
+			}
+		})
+	}
+}
+
+func TestFixtureSave(t *testing.T) {
+	f := &Fixture{
+		Router: "r1",
+		Exchanges: []*FixtureExchange{
+			// Streams which are not valid UTF-8 must survive the round trip
+			{Command: "show controllers", Raw: "show controllers\r\n\xff\xfe\r\nRP/0/RP0/CPU0:r1#"},
+		},
+	}
+	fn := filepath.Join(t.TempDir(), "r1.yaml")
+	if err := f.Save(fn); err != nil {
+		t.Fatalf("failed to save fixture with error: %+v", err)
+	}
+	l, err := LoadFixture(fn)
+	if err != nil {
+		t.Fatalf("failed to load fixture with error: %+v", err)
+	}
+	if l.Exchanges[0].Raw != f.Exchanges[0].Raw {
+		t.Fatalf("expected %q, got %q", f.Exchanges[0].Raw, l.Exchanges[0].Raw)
+	}
+}
@@ -0,0 +1,133 @@
+package types
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"gopkg.in/yaml.v3"
+)
+
+// loadCommandFile reads the commands file and merges the files it includes, see merge. Files being loaded are
+// kept in the stack to detect cycles, a file included more than once is merged only the first time.
+func loadCommandFile(fn string, stack []string, loaded map[string]bool) (*Commander, error) {
+	abs, err := filepath.Abs(fn)
+	if err != nil {
+		return nil, fmt.Errorf("fail to resolve path of file %s with error: %+v", fn, err)
+	}
+	for _, f := range stack {
+		if f == abs {
+			return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), abs)
+		}
+	}
+	loaded[abs] = true
+	b, err := readCommandFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	c := &Commander{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal commands yaml %s with error: %+v", fn, err)
+	}
+	if len(c.Include) == 0 {
+		return c, nil
+	}
+	stack = append(stack, abs)
+	m := &Commander{}
+	for _, inc := range c.Include {
+		files, err := resolveInclude(filepath.Dir(fn), inc)
+		if err != nil {
+			return nil, fmt.Errorf("file %s: %+v", fn, err)
+		}
+		for _, f := range files {
+			if a, err := filepath.Abs(f); err == nil && loaded[a] && !onStack(stack, a) {
+				glog.V(5).Infof("file %s: %s is already included", fn, f)
+				continue
+			}
+			ic, err := loadCommandFile(f, stack, loaded)
+			if err != nil {
+				return nil, err
+			}
+			merge(m, ic)
+		}
+	}
+	merge(m, c)
+
+	return m, nil
+}
+
+// resolveInclude returns files matching the include, relative paths are relative to the directory of the
+// including file, an include which does not match any file is an error.
+func resolveInclude(dir string, inc string) ([]string, error) {
+	if !filepath.IsAbs(inc) {
+		inc = filepath.Join(dir, inc)
+	}
+	files, err := filepath.Glob(inc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include %q with error: %+v", inc, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("include %q does not match any file", inc)
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func onStack(stack []string, f string) bool {
+	for _, s := range stack {
+		if s == f {
+			return true
+		}
+	}
+	return false
+}
+
+// merge merges src into dst, src is merged after dst: commands of src follow commands of dst, tests of src for a command
+// already having tests are merged by test id, a test id defined by both is replaced by src's test, variables, collect
+// and repro sections of src override dst's.
+func merge(dst, src *Commander) {
+	if src.Vars != nil {
+		if dst.Vars == nil {
+			dst.Vars = make(map[string]string, len(src.Vars))
+		}
+		for k, v := range src.Vars {
+			dst.Vars[k] = v
+		}
+	}
+	if src.Repro != nil {
+		dst.Repro = src.Repro
+	}
+	if src.Collect != nil {
+		dst.Collect = src.Collect
+	}
+	dst.MainCommandGroup = append(dst.MainCommandGroup, src.MainCommandGroup...)
+	for _, st := range src.Tests {
+		if st == nil {
+			continue
+		}
+		var dt *Tests
+		for _, t := range dst.Tests {
+			if t != nil && t.Cmd == st.Cmd {
+				dt = t
+				break
+			}
+		}
+		if dt == nil {
+			dst.Tests = append(dst.Tests, st)
+			continue
+		}
+	next:
+		for _, s := range st.Source {
+			for i, d := range dt.Source {
+				if s != nil && d != nil && d.ID == s.ID {
+					dt.Source[i] = s
+					continue next
+				}
+			}
+			dt.Source = append(dt.Source, s)
+		}
+	}
+}
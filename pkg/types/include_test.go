@@ -0,0 +1,200 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeIncludeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		fn := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fn), 0o755); err != nil {
+			t.Fatalf("failed to create directory with error: %+v", err)
+		}
+		if err := os.WriteFile(fn, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write file %s with error: %+v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestGetCommandsInclude(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		cmds  []string
+		ids   []int
+		vars  map[string]string
+		repro bool
+		fail  string
+	}{
+		{
+			name: "globs in order",
+			files: map[string]string{
+				"main.yaml": `include:
+- lib/*.yaml
+vars:
+  VRF: GI
+commands:
+- command: "show version"`,
+				"lib/b_cef.yaml": `vars:
+  VRF: RED
+  LC: 0/0/CPU0
+commands:
+- command: "show cef drops"`,
+				"lib/a_bgp.yaml": `commands:
+- command: "show bgp summary"`,
+			},
+			cmds: []string{"show bgp summary", "show cef drops", "show version"},
+			vars: map[string]string{"VRF": "GI", "LC": "0/0/CPU0"},
+		},
+		{
+			name: "tests merged by id",
+			files: map[string]string{
+				"main.yaml": `include: [tests.yaml]
+tests:
+- command: "show cef drops"
+  command_tests:
+  - id: 2
+    pattern:
+      pattern_string: 'drops'
+commands:
+- command: "show cef drops"
+  command_test_ids: [1, 2, 3]`,
+				"tests.yaml": `repro:
+  times: 2
+tests:
+- command: "show cef drops"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: 'drops'
+  - id: 2
+    pattern:
+      pattern_string: 'errors'
+  - id: 3
+    pattern:
+      pattern_string: 'discards'`,
+			},
+			cmds:  []string{"show cef drops"},
+			ids:   []int{1, 2, 3},
+			repro: true,
+		},
+		{
+			name: "included twice",
+			files: map[string]string{
+				"main.yaml": `include: [a.yaml, b.yaml]`,
+				"a.yaml": `include: [common.yaml]
+commands:
+- command: "show a"`,
+				"b.yaml": `include: [common.yaml]
+commands:
+- command: "show b"`,
+				"common.yaml": `commands:
+- command: "show common"`,
+			},
+			cmds: []string{"show common", "show a", "show b"},
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"main.yaml": `include: [a.yaml]`,
+				"a.yaml":    `include: [main.yaml]`,
+			},
+			fail: "include cycle",
+		},
+		{
+			name: "no matching files",
+			files: map[string]string{
+				"main.yaml": `include: [lib/*.yaml]`,
+			},
+			fail: "does not match any file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeIncludeFiles(t, tt.files)
+			c, err := GetCommands(filepath.Join(dir, "main.yaml"))
+			if err != nil && tt.fail == "" {
+				t.Fatalf("supposed to succeed but failed with error: %+v", err)
+			}
+			if err == nil && tt.fail != "" {
+				t.Fatalf("supposed to fail but succeeded")
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), tt.fail) {
+					t.Fatalf("expected error %q, got %+v", tt.fail, err)
+				}
+				return
+			}
+			if len(c.MainCommandGroup) != len(tt.cmds) {
+				t.Fatalf("expected %d commands, got %d", len(tt.cmds), len(c.MainCommandGroup))
+			}
+			for i, cmd := range tt.cmds {
+				if c.MainCommandGroup[i].Cmd != cmd {
+					t.Fatalf("expected command %q, got %q", cmd, c.MainCommandGroup[i].Cmd)
+				}
+			}
+			for k, v := range tt.vars {
+				if c.Vars[k] != v {
+					t.Fatalf("expected variable %s %q, got %q", k, v, c.Vars[k])
+				}
+			}
+			if (c.Repro != nil) != tt.repro {
+				t.Fatalf("expected repro %t, got %+v", tt.repro, c.Repro)
+			}
+			if len(tt.ids) == 0 {
+				return
+			}
+			tests := c.CommandsWithTests["show cef drops"]
+			if tests == nil || len(tests.Tests) != len(tt.ids) {
+				t.Fatalf("expected tests %v, got %+v", tt.ids, tests)
+			}
+			// The including file's definition of test id 2 replaces the included one
+			if tests.Tests[2].Pattern.PatternString != "drops" {
+				t.Fatalf("test id 2 is not replaced: %q", tests.Tests[2].Pattern.PatternString)
+			}
+		})
+	}
+}
+
+func TestValidateCommandFileInclude(t *testing.T) {
+	dir := writeIncludeFiles(t, map[string]string{
+		"main.yaml": `include:
+- tests.yaml
+- missing/*.yaml
+- a.yaml
+commands:
+- command: "show cef drops"
+  command_test_ids: [1]`,
+		"tests.yaml": `tests:
+- command: "show cef drops"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: 'drops'
+    occurrence: -1`,
+		"a.yaml": `include: [main.yaml]`,
+	})
+	problems, err := ValidateCommandFile(filepath.Join(dir, "main.yaml"))
+	if err != nil {
+		t.Fatalf("failed to validate with error: %+v", err)
+	}
+	expect := []string{
+		filepath.Join(dir, "main.yaml") + ": line 3: include",
+		filepath.Join(dir, "tests.yaml") + ": line 7: test id 1 occurrence must not be negative",
+		filepath.Join(dir, "a.yaml") + ": line 1: include of " + filepath.Join(dir, "main.yaml") + " makes a cycle",
+	}
+	if len(problems) != len(expect) {
+		t.Fatalf("expected %d problems, got %d: %+v", len(expect), len(problems), problems)
+	}
+	for i, p := range problems {
+		if !strings.HasPrefix(p.String(), expect[i]) {
+			t.Fatalf("expected problem %q, got %q", expect[i], p.String())
+		}
+	}
+}
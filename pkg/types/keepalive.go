@@ -0,0 +1,142 @@
+package types
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"golang.org/x/crypto/ssh"
+)
+
+const keepAliveRequest = "keepalive@openssh.com"
+
+// KeepAlivePolicy defines how the router's SSH transport is checked while it is idle.
+type KeepAlivePolicy struct {
+	// Interval is the number of seconds between keepalive requests, a request not answered within the interval fails
+	Interval int
+	// MaxFailures is the number of consecutive failed keepalive requests after which the transport is considered dead
+	MaxFailures int
+}
+
+// TransportError is returned when the router's transport fails, reading or writing the session fails or keepalive
+// requests detect the SSH transport dead, in the latter case the connection is closed and commands are not sent
+// to the router anymore.
+type TransportError struct {
+	Router string
+	// Failures is the number of failed keepalive requests, 0 when the session failed
+	Failures int
+	Err      error
+}
+
+func (e *TransportError) Error() string {
+	if e.Failures == 0 {
+		return fmt.Sprintf("transport to router %s failed with error: %+v", e.Router, e.Err)
+	}
+	return fmt.Sprintf("SSH transport to router %s is dead, %d keepalive request(s) failed, last error: %+v", e.Router, e.Failures, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// keepAlive sends keepalive requests over the SSH client until it is stopped or the transport is found dead
+type keepAlive struct {
+	mx     sync.Mutex
+	router string
+	client *ssh.Client
+	policy *KeepAlivePolicy
+	stop   chan struct{}
+	err    *TransportError
+}
+
+func newKeepAlive(router string, client *ssh.Client, policy *KeepAlivePolicy) *keepAlive {
+	ka := &keepAlive{
+		router: router,
+		client: client,
+		policy: policy,
+		stop:   make(chan struct{}),
+	}
+	go ka.run()
+
+	return ka
+}
+
+func (ka *keepAlive) run() {
+	interval := time.Duration(ka.policy.Interval) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		select {
+		case <-ka.stop:
+			return
+		case <-ticker.C:
+		}
+		err := ka.send(interval)
+		if err == nil {
+			failures = 0
+			continue
+		}
+		select {
+		case <-ka.stop:
+			return
+		default:
+		}
+		failures++
+		glog.Warningf("router %s: keepalive request %d/%d failed with error: %+v", ka.router, failures, ka.policy.MaxFailures, err)
+		if failures < ka.policy.MaxFailures {
+			continue
+		}
+		ka.mx.Lock()
+		ka.err = &TransportError{Router: ka.router, Failures: failures, Err: err}
+		ka.mx.Unlock()
+		glog.Error(ka.err.Error())
+		// Closing the connection unblocks the command waiting for the router's output
+		ka.client.Close()
+		return
+	}
+}
+
+// send sends a keepalive request, the router's reply to the request does not matter, only getting it in time does
+func (ka *keepAlive) send(timeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := ka.client.SendRequest(keepAliveRequest, true, nil)
+		errCh <- err
+	}()
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case err := <-errCh:
+		return err
+	case <-t.C:
+		return fmt.Errorf("no reply in %s", timeout)
+	}
+}
+
+// transportError returns the error if the transport was found dead
+func (ka *keepAlive) transportError() error {
+	if ka == nil {
+		return nil
+	}
+	ka.mx.Lock()
+	defer ka.mx.Unlock()
+	if ka.err == nil {
+		return nil
+	}
+	return ka.err
+}
+
+func (ka *keepAlive) close() {
+	if ka == nil {
+		return
+	}
+	ka.mx.Lock()
+	defer ka.mx.Unlock()
+	select {
+	case <-ka.stop:
+	default:
+		close(ka.stop)
+	}
+}
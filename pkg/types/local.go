@@ -1,16 +1,24 @@
 package types
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os/exec"
 	"strings"
 	"time"
 
 	"github.com/golang/glog"
 	"github.com/sbezverk/routercommander/pkg/log"
+	"github.com/sbezverk/routercommander/pkg/platform"
 )
 
 var _ Router = &localRouter{}
 
+// ErrLocalConfig is returned for configuration blocks processed by the local router.
+var ErrLocalConfig = errors.New("configuration blocks are not supported for local routers")
+
 type localRouter struct {
 	name   string
 	logger log.Logger
@@ -24,35 +32,45 @@ func (l *localRouter) GetLogger() log.Logger {
 	return l.logger
 }
 
-func (l *localRouter) ProcessCommand(cmd *Command, collectResult bool) ([]*CmdResult, error) {
+// ProcessCommand executes the command on the local host, a command exiting with an error or not found is
+// rejected and the command's on_error policy is applied. Configuration blocks are not supported.
+func (l *localRouter) ProcessCommand(ctx context.Context, cmd *Command, collectResult bool) ([]*CmdResult, error) {
+	if cmd.Config != nil {
+		return nil, fmt.Errorf("configuration block %q: %w", cmd.Cmd, ErrLocalConfig)
+	}
 	c := cmd.Cmd
 	results := make([]*CmdResult, 0)
 
 	// TODO (sbezverk) Add some sanity check for this timer
 
 	if cmd.WaitBefore != 0 {
-		Delay(cmd.WaitBefore)
+		if err := Delay(ctx, cmd.WaitBefore); err != nil {
+			return nil, err
+		}
 	}
 	commandTimeout := DefaultCommandTimeout
 	if cmd.CmdTimeout != 0 {
 		commandTimeout = cmd.CmdTimeout
 	}
 	var err error
-	rs, err := l.sendCommand(c, cmd.Times, cmd.Interval, cmd.Debug, commandTimeout)
+	rs, err := l.sendCommand(ctx, c, cmd.Times, cmd.Interval, cmd.Debug, commandTimeout)
 	if err != nil {
 		return nil, err
 	}
-	if collectResult {
-		results = append(results, rs...)
+	if err := checkRejected(l, cmd, rs); err != nil {
+		return nil, err
 	}
+	results = append(results, collect(rs, collectResult)...)
 	if cmd.WaitAfter != 0 {
-		Delay(cmd.WaitAfter)
+		if err := Delay(ctx, cmd.WaitAfter); err != nil {
+			return nil, err
+		}
 	}
 
 	return results, nil
 }
 
-func (l *localRouter) sendCommand(cmd string, times, interval int, debug bool, commandTimeout int) ([]*CmdResult, error) {
+func (l *localRouter) sendCommand(ctx context.Context, cmd string, times, interval int, debug bool, commandTimeout int) ([]*CmdResult, error) {
 	if glog.V(5) {
 		if interval == 0 || times == 0 {
 			glog.Infof("Sending command: %q to router: %q", cmd, l.GetName())
@@ -61,49 +79,82 @@ func (l *localRouter) sendCommand(cmd string, times, interval int, debug bool, c
 		}
 	}
 	if interval == 0 || times == 0 {
-		b, err := l.GetData(cmd, debug, commandTimeout)
+		r, err := l.execute(ctx, cmd, debug, commandTimeout)
 		if err != nil {
 			return nil, err
 		}
-		if l.logger != nil {
-			l.logger.Log([]byte("=========> " + cmd + "\n"))
-			l.logger.Log(b)
-			l.logger.Log([]byte("\n\n"))
-		}
-		return []*CmdResult{
-			{
-				Cmd:    cmd,
-				Result: b,
-			},
-		}, err
+		return []*CmdResult{r}, nil
 	}
 	results := make([]*CmdResult, 0)
 	ticker := time.NewTicker(time.Second * time.Duration(interval))
 	defer ticker.Stop()
 	for t := 0; t < times; t++ {
-		b, err := l.GetData(cmd, debug, commandTimeout)
+		r, err := l.execute(ctx, cmd, debug, commandTimeout)
 		if err != nil {
 			return nil, err
 		}
-		if l.logger != nil {
-			l.logger.Log([]byte("=========> " + cmd + "\n"))
-			l.logger.Log(b)
-			l.logger.Log([]byte("\n\n"))
+		results = append(results, r)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
-		results = append(results, &CmdResult{
-			Cmd:    cmd,
-			Result: b,
-		})
-		<-ticker.C
 	}
 
 	return results, nil
 }
 
+// execute executes the command once and logs its output, the command exiting with an error or not found
+// is returned as the rejected result.
+func (l *localRouter) execute(ctx context.Context, cmd string, debug bool, commandTimeout int) (*CmdResult, error) {
+	start := time.Now()
+	b, err := l.GetData(ctx, cmd, debug, commandTimeout)
+	r := &CmdResult{
+		Cmd:    cmd,
+		Start:  start,
+		End:    time.Now(),
+		Result: b,
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var ok bool
+		if r.Error, ok = rejection(err); !ok {
+			return nil, err
+		}
+	}
+	if l.logger != nil {
+		l.logger.Log([]byte(CommandMarker + cmd + "\n"))
+		l.logger.Log(b)
+		l.logger.Log([]byte("\n\n"))
+	}
+
+	return r, nil
+}
+
+// rejection returns the reason of the local command's rejection, false is returned when the error does not
+// come from the command.
+func rejection(err error) (string, bool) {
+	var ee *exec.ExitError
+	var pe *fs.PathError
+	switch {
+	case errors.As(err, &ee):
+		if line, _, _ := strings.Cut(strings.TrimSpace(string(ee.Stderr)), "\n"); line != "" {
+			return line, true
+		}
+		return ee.Error(), true
+	case errors.As(err, &pe), errors.Is(err, exec.ErrNotFound):
+		return err.Error(), true
+	}
+
+	return "", false
+}
+
 func (l *localRouter) Close() {
 }
 
-func (l *localRouter) GetData(cmd string, debug bool, commandTimeout int) ([]byte, error) {
+func (l *localRouter) GetData(ctx context.Context, cmd string, debug bool, commandTimeout int) ([]byte, error) {
 	parts := strings.Split(cmd, " ")
 	var c *exec.Cmd
 	if len(parts) > 1 {
@@ -111,17 +162,25 @@ func (l *localRouter) GetData(cmd string, debug bool, commandTimeout int) ([]byt
 		if parts[0] == "bash" && len(parts) > 2 {
 			// Special case of executing bash internal command
 			glog.Infof("><SB> special case for bash")
-			c = exec.Command(parts[0], parts[1], strings.Join(parts[2:], " "))
+			c = exec.CommandContext(ctx, parts[0], parts[1], strings.Join(parts[2:], " "))
 		} else {
-			c = exec.Command(parts[0], parts[1:]...)
+			c = exec.CommandContext(ctx, parts[0], parts[1:]...)
 		}
 	} else {
-		c = exec.Command(parts[0])
+		c = exec.CommandContext(ctx, parts[0])
 	}
 
 	glog.Infof("><SB> command: %+v", c.String())
 
-	return c.Output()
+	b, err := c.Output()
+	var ee *exec.ExitError
+	var pe *fs.PathError
+	if err != nil && ctx.Err() == nil && !errors.As(err, &ee) && !errors.As(err, &pe) && !errors.Is(err, exec.ErrNotFound) {
+		// The command is started but its output can not be read
+		return nil, &TransportError{Router: l.name, Err: err}
+	}
+
+	return b, err
 }
 
 func (l *localRouter) IsExistingLocation(loc string) bool {
@@ -144,6 +203,10 @@ func (l *localRouter) GetActiveRP() string {
 	return ""
 }
 
+func (l *localRouter) GetPlatform() platform.Platform {
+	return nil
+}
+
 func NewLocalRouter(router string, li log.Logger) Router {
 	return &localRouter{
 		name:   router,
@@ -0,0 +1,107 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Operations supported by test's fields
+const (
+	OpCompareWithPreviousNeq      = "compare_with_previous_neq"
+	OpCompareWithPreviousEq       = "compare_with_previous_eq"
+	OpCompareWithValueNeq         = "compare_with_value_neq"
+	OpCompareWithValueEq          = "compare_with_value_eq"
+	OpContainSubstring            = "contain_substring"
+	OpNotContainSubstring         = "not_contain_substring"
+	OpCompareWithValueGt          = "compare_with_value_gt"
+	OpCompareWithValueLt          = "compare_with_value_lt"
+	OpDeltaWithPreviousGt         = "delta_with_previous_gt"
+	OpDeltaWithPreviousLt         = "delta_with_previous_lt"
+	OpRateWithPreviousGt          = "rate_with_previous_gt"
+	OpPercentChangeWithPreviousGt = "percent_change_with_previous_gt"
+)
+
+// IsOperation returns true if the operation is supported by test's fields.
+func IsOperation(op string) bool {
+	switch op {
+	case OpCompareWithPreviousNeq, OpCompareWithPreviousEq, OpCompareWithValueNeq, OpCompareWithValueEq,
+		OpContainSubstring, OpNotContainSubstring:
+		return true
+	}
+	return IsNumericOperation(op)
+}
+
+// IsNumericOperation returns true if the operation requires the field's value to be parsed as a number.
+func IsNumericOperation(op string) bool {
+	switch op {
+	case OpCompareWithValueGt, OpCompareWithValueLt, OpDeltaWithPreviousGt, OpDeltaWithPreviousLt,
+		OpRateWithPreviousGt, OpPercentChangeWithPreviousGt:
+		return true
+	}
+	return false
+}
+
+// ParseNumber parses a value extracted from a command output, integers (decimal or hex with 0x prefix) are
+// returned as int64, everything else as float64. Thousand separators and trailing percent signs are ignored.
+func ParseNumber(s string) (interface{}, error) {
+	v := strings.TrimSpace(s)
+	v = strings.TrimRight(v, "%;")
+	v = strings.ReplaceAll(v, ",", "")
+	if v == "" {
+		return nil, fmt.Errorf("value %q is not a number", s)
+	}
+	if strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X") {
+		u, err := strconv.ParseUint(v[2:], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a hex number", s)
+		}
+		if u > math.MaxInt64 {
+			return float64(u), nil
+		}
+		return int64(u), nil
+	}
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, fmt.Errorf("value %q is not a number", s)
+	}
+
+	return f, nil
+}
+
+// ToFloat64 converts a number returned by ParseNumber to float64.
+func ToFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// Delta returns the difference between current and previous numbers, integer arithmetic is used when
+// both numbers are integers to avoid losing precision of large counters.
+func Delta(current, previous interface{}) (float64, bool) {
+	ci, cok := current.(int64)
+	pi, pok := previous.(int64)
+	if cok && pok {
+		return float64(ci - pi), true
+	}
+	c, ok := ToFloat64(current)
+	if !ok {
+		return 0, false
+	}
+	p, ok := ToFloat64(previous)
+	if !ok {
+		return 0, false
+	}
+
+	return c - p, true
+}
@@ -0,0 +1,69 @@
+package types
+
+import (
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expect interface{}
+		fail   bool
+	}{
+		{
+			name:   "integer",
+			input:  "1234",
+			expect: int64(1234),
+		},
+		{
+			name:   "negative integer",
+			input:  "-5",
+			expect: int64(-5),
+		},
+		{
+			name:   "thousand separators",
+			input:  "1,234,567",
+			expect: int64(1234567),
+		},
+		{
+			name:   "float",
+			input:  "12.5",
+			expect: 12.5,
+		},
+		{
+			name:   "percent",
+			input:  "85%",
+			expect: int64(85),
+		},
+		{
+			name:   "hex",
+			input:  "0x1F",
+			expect: int64(31),
+		},
+		{
+			name:  "not a number",
+			input: "UP",
+			fail:  true,
+		},
+		{
+			name:  "empty",
+			input: "",
+			fail:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := ParseNumber(tt.input)
+			if err != nil && !tt.fail {
+				t.Fatalf("failed with error: %+v", err)
+			}
+			if err == nil && tt.fail {
+				t.Fatalf("expected to fail but succeeded with value: %v", v)
+			}
+			if v != tt.expect {
+				t.Fatalf("expected %T %v but got %T %v", tt.expect, tt.expect, v, v)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package types
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/sbezverk/routercommander/pkg/log"
+	"github.com/sbezverk/routercommander/pkg/platform"
+)
+
+const (
+	// CommandMarker precedes every command in the routercommander log
+	CommandMarker = "=========> "
+)
+
+// CapturedCommand is a command and its output found in a routercommander log.
+type CapturedCommand struct {
+	Cmd    string
+	Output []byte
+}
+
+// ParseCapturedLog splits a routercommander log into commands and their outputs, commands
+// are returned in the order they appear in the log.
+func ParseCapturedLog(rd io.Reader) ([]*CapturedCommand, error) {
+	captured := make([]*CapturedCommand, 0)
+	var current *CapturedCommand
+	var buf bytes.Buffer
+	flush := func() {
+		if current == nil {
+			return
+		}
+		// Every command output is followed by two new lines added by the logger
+		b := bytes.TrimSuffix(buf.Bytes(), []byte("\n\n"))
+		current.Output = make([]byte, len(b))
+		copy(current.Output, b)
+		captured = append(captured, current)
+		buf.Reset()
+	}
+	br := bufio.NewReader(rd)
+	for {
+		l, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read captured log with error: %+v", err)
+		}
+		if bytes.HasPrefix(l, []byte(CommandMarker)) {
+			flush()
+			current = &CapturedCommand{
+				Cmd: strings.TrimSuffix(string(l[len(CommandMarker):]), "\n"),
+			}
+		} else if current != nil {
+			buf.Write(l)
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	flush()
+
+	return captured, nil
+}
+
+var _ Router = &replayRouter{}
+
+// replayRouter serves outputs of commands captured in a routercommander log instead of a live router,
+// repeated occurrences of the same command are served in the order they were captured.
+type replayRouter struct {
+	mx        sync.Mutex
+	name      string
+	logger    log.Logger
+	outputs   map[string][][]byte
+	served    map[string]int
+	locations *platform.Locations
+}
+
+func (rr *replayRouter) IsExistingLocation(l string) bool {
+	return rr.locations.IsExisting(l)
+}
+
+func (rr *replayRouter) GetAllLCs() []string {
+	return rr.locations.AllLCs()
+}
+
+func (rr *replayRouter) GetAllRPs() []string {
+	return rr.locations.AllRPs()
+}
+
+func (rr *replayRouter) GetActiveRP() string {
+	return rr.locations.Active()
+}
+
+func (rr *replayRouter) GetAllLocations() []string {
+	return rr.locations.All()
+}
+
+func (rr *replayRouter) GetName() string {
+	return rr.name
+}
+
+// GetPlatform returns the default platform, captured logs do not carry the router's platform
+func (rr *replayRouter) GetPlatform() platform.Platform {
+	return platform.Default()
+}
+
+func (rr *replayRouter) GetLogger() log.Logger {
+	return rr.logger
+}
+
+func (rr *replayRouter) Close() {
+}
+
+func (rr *replayRouter) GetData(ctx context.Context, cmd string, debug bool, commandTimeout int) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	rr.mx.Lock()
+	defer rr.mx.Unlock()
+	// Commands with locations and pipe modifiers carry trailing spaces, matching is done on trimmed commands
+	key := strings.TrimSpace(cmd)
+	outputs, ok := rr.outputs[key]
+	if !ok {
+		return nil, fmt.Errorf("command %q is not found in the captured log", cmd)
+	}
+	i := rr.served[key]
+	if i >= len(outputs) {
+		return nil, fmt.Errorf("command %q was captured %d time(s), no more captured outputs left", cmd, len(outputs))
+	}
+	rr.served[key]++
+	b := outputs[i]
+	if debug {
+		glog.Infof("Replaying %q occurrence %d", cmd, i+1)
+	}
+	if rr.logger != nil {
+		rr.logger.Log([]byte(CommandMarker + cmd + "\n"))
+		rr.logger.Log(b)
+		rr.logger.Log([]byte("\n\n"))
+	}
+
+	return b, nil
+}
+
+func (rr *replayRouter) ProcessCommand(ctx context.Context, cmd *Command, collectResult bool) ([]*CmdResult, error) {
+	// Captured outputs do not need waiting between commands
+	e := &executor{r: rr, pace: false}
+
+	return e.processCommand(ctx, cmd, collectResult)
+}
+
+func isSessionSetupCommand(cmd string) bool {
+	for _, p := range platform.All() {
+		for _, c := range sessionSetupCommands(p) {
+			if strings.TrimSpace(cmd) == c {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NewReplayRouter returns a Router serving commands' outputs from a previously captured routercommander log.
+func NewReplayRouter(name string, fn string, l log.Logger) (Router, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open captured log %s with error: %+v", fn, err)
+	}
+	defer f.Close()
+	captured, err := ParseCapturedLog(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(captured) == 0 {
+		return nil, fmt.Errorf("no commands found in captured log %s", fn)
+	}
+
+	return newReplayRouter(name, captured, l), nil
+}
+
+func newReplayRouter(name string, captured []*CapturedCommand, l log.Logger) *replayRouter {
+	rr := &replayRouter{
+		name:    name,
+		logger:  l,
+		outputs: make(map[string][][]byte),
+		served:  make(map[string]int),
+	}
+	for i, c := range captured {
+		key := strings.TrimSpace(c.Cmd)
+		// Platform information is captured by the router's session setup right after disabling the paging,
+		// when available it is used to expand locations and it is not a part of commands to replay.
+		d := rr.GetPlatform()
+		if key == d.LocationsCommand() && i > 0 && isSessionSetupCommand(captured[i-1].Cmd) {
+			l, err := d.ParseLocations(c.Output)
+			if err != nil {
+				glog.Warningf("replay of router %s: failed to populate platform information with error: %+v", name, err)
+			} else {
+				rr.locations = l
+			}
+			continue
+		}
+		rr.outputs[key] = append(rr.outputs[key], c.Output)
+	}
+
+	return rr
+}
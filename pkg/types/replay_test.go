@@ -0,0 +1,104 @@
+package types
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const capturedLog = `=========> terminal w 256
+
+
+=========> terminal l 0
+
+
+=========> show platform
+Node            Type                      State            Config State
+-----------------------------------------------------------------------------
+0/RSP0/CPU0     A9K-RSP880-TR(Active)     IOS XR RUN       PWR,NSHUT,MON
+0/0/CPU0        A9K-8X100GE-TR            IOS XR RUN       PWR,NSHUT,MON
+
+
+=========> show cef drops location 0/0/CPU0 
+Discard drops packets : 10
+
+
+=========> show clock
+10:00:00.000 UTC
+
+
+=========> show cef drops location 0/0/CPU0 
+Discard drops packets : 20
+
+
+=========> show clock
+10:00:10.000 UTC
+
+
+`
+
+func TestParseCapturedLog(t *testing.T) {
+	captured, err := ParseCapturedLog(strings.NewReader(capturedLog))
+	if err != nil {
+		t.Fatalf("failed to parse captured log with error: %+v", err)
+	}
+	if len(captured) != 7 {
+		t.Fatalf("expected 7 captured commands, got %d", len(captured))
+	}
+	if captured[3].Cmd != "show cef drops location 0/0/CPU0 " {
+		t.Fatalf("unexpected command %q", captured[3].Cmd)
+	}
+	if string(captured[3].Output) != "Discard drops packets : 10\n" {
+		t.Fatalf("unexpected output %q", string(captured[3].Output))
+	}
+	if len(captured[0].Output) != 0 {
+		t.Fatalf("expected empty output of terminal command, got %q", string(captured[0].Output))
+	}
+}
+
+func TestReplayRouter(t *testing.T) {
+	captured, err := ParseCapturedLog(strings.NewReader(capturedLog))
+	if err != nil {
+		t.Fatalf("failed to parse captured log with error: %+v", err)
+	}
+	r := newReplayRouter("r1", captured, nil)
+	if r.GetActiveRP() != "0/RSP0/CPU0" {
+		t.Fatalf("expected active RP 0/RSP0/CPU0, got %q", r.GetActiveRP())
+	}
+	drops := &Command{Cmd: "show cef drops", Location: []string{"all-lc"}}
+	clock := &Command{Cmd: "show clock"}
+	for it, expect := range []string{"10", "20"} {
+		rs, err := r.ProcessCommand(context.Background(), drops, true)
+		if err != nil {
+			t.Fatalf("iteration %d: failed to process command with error: %+v", it, err)
+		}
+		if len(rs) != 1 || rs[0].Location != "0/0/CPU0" || !strings.Contains(string(rs[0].Result), expect) {
+			t.Fatalf("iteration %d: unexpected results %+v", it, rs)
+		}
+		if _, err := r.ProcessCommand(context.Background(), clock, true); err != nil {
+			t.Fatalf("iteration %d: failed to process command with error: %+v", it, err)
+		}
+	}
+	if _, err := r.ProcessCommand(context.Background(), clock, true); err == nil {
+		t.Fatalf("expected error when captured outputs are exhausted")
+	}
+	if _, err := r.ProcessCommand(context.Background(), &Command{Cmd: "show version"}, true); err == nil {
+		t.Fatalf("expected error for a command which was not captured")
+	}
+}
+
+func TestReplayRouterTimes(t *testing.T) {
+	captured, err := ParseCapturedLog(strings.NewReader(capturedLog))
+	if err != nil {
+		t.Fatalf("failed to parse captured log with error: %+v", err)
+	}
+	r := newReplayRouter("r1", captured, nil)
+	// Interval is not honored during replay, the test would take 10 seconds otherwise
+	rs, err := r.ProcessCommand(context.Background(), &Command{Cmd: "show clock", Times: 2, Interval: 5}, true)
+	if err != nil {
+		t.Fatalf("failed to process command with error: %+v", err)
+	}
+	if len(rs) != 2 || !strings.Contains(string(rs[1].Result), "10:00:10") {
+		t.Fatalf("unexpected results %+v", rs)
+	}
+}
@@ -2,8 +2,9 @@ package types
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"html/template"
 	"io"
 	"strconv"
 	"sync/atomic"
@@ -14,7 +15,7 @@ import (
 
 	"github.com/golang/glog"
 	"github.com/sbezverk/routercommander/pkg/log"
-	"github.com/sbezverk/routercommander/pkg/patterns"
+	"github.com/sbezverk/routercommander/pkg/platform"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -31,9 +32,9 @@ func normalizePromptBuffer(buffer []byte) []byte {
 	return ansiEscape.ReplaceAll(clean, nil)
 }
 
-func findPromptIndex(buffer []byte) []int {
+func findPromptIndex(buffer []byte, prompts []*regexp.Regexp) []int {
 	clean := normalizePromptBuffer(buffer)
-	for _, p := range []*regexp.Regexp{patterns.Prompt, patterns.SysadminPrompt, patterns.RunShellPrompt, patterns.NXOSPrompt} {
+	for _, p := range prompts {
 		if idx := p.FindIndex(clean); idx != nil {
 			return idx
 		}
@@ -48,345 +49,161 @@ type Router interface {
 	GetActiveRP() string
 	GetAllLocations() []string
 	GetName() string
-	GetData(string, bool, int) ([]byte, error)
-	ProcessCommand(*Command, bool) ([]*CmdResult, error)
+	GetData(context.Context, string, bool, int) ([]byte, error)
+	ProcessCommand(context.Context, *Command, bool) ([]*CmdResult, error)
 	Close()
 	GetLogger() log.Logger
+	// GetPlatform returns the driver of the router's platform, nil when the router is not a network device
+	GetPlatform() platform.Platform
 }
 
 func (r *router) IsExistingLocation(l string) bool {
-	if r.platform == nil {
-		return false
-	}
-	if r.platform.rps == nil && r.platform.lcs == nil {
-		return false
-	}
-	if r.platform.rps != nil {
-		if _, found := r.platform.rps.rps[l]; found {
-			return true
-		}
-	}
-	if r.platform.lcs != nil {
-		if _, found := r.platform.lcs.lcs[l]; found {
-			return true
-		}
-	}
-	return false
+	return r.locations.IsExisting(l)
 }
 
-func isXRPlatform(platformType string) bool {
-	normalized := strings.ToLower(strings.TrimSpace(platformType))
-	return normalized == "" || normalized == "iosxr" || normalized == "xr"
-}
-
-func sessionSetupCommands(platformType string) []string {
-	if isXRPlatform(platformType) {
-		return []string{"terminal w 256", "terminal l 0"}
-	}
-	return []string{"terminal width 256", "terminal length 0"}
+// sessionSetupCommands returns commands sent to the platform's router before any other command
+func sessionSetupCommands(p platform.Platform) []string {
+	return append(append([]string{}, p.SessionSetup()...), p.DisablePaging())
 }
 
 func (r *router) GetAllLCs() []string {
-	if r.platform == nil {
-		return r.GetAllRPs()
-	}
-	if r.platform.lcs == nil {
-		return r.GetAllRPs()
-	}
-	if len(r.platform.lcs.lcs) == 0 {
-		return r.GetAllRPs()
-	}
-	lcs := make([]string, len(r.platform.lcs.lcs))
-	i := 0
-	for lc := range r.platform.lcs.lcs {
-		lcs[i] = lc
-		i++
-	}
-
-	return lcs
+	return r.locations.AllLCs()
 }
 
 func (r *router) GetAllRPs() []string {
-	if r.platform == nil {
-		return nil
-	}
-	if r.platform.rps == nil {
-		return nil
-	}
-	if len(r.platform.rps.rps) == 0 {
-		return nil
-	}
-	rps := make([]string, len(r.platform.rps.rps))
-	i := 0
-	for rp := range r.platform.rps.rps {
-		rps[i] = rp
-		i++
-	}
-
-	return rps
+	return r.locations.AllRPs()
 }
 
 func (r *router) GetAllLocations() []string {
-	locations := make([]string, 0)
-	rps := r.GetAllRPs()
-	if rps != nil {
-		locations = append(locations, rps...)
-	}
-	lcs := r.GetAllLCs()
-	if lcs != nil {
-		locations = append(locations, lcs...)
-	}
-
-	return locations
+	return r.locations.All()
 }
 
 func (r *router) GetActiveRP() string {
-	if r.platform.rps == nil {
-		return ""
-	}
-	if len(r.platform.rps.rps) == 0 {
-		return ""
-	}
-	for loc, rp := range r.platform.rps.rps {
-		if rp.isActive {
-			return loc
-		}
-	}
-	return ""
+	return r.locations.Active()
 }
 
 func (r *router) GetName() string {
 	return r.name
 }
 
+func (r *router) GetPlatform() platform.Platform {
+	return r.driver
+}
+
 func (r *router) GetLogger() log.Logger {
 	return r.logger
 }
 
 type CmdResult struct {
-	Cmd    string
-	Result []byte
-}
-
-func Delay(d int) {
-	t := time.NewTimer(time.Duration(d) * time.Second)
-	defer t.Stop()
-	<-t.C
+	Cmd      string
+	Location string
+	Start    time.Time
+	End      time.Time
+	Result   []byte
+	// Error is the line of the output reporting the command was rejected by the router
+	Error string
 }
 
-func (r *router) ProcessCommand(cmd *Command, collectResult bool) ([]*CmdResult, error) {
-	c := cmd.Cmd
-	results := make([]*CmdResult, 0)
-
-	// TODO (sbezverk) Add some sanity check for this timer
-
-	if cmd.WaitBefore != 0 {
-		Delay(cmd.WaitBefore)
-	}
-	commandTimeout := DefaultCommandTimeout
-	if cmd.CmdTimeout != 0 && cmd.CmdTimeout > DefaultCommandTimeout {
-		commandTimeout = cmd.CmdTimeout
-	}
-	pipeModifier := ""
-	if cmd.PipeModifier != "" {
-		pipeModifier += " | " + cmd.PipeModifier
-	}
-	if len(cmd.Location) == 0 {
-		var err error
-		rs, err := r.sendCommand(c+pipeModifier, cmd.Times, cmd.Interval, cmd.Debug, commandTimeout)
-		if err != nil {
-			return nil, err
-		}
-		if collectResult {
-			results = append(results, rs...)
-		}
-	} else {
-		locs, err := prepareLocations(r, cmd)
-		if err != nil {
-			return nil, err
-		}
-		rs, err := r.sendCommandWithLocations(cmd, locs, pipeModifier, commandTimeout)
-		if err != nil {
-			return nil, err
-		}
-		if collectResult {
-			results = append(results, rs...)
-		}
-	}
-	if cmd.WaitAfter != 0 {
-		Delay(cmd.WaitAfter)
-	}
+func (r *router) ProcessCommand(ctx context.Context, cmd *Command, collectResult bool) ([]*CmdResult, error) {
+	e := &executor{r: r, pace: true}
 
-	return results, nil
+	return e.processCommand(ctx, cmd, collectResult)
 }
 
-func transforLocation(tmpl *template.Template, loc string) (string, error) {
-	l := strings.Split(loc, "/")
-	if len(l) < 3 {
-		return "", fmt.Errorf("location %s is in unknown format", loc)
-	}
-	slot, err := strconv.ParseInt(l[1], 10, 0)
-	if err != nil {
-		return "", err
-	}
-	buf := new(bytes.Buffer)
-	if err := tmpl.Execute(buf, struct {
-		Slot int
-	}{
-		Slot: int(slot),
-	}); err != nil {
-		return "", err
-	}
-
-	return buf.String(), nil
+// DialFunc establishes a SSH client connection to the address with the client configuration.
+type DialFunc func(addr string, config *ssh.ClientConfig) (*ssh.Client, error)
+
+// RouterOptions carries optional parameters of a router reachable over SSH.
+type RouterOptions struct {
+	// Dial is used to establish the SSH connection, when nil the router is dialed directly.
+	Dial DialFunc
+	// Reconnect defines how the lost connection is re-established, when nil the connection is not re-established.
+	Reconnect *ReconnectPolicy
+	// KeepAlive defines how the idle connection is checked, when nil keepalive requests are not sent.
+	KeepAlive *KeepAlivePolicy
+	// Record is the file the raw session with the router is saved to as a fixture when the router is closed,
+	// when empty the session is not recorded.
+	Record string
 }
 
-func prepareLocations(r *router, cmd *Command) ([]string, error) {
-	locs := make([]string, 0)
-	for _, l := range cmd.Location {
-		switch l {
-		case "all":
-			locs = append(locs, r.GetAllLocations()...)
-		case "all-rp":
-			locs = append(locs, r.GetAllRPs()...)
-		case "all-lc":
-			locs = append(locs, r.GetAllLCs()...)
-		default:
-			locs = append(locs, l)
-		}
-	}
-	if cmd.LocationFmtTmpl == "" {
-		// No location customization format
-		return locs, nil
-	}
-	tmpl, err := template.New("Slot").Parse(cmd.LocationFmtTmpl)
-	if err != nil {
-		return nil, err
-	}
-	for i := 0; i < len(locs); i++ {
-		locs[i], err = transforLocation(tmpl, locs[i])
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return locs, nil
+// ReconnectPolicy defines how the lost connection to the router is re-established.
+type ReconnectPolicy struct {
+	// MaxAttempts is the maximum number of reconnect attempts, 0 disables reconnection
+	MaxAttempts int
+	// Backoff is the number of seconds to wait before the first attempt, doubled after every failed attempt
+	Backoff int
+	// MaxBackoff limits the number of seconds to wait between attempts, 0 means no limit
+	MaxBackoff int
 }
 
-func (r *router) sendCommandWithLocations(cmd *Command, locations []string, pipeModifier string, commandTimeout int) ([]*CmdResult, error) {
-	results := make([]*CmdResult, 0)
-	var tmpl *template.Template
-	var err error
-	if cmd.LocationCustomized {
-		tmpl, err = template.New("Command").Parse(cmd.Cmd)
-		if err != nil {
-			return nil, err
-		}
-	}
-	for _, l := range locations {
-		switch l {
-		case "all":
-			expanded := r.GetAllLocations()
-			rs, err := r.sendCommandWithLocations(cmd, expanded, pipeModifier, commandTimeout)
-			if err != nil {
-				return nil, err
-			}
-			results = append(results, rs...)
-		case "all-rp":
-			expanded := r.GetAllRPs()
-			rs, err := r.sendCommandWithLocations(cmd, expanded, pipeModifier, commandTimeout)
-			if err != nil {
-				return nil, err
-			}
-			results = append(results, rs...)
-		case "all-lc":
-			expanded := r.GetAllLCs()
-			rs, err := r.sendCommandWithLocations(cmd, expanded, pipeModifier, commandTimeout)
-			if err != nil {
-				return nil, err
-			}
-			results = append(results, rs...)
-		default:
-			var fc string
-			if !cmd.LocationCustomized {
-				fc = cmd.Cmd + " " + "location " + l + " " + pipeModifier
+// ReconnectedError is returned for the command which was interrupted by the loss of the connection, when the
+// connection was re-established. The command's output is lost, following commands are sent over the new connection.
+type ReconnectedError struct {
+	Cmd      string
+	Err      error
+	Lost     time.Time
+	Attempts int
+	Gap      time.Duration
+}
 
-			} else {
-				buf := new(bytes.Buffer)
-				if err := tmpl.Execute(buf, struct {
-					Location string
-				}{
-					Location: l,
-				}); err != nil {
-					return nil, err
-				}
-				fc = buf.String() + " " + pipeModifier
-			}
-			rs, err := r.sendCommand(fc, cmd.Times, cmd.Interval, cmd.Debug, commandTimeout)
-			if err != nil {
-				return nil, err
-			}
-			results = append(results, rs...)
-		}
-	}
+func (e *ReconnectedError) Error() string {
+	return fmt.Sprintf("connection lost at %s while sending %q with error: %+v, reconnected after %s and %d attempt(s)",
+		e.Lost.Format(time.RFC3339), e.Cmd, e.Err, e.Gap.Round(time.Second), e.Attempts)
+}
 
-	return results, nil
+func (e *ReconnectedError) Unwrap() error {
+	return e.Err
 }
 
-func (r *router) sendCommand(cmd string, times, interval int, debug bool, commandTimeout int) ([]*CmdResult, error) {
-	if glog.V(5) {
-		if interval == 0 || times == 0 {
-			glog.Infof("Sending command: %q to router: %q, command timeout: %d seconds", cmd, r.GetName(), commandTimeout)
-		} else {
-			glog.Infof("Sending command: %q, %d times with interval of %d seconds to router: %q, command timeout: %d seconds", cmd, times, interval, r.GetName(), commandTimeout)
-		}
-	}
-	if interval == 0 || times == 0 {
-		b, err := r.GetData(cmd, debug, commandTimeout)
-		if err != nil {
-			return nil, err
-		}
-		return []*CmdResult{
-			{
-				Cmd:    cmd,
-				Result: b,
-			},
-		}, err
-	}
-	results := make([]*CmdResult, 0)
-	ticker := time.NewTicker(time.Second * time.Duration(interval))
-	defer ticker.Stop()
-	for t := 0; t < times; t++ {
-		b, err := r.GetData(cmd, debug, commandTimeout)
-		if err != nil {
-			return nil, err
-		}
-		results = append(results, &CmdResult{
-			Cmd:    cmd,
-			Result: b,
-		})
-		<-ticker.C
-	}
+// IsConnectionLost returns true if the error is caused by the router's connection going away
+func IsConnectionLost(err error) bool {
+	var te *TransportError
+	return errors.As(err, &te)
+}
 
-	return results, nil
+func directDial(addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	return ssh.Dial("tcp", addr, config)
 }
 
 var _ Router = &router{}
 
 type router struct {
-	name         string
-	port         int
-	platformType string
-	sshConfig    *ssh.ClientConfig
-	stdin        io.WriteCloser
-	stdout       io.Reader
-	session      *ssh.Session
-	sshClient    *ssh.Client
-	logger       log.Logger
-	platform     *platform
+	name            string
+	port            int
+	driver          platform.Platform
+	sshConfig       *ssh.ClientConfig
+	dial            DialFunc
+	reconnectPolicy *ReconnectPolicy
+	keepAlivePolicy *KeepAlivePolicy
+	keepAlive       *keepAlive
+	// broken is the transport error of the session which can not be reused, set when the session fails and it
+	// is not reconnected
+	broken     error
+	stdin      io.WriteCloser
+	stdout     io.Reader
+	session    *ssh.Session
+	sshClient  *ssh.Client
+	logger     log.Logger
+	locations  *platform.Locations
+	recordFile string
+	fixture    *Fixture
+	recording  *recordingReader
 }
 
 func (r *router) Close() {
+	r.disconnect()
+	if r.fixture == nil {
+		return
+	}
+	if err := r.fixture.Save(r.recordFile); err != nil {
+		glog.Errorf("router %s: failed to save recorded session with error: %+v", r.name, err)
+	} else {
+		glog.Infof("router %s: recorded session is saved to %s", r.name, r.recordFile)
+	}
+}
+
+func (r *router) disconnect() {
+	r.keepAlive.close()
 	if r.session != nil {
 		r.session.Close()
 	}
@@ -395,9 +212,30 @@ func (r *router) Close() {
 	}
 }
 
-func (r *router) GetData(cmd string, debug bool, commandTimeout int) ([]byte, error) {
-	buffer, err := sendCommand(r.stdin, r.stdout, cmd, debug, r.logger, commandTimeout)
+func (r *router) GetData(ctx context.Context, cmd string, debug bool, commandTimeout int) ([]byte, error) {
+	// Not sending commands over the transport found dead while the router was idle, or over the session broken
+	// by an aborted command
+	err := r.keepAlive.transportError()
+	if err == nil {
+		err = r.broken
+	}
+	var buffer []byte
+	if err == nil {
+		buffer, err = r.send(ctx, cmd, debug, commandTimeout)
+		if terr := r.keepAlive.transportError(); err != nil && terr != nil {
+			// The command failed because the dead transport was closed
+			err = terr
+		}
+	}
 	if err != nil {
+		if !IsConnectionLost(err) {
+			return nil, err
+		}
+		if r.reconnectPolicy != nil && ctx.Err() == nil {
+			return nil, r.reconnect(ctx, cmd, err)
+		}
+		// The session is replaced by the next reconnect, until then no command is sent over it
+		r.broken = err
 		return nil, err
 	}
 
@@ -405,19 +243,47 @@ func (r *router) GetData(cmd string, debug bool, commandTimeout int) ([]byte, er
 }
 
 func NewRouter(rn string, port int, platformType string, sshConfig *ssh.ClientConfig, l log.Logger) (Router, error) {
+	return NewRouterWithOptions(rn, port, platformType, sshConfig, l, nil)
+}
+
+func NewRouterWithOptions(rn string, port int, platformType string, sshConfig *ssh.ClientConfig, l log.Logger, opts *RouterOptions) (Router, error) {
+	driver := platform.Get(platformType)
 	r := &router{
-		name:         rn,
-		port:         port,
-		platformType: platformType,
-		sshConfig:    sshConfig,
-		logger:       l,
-		platform:     &platform{},
+		name:      rn,
+		port:      port,
+		driver:    driver,
+		sshConfig: sshConfig,
+		dial:      directDial,
+		logger:    l,
+	}
+	if opts != nil && opts.Dial != nil {
+		r.dial = opts.Dial
+	}
+	if opts != nil && opts.Reconnect != nil && opts.Reconnect.MaxAttempts > 0 {
+		r.reconnectPolicy = opts.Reconnect
+	}
+	if opts != nil && opts.KeepAlive != nil && opts.KeepAlive.Interval > 0 && opts.KeepAlive.MaxFailures > 0 {
+		r.keepAlivePolicy = opts.KeepAlive
 	}
+	if opts != nil && opts.Record != "" {
+		r.recordFile = opts.Record
+		r.fixture = &Fixture{Router: rn, Platform: driver.Name()}
+	}
+	if err := r.connect(); err != nil {
+		return nil, err
+	}
+	r.startKeepAlive()
+
+	return r, nil
+}
+
+// connect dials the router, sets up the session and discovers the router's locations
+func (r *router) connect() error {
 	// Dial and if successful, create ssh session
 	var err error
-	r.sshClient, err = ssh.Dial("tcp", r.name+":"+strconv.Itoa(r.port), r.sshConfig)
+	r.sshClient, err = r.dial(r.name+":"+strconv.Itoa(r.port), r.sshConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to dial router: %s with error: %+v", r.name, err)
+		return fmt.Errorf("failed to dial router: %s with error: %+v", r.name, err)
 	}
 	defer func() {
 		if err != nil {
@@ -426,62 +292,141 @@ func NewRouter(rn string, port int, platformType string, sshConfig *ssh.ClientCo
 	}()
 	r.session, err = r.sshClient.NewSession()
 	if err != nil {
-		return nil, fmt.Errorf("failed to establish a session with error: %+v", err)
+		return fmt.Errorf("failed to establish a session with error: %+v", err)
 	}
-	glog.Infof("Successfully dialed router: %s", rn)
+	glog.Infof("Successfully dialed router: %s", r.name)
 	if err = r.session.RequestPty("vt100", 256, 40, ssh.TerminalModes{
 		ssh.ECHO:          0,
 		ssh.TTY_OP_ISPEED: 14400,
 		ssh.TTY_OP_OSPEED: 14400,
 	}); err != nil {
-		return nil, fmt.Errorf("failed to pty with error: %+v", err)
+		return fmt.Errorf("failed to pty with error: %+v", err)
 	}
 
 	// StdinPipe for commands
 	r.stdin, err = r.session.StdinPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to establish stdin pipe with error: %+v", err)
+		return fmt.Errorf("failed to establish stdin pipe with error: %+v", err)
 	}
 
 	r.stdout, err = r.session.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to establish stdout pipe with error: %+v", err)
+		return fmt.Errorf("failed to establish stdout pipe with error: %+v", err)
+	}
+	if r.fixture != nil {
+		r.recording = &recordingReader{rd: r.stdout}
+		r.stdout = r.recording
 	}
 
 	// Start remote shell
 	if err = r.session.Shell(); err != nil {
-		return nil, fmt.Errorf("failed to establish a session shell with error: %+v", err)
+		return fmt.Errorf("failed to establish a session shell with error: %+v", err)
 	}
 	var banner []byte
-	banner, err = drainUntilPrompt(r.stdout, []*regexp.Regexp{patterns.Prompt, patterns.SysadminPrompt, patterns.RunShellPrompt, patterns.NXOSPrompt}, 30*time.Second)
+	banner, err = drainUntilPrompt(r.stdout, r.driver.Prompts(), 30*time.Second)
 	if err != nil {
-		return nil, fmt.Errorf("failed to synchronize initial prompt: %w; banner=%s", err, string(banner))
+		return fmt.Errorf("failed to synchronize initial prompt: %w; banner=%s", err, string(banner))
+	}
+	if r.fixture != nil {
+		// Only the banner of the first connection is kept
+		if b := r.recording.take(); r.fixture.Banner == "" {
+			r.fixture.Banner = b
+		}
 	}
 	// Prepare session with correct parameters
-	for _, cmd := range sessionSetupCommands(platformType) {
-		if _, err = r.GetData(cmd, false, DefaultCommandTimeout); err != nil {
-			return nil, err
+	for _, cmd := range sessionSetupCommands(r.driver) {
+		if _, err = r.send(context.Background(), cmd, false, DefaultCommandTimeout); err != nil {
+			return err
 		}
 	}
-	if !isXRPlatform(platformType) {
-		return r, nil
+	if r.driver.LocationsCommand() == "" {
+		return nil
 	}
-	// Getting platform information
+	// Getting locations information
 	var b []byte
-	b, err = r.GetData("show platform", false, DefaultCommandTimeout)
+	b, err = r.send(context.Background(), r.driver.LocationsCommand(), false, DefaultCommandTimeout)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	var p *platform
-	p, err = populatePlatformInfo(b)
+	var l *platform.Locations
+	l, err = r.driver.ParseLocations(b)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	r.platform = p
-	return r, nil
+	r.locations = l
+
+	return nil
+}
+
+func (r *router) startKeepAlive() {
+	if r.keepAlivePolicy == nil {
+		return
+	}
+	r.keepAlive = newKeepAlive(r.name, r.sshClient, r.keepAlivePolicy)
+}
+
+// reconnect re-establishes the lost connection according to the reconnect policy, the delay between attempts
+// starts at the policy's backoff and doubles after every failed attempt up to the policy's max backoff.
+func (r *router) reconnect(ctx context.Context, cmd string, cause error) error {
+	p := r.reconnectPolicy
+	lost := time.Now()
+	glog.Warningf("router %s: connection lost while sending %q with error: %+v, reconnecting...", r.name, cmd, cause)
+	r.disconnect()
+	backoff := p.Backoff
+	var err error
+	for attempt := 1; attempt <= p.MaxAttempts; attempt++ {
+		if derr := Delay(ctx, backoff); derr != nil {
+			return derr
+		}
+		if err = r.connect(); err == nil {
+			r.broken = nil
+			r.startKeepAlive()
+			re := &ReconnectedError{
+				Cmd:      cmd,
+				Err:      cause,
+				Lost:     lost,
+				Attempts: attempt,
+				Gap:      time.Since(lost),
+			}
+			glog.Warningf("router %s: %s", r.name, re.Error())
+			if r.logger != nil {
+				r.logger.Log([]byte(fmt.Sprintf("!!! %s\n\n", re.Error())))
+			}
+			return re
+		}
+		glog.Errorf("router %s: reconnect attempt %d/%d failed with error: %+v", r.name, attempt, p.MaxAttempts, err)
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+
+	return fmt.Errorf("failed to reconnect to router %s after %d attempt(s), connection lost with error: %+v, last attempt failed with error: %+v", r.name, p.MaxAttempts, cause, err)
+}
+
+// send sends the command to the router, when the session is recorded, the raw output of the command is added
+// to the fixture
+func (r *router) send(ctx context.Context, cmd string, debug bool, commandTimeout int) ([]byte, error) {
+	b, err := sendCommand(ctx, r.stdin, r.stdout, r.driver.Prompts(), cmd, debug, r.logger, commandTimeout)
+	var te *TransportError
+	if errors.As(err, &te) {
+		te.Router = r.name
+	}
+	if r.fixture != nil {
+		r.fixture.Exchanges = append(r.fixture.Exchanges, &FixtureExchange{Command: cmd, Raw: r.recording.take()})
+	}
+
+	return b, err
 }
 
-func sendCommand(stdin io.WriteCloser, stdout io.Reader, cmd string, debug bool, l log.Logger, commandTimeout int) ([]byte, error) {
+// abortGracePeriod is the time given to the router to return to the prompt after the command is aborted, when
+// the prompt is not back the session is broken
+var abortGracePeriod = 5 * time.Second
+
+func sendCommand(ctx context.Context, stdin io.WriteCloser, stdout io.Reader, prompts []*regexp.Regexp, cmd string, debug bool, l log.Logger, commandTimeout int) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("command %q is not sent with error: %w", cmd, err)
+	}
 	sanitizedcmd := strings.Replace(cmd, "|", "\\|", -1)
 	// Some h/w specific commands send `\` escape, adding another escape to escape the original
 	s1 := string(bytes.Replace([]byte(sanitizedcmd), []byte(`\`), []byte(`\\`), -1))
@@ -519,7 +464,7 @@ func sendCommand(stdin io.WriteCloser, stdout io.Reader, cmd string, debug bool,
 				if !cmdFound {
 					continue
 				}
-				if findPromptIndex(fullInput.Bytes()) != nil {
+				if findPromptIndex(fullInput.Bytes(), prompts) != nil {
 					endFound.Store(true)
 					out := make([]byte, fullInput.Len())
 					copy(out, fullInput.Bytes())
@@ -536,20 +481,20 @@ func sendCommand(stdin io.WriteCloser, stdout io.Reader, cmd string, debug bool,
 
 	// If logging is enabled, sending the command to the logger process
 	if l != nil {
-		l.Log([]byte("=========> " + cmd + "\n"))
+		l.Log([]byte(CommandMarker + cmd + "\n"))
 	}
 	if debug {
 		glog.Infof("Sending \"%s\"", cmd)
 	}
 	if _, err := fmt.Fprintf(stdin, "%s\n", cmd); err != nil {
-		return nil, fmt.Errorf("failed to send command %s  with error: %+v", cmd, err)
+		return nil, &TransportError{Err: fmt.Errorf("failed to send command %s with error: %w", cmd, err)}
 	}
 	select {
 	case err := <-errCh:
-		return nil, err
+		return nil, &TransportError{Err: fmt.Errorf("failed to read output of command %s with error: %w", cmd, err)}
 	case buff := <-doneCh:
-		// Attempt to catch extra 2 bytes
-		buffer := bytes.Replace(buff, []byte{0x0d}, []byte{}, -1)
+		// Prompt indexes are found in the normalized buffer, so the output is cut from the normalized buffer too
+		buffer := normalizePromptBuffer(buff)
 		// Removing the actual command from the buffer
 
 		start := startPattern.FindIndex(buffer)
@@ -560,7 +505,7 @@ func sendCommand(stdin io.WriteCloser, stdout io.Reader, cmd string, debug bool,
 		if eol != nil {
 			start[1] = start[0] + eol[0]
 		}
-		end := findPromptIndex(buffer)
+		end := findPromptIndex(buffer, prompts)
 		if end == nil {
 			return nil, fmt.Errorf("failed to find end of command %q in output, buffer: %s", cmd, string(buffer))
 		}
@@ -577,13 +522,35 @@ func sendCommand(stdin io.WriteCloser, stdout io.Reader, cmd string, debug bool,
 		return b, nil
 	case <-timeout.C:
 		return nil, fmt.Errorf("time out waiting for the result of %q, start found %t, end found %t", cmd, startFound.Load(), endFound.Load())
+	case <-ctx.Done():
+		// Aborting the in-flight command with Ctrl-C, the rest of its output is drained until the prompt
+		// comes back, so the session can still be used to clean up.
+		glog.Warningf("aborting command %q with error: %+v", cmd, ctx.Err())
+		var err error
+		if _, err = stdin.Write([]byte{0x03}); err == nil {
+			grace := time.NewTimer(abortGracePeriod)
+			defer grace.Stop()
+			select {
+			case <-doneCh:
+			case err = <-errCh:
+			case <-grace.C:
+				err = fmt.Errorf("prompt is not back after %s", abortGracePeriod)
+			}
+		}
+		if l != nil {
+			l.Log([]byte(fmt.Sprintf("command aborted: %+v\n\n", ctx.Err())))
+		}
+		if err != nil {
+			// The session is out of sync with the router, it can not be reused
+			return nil, &TransportError{Err: fmt.Errorf("command %q aborted with error: %w, %v", cmd, ctx.Err(), err)}
+		}
+		return nil, fmt.Errorf("command %q aborted with error: %w", cmd, ctx.Err())
 	}
 }
 
 func drainUntilPrompt(stdout io.Reader, prompt []*regexp.Regexp, timeout time.Duration) ([]byte, error) {
 	buf := make([]byte, 0, 64*1024)
 	tmp := make([]byte, 4096)
-	_ = prompt
 
 	deadline := time.NewTimer(timeout)
 	defer deadline.Stop()
@@ -598,7 +565,7 @@ func drainUntilPrompt(stdout io.Reader, prompt []*regexp.Regexp, timeout time.Du
 			n, err := stdout.Read(tmp)
 			if n > 0 {
 				buf = append(buf, tmp[:n]...)
-				if findPromptIndex(buf) != nil {
+				if findPromptIndex(buf, prompt) != nil {
 					doneCh <- struct {
 						data []byte
 						err  error
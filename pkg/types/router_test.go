@@ -1,11 +1,22 @@
 package types
 
 import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/sbezverk/routercommander/pkg/platform"
+	"golang.org/x/crypto/ssh"
 )
 
 // simulateRouter writes a fake router response to stdoutW after reading the
@@ -30,7 +41,7 @@ func TestSendCommand_NormalOutput(t *testing.T) {
 	want := "Cisco IOS XR\nsome output line\n"
 	simulateRouter(t, stdinR, stdoutW, "some output line")
 
-	result, err := sendCommand(stdinW, stdoutR, "show version", false, nil, 5)
+	result, err := sendCommand(context.Background(), stdinW, stdoutR, platform.Default().Prompts(), "show version", false, nil, 5)
 	if err != nil {
 		t.Fatalf("sendCommand returned unexpected error: %v", err)
 	}
@@ -50,7 +61,7 @@ func TestSendCommand_LargeOutput(t *testing.T) {
 	large := strings.Repeat("10.0.0.0/24 via 192.168.1.1\n", 1_500) // ~45 KB
 	simulateRouter(t, stdinR, stdoutW, large)
 
-	result, err := sendCommand(stdinW, stdoutR, "show version", false, nil, 10)
+	result, err := sendCommand(context.Background(), stdinW, stdoutR, platform.Default().Prompts(), "show version", false, nil, 10)
 	if err != nil {
 		t.Fatalf("sendCommand returned unexpected error on large output: %v", err)
 	}
@@ -76,7 +87,7 @@ func TestSendCommand_Timeout(t *testing.T) {
 		stdoutW.Close()
 	}()
 
-	_, err := sendCommand(stdinW, stdoutR, "show version", false, nil, 1)
+	_, err := sendCommand(context.Background(), stdinW, stdoutR, platform.Default().Prompts(), "show version", false, nil, 1)
 	if err == nil {
 		t.Fatal("expected a timeout error, got nil")
 	}
@@ -99,9 +110,10 @@ func TestSendCommand_ReadError(t *testing.T) {
 	// Close the write-end immediately — Read on stdoutR will return io.EOF.
 	stdoutW.Close()
 
-	_, err := sendCommand(stdinW, stdoutR, "show version", false, nil, 5)
-	if err == nil {
-		t.Fatal("expected an error from closed stdout pipe, got nil")
+	_, err := sendCommand(context.Background(), stdinW, stdoutR, platform.Default().Prompts(), "show version", false, nil, 5)
+	var te *TransportError
+	if !errors.As(err, &te) || !errors.Is(err, io.EOF) {
+		t.Fatalf("expected transport error from closed stdout pipe, got: %+v", err)
 	}
 }
 
@@ -116,7 +128,8 @@ func TestSendCommand_NXOSPrompt(t *testing.T) {
 		fmt.Fprintf(stdoutW, "show version\nNX-OS output here\nnxos-switch#\n")
 	}()
 
-	result, err := sendCommand(stdinW, stdoutR, "show version", false, nil, 5)
+	// Routers without the platform use the default driver, it matches NX-OS prompts too
+	result, err := sendCommand(context.Background(), stdinW, stdoutR, platform.Default().Prompts(), "show version", false, nil, 5)
 	if err != nil {
 		t.Fatalf("sendCommand with NX-OS prompt returned error: %v", err)
 	}
@@ -124,3 +137,382 @@ func TestSendCommand_NXOSPrompt(t *testing.T) {
 		t.Fatalf("expected NX-OS output in result, got: %q", string(result))
 	}
 }
+
+func TestSendCommand_Cancelled(t *testing.T) {
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	aborted := make(chan struct{})
+	go func() {
+		defer stdoutW.Close()
+		buf := make([]byte, 4096)
+		stdinR.Read(buf)                                            //nolint:errcheck
+		fmt.Fprintf(stdoutW, "show logging\nlong running output\n") //nolint:errcheck
+		cancel()
+		// The command is aborted by Ctrl-C, the router returns to the prompt
+		n, _ := stdinR.Read(buf)
+		if n == 1 && buf[0] == 0x03 {
+			close(aborted)
+		}
+		fmt.Fprintf(stdoutW, "^C\nRP/0/RSP0/CPU0:router#\n") //nolint:errcheck
+	}()
+
+	_, err := sendCommand(ctx, stdinW, stdoutR, platform.Default().Prompts(), "show logging", false, nil, 10)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error, got: %v", err)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Fatal("the command was not aborted with Ctrl-C")
+	}
+}
+
+func TestSendCommand_AbortTimeout(t *testing.T) {
+	grace := abortGracePeriod
+	abortGracePeriod = 100 * time.Millisecond
+	defer func() { abortGracePeriod = grace }()
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	defer stdoutW.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		buf := make([]byte, 4096)
+		stdinR.Read(buf)                                            //nolint:errcheck
+		fmt.Fprintf(stdoutW, "show logging\nlong running output\n") //nolint:errcheck
+		cancel()
+		// Ctrl-C is ignored, the prompt never comes back
+		stdinR.Read(buf) //nolint:errcheck
+	}()
+
+	_, err := sendCommand(ctx, stdinW, stdoutR, platform.Default().Prompts(), "show logging", false, nil, 10)
+	if !errors.Is(err, context.Canceled) || !IsConnectionLost(err) {
+		t.Fatalf("expected cancellation transport error, got: %v", err)
+	}
+}
+
+func TestIsConnectionLost(t *testing.T) {
+	tests := []struct {
+		err  error
+		lost bool
+	}{
+		{err: &TransportError{Router: "r1", Err: io.EOF}, lost: true},
+		{err: fmt.Errorf("command failed with error: %w", &TransportError{Router: "r1", Err: io.ErrClosedPipe}), lost: true},
+		{err: &ReconnectedError{Cmd: "reload", Err: &TransportError{Router: "r1", Err: io.EOF}}, lost: true},
+		// Errors which are not transport failures are not a lost connection whatever their text says
+		{err: fmt.Errorf("failed to read from stdout with error: EOF"), lost: false},
+		{err: fmt.Errorf("exit status 1: write tcp 10.0.0.1:22: broken pipe"), lost: false},
+		{err: fmt.Errorf("timeout waiting for prompt"), lost: false},
+	}
+	for _, tt := range tests {
+		if got := IsConnectionLost(tt.err); got != tt.lost {
+			t.Fatalf("error %q: expected %t but got %t", tt.err, tt.lost, got)
+		}
+	}
+}
+
+// testSSHServer is a minimal router's SSH server answering every command with its output followed by the prompt,
+// drop is called for every command and closes the connection when it returns true. When stall is set, global
+// requests are never answered, as by a router behind a firewall which silently dropped the session.
+type testSSHServer struct {
+	ln      net.Listener
+	config  *ssh.ServerConfig
+	outputs map[string]string
+	drop    func(cmd string) bool
+	stall   atomic.Bool
+}
+
+func newTestSSHServer(t *testing.T, outputs map[string]string, drop func(cmd string) bool) *testSSHServer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate host key with error: %+v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("failed to create host key signer with error: %+v", err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen with error: %+v", err)
+	}
+	s := &testSSHServer{ln: ln, config: config, outputs: outputs, drop: drop}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+
+	return s
+}
+
+func (s *testSSHServer) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *testSSHServer) serve(c net.Conn) {
+	defer c.Close()
+	_, chans, reqs, err := ssh.NewServerConn(c, s.config)
+	if err != nil {
+		return
+	}
+	go func() {
+		for req := range reqs {
+			if s.stall.Load() {
+				continue
+			}
+			req.Reply(false, nil) //nolint:errcheck
+		}
+	}()
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "unsupported channel type") //nolint:errcheck
+			continue
+		}
+		ch, chReqs, err := nc.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range chReqs {
+				req.Reply(req.Type == "pty-req" || req.Type == "shell", nil) //nolint:errcheck
+			}
+		}()
+		const prompt = "RP/0/RSP0/CPU0:router#"
+		fmt.Fprintf(ch, "banner\n%s", prompt) //nolint:errcheck
+		r := bufio.NewReader(ch)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimSpace(line)
+			if s.drop != nil && s.drop(cmd) {
+				return
+			}
+			fmt.Fprintf(ch, "%s\n%s\n%s", cmd, s.outputs[cmd], prompt) //nolint:errcheck
+		}
+	}
+}
+
+func TestRouterReconnect(t *testing.T) {
+	var mx sync.Mutex
+	dropped := false
+	srv := newTestSSHServer(t, map[string]string{"show clock": "10:00:00.000 UTC"}, func(cmd string) bool {
+		mx.Lock()
+		defer mx.Unlock()
+		// The connection goes away once, when the router reloads
+		if cmd == "reload" && !dropped {
+			dropped = true
+			return true
+		}
+		return false
+	})
+	config := &ssh.ClientConfig{User: "cisco", HostKeyCallback: ssh.InsecureIgnoreHostKey(), Timeout: 5 * time.Second}
+	tests := []struct {
+		name      string
+		reconnect *ReconnectPolicy
+	}{
+		{name: "reconnect disabled"},
+		{name: "reconnect enabled", reconnect: &ReconnectPolicy{MaxAttempts: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mx.Lock()
+			dropped = false
+			mx.Unlock()
+			r, err := NewRouterWithOptions("127.0.0.1", srv.port(), "nxos", config, nil, &RouterOptions{Reconnect: tt.reconnect})
+			if err != nil {
+				t.Fatalf("failed to connect with error: %+v", err)
+			}
+			defer r.Close()
+			_, err = r.ProcessCommand(context.Background(), &Command{Cmd: "reload"}, true)
+			var re *ReconnectedError
+			if tt.reconnect == nil {
+				if err == nil || errors.As(err, &re) {
+					t.Fatalf("expected connection error, got: %+v", err)
+				}
+				return
+			}
+			if !errors.As(err, &re) || re.Cmd != "reload" || re.Attempts != 1 {
+				t.Fatalf("expected reconnected error, got: %+v", err)
+			}
+			rs, err := r.ProcessCommand(context.Background(), &Command{Cmd: "show clock"}, true)
+			if err != nil {
+				t.Fatalf("command after reconnect failed with error: %+v", err)
+			}
+			if len(rs) != 1 || !strings.Contains(string(rs[0].Result), "10:00:00") {
+				t.Fatalf("unexpected results after reconnect: %+v", rs)
+			}
+		})
+	}
+}
+
+func TestRouterAbortedSession(t *testing.T) {
+	grace := abortGracePeriod
+	abortGracePeriod = 100 * time.Millisecond
+	defer func() { abortGracePeriod = grace }()
+	release := make(chan struct{})
+	defer close(release)
+	srv := newTestSSHServer(t, map[string]string{"show clock": "10:00:00.000 UTC"}, func(cmd string) bool {
+		// The router hangs on the command and ignores Ctrl-C
+		if cmd == "show tech-support" {
+			<-release
+			return true
+		}
+		return false
+	})
+	config := &ssh.ClientConfig{User: "cisco", HostKeyCallback: ssh.InsecureIgnoreHostKey(), Timeout: 5 * time.Second}
+	tests := []struct {
+		name      string
+		reconnect *ReconnectPolicy
+	}{
+		{name: "reconnect disabled"},
+		{name: "reconnect enabled", reconnect: &ReconnectPolicy{MaxAttempts: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRouterWithOptions("127.0.0.1", srv.port(), "nxos", config, nil, &RouterOptions{Reconnect: tt.reconnect})
+			if err != nil {
+				t.Fatalf("failed to connect with error: %+v", err)
+			}
+			defer r.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			if _, err := r.ProcessCommand(ctx, &Command{Cmd: "show tech-support"}, true); !IsConnectionLost(err) {
+				t.Fatalf("expected transport error, got: %+v", err)
+			}
+			// The broken session is never reused
+			_, err = r.ProcessCommand(context.Background(), &Command{Cmd: "show clock"}, true)
+			var re *ReconnectedError
+			if tt.reconnect == nil {
+				if !IsConnectionLost(err) || errors.As(err, &re) {
+					t.Fatalf("expected transport error, got: %+v", err)
+				}
+				return
+			}
+			if !errors.As(err, &re) || re.Cmd != "show clock" {
+				t.Fatalf("expected reconnected error, got: %+v", err)
+			}
+			rs, err := r.ProcessCommand(context.Background(), &Command{Cmd: "show clock"}, true)
+			if err != nil {
+				t.Fatalf("command after reconnect failed with error: %+v", err)
+			}
+			if len(rs) != 1 || !strings.Contains(string(rs[0].Result), "10:00:00") {
+				t.Fatalf("unexpected results after reconnect: %+v", rs)
+			}
+		})
+	}
+}
+
+func TestRouterKeepAlive(t *testing.T) {
+	srv := newTestSSHServer(t, map[string]string{"show clock": "10:00:00.000 UTC"}, nil)
+	config := &ssh.ClientConfig{User: "cisco", HostKeyCallback: ssh.InsecureIgnoreHostKey(), Timeout: 5 * time.Second}
+	r, err := NewRouterWithOptions("127.0.0.1", srv.port(), "nxos", config, nil, &RouterOptions{
+		KeepAlive: &KeepAlivePolicy{Interval: 1, MaxFailures: 1},
+	})
+	if err != nil {
+		t.Fatalf("failed to connect with error: %+v", err)
+	}
+	defer r.Close()
+	// Answered keepalives keep the transport alive
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := r.GetData(context.Background(), "show clock", false, 5); err != nil {
+		t.Fatalf("command failed with error: %+v", err)
+	}
+	srv.stall.Store(true)
+	time.Sleep(3 * time.Second)
+	_, err = r.GetData(context.Background(), "show clock", false, 5)
+	var te *TransportError
+	if !errors.As(err, &te) || te.Failures != 1 {
+		t.Fatalf("expected transport error, got: %+v", err)
+	}
+	if !IsConnectionLost(err) {
+		t.Fatalf("transport error must be considered a lost connection")
+	}
+}
+
+func TestLocalRouterErrors(t *testing.T) {
+	r := NewLocalRouter("local", nil)
+	for _, cmd := range []string{"bash -c exit 1", "routercommander-no-such-command"} {
+		_, err := r.GetData(context.Background(), cmd, false, 5)
+		if err == nil {
+			t.Fatalf("command %q supposed to fail but succeeded", cmd)
+		}
+		// Failed commands do not mean the connection is lost
+		if IsConnectionLost(err) {
+			t.Fatalf("command %q: error %+v must not be considered a lost connection", cmd, err)
+		}
+	}
+}
+
+func TestLocalRouterProcessCommand(t *testing.T) {
+	r := NewLocalRouter("local", nil)
+	tests := []struct {
+		name     string
+		cmd      *Command
+		rejected string
+		fail     bool
+	}{
+		{
+			name: "succeeded",
+			cmd:  &Command{Cmd: "echo ok"},
+		},
+		{
+			name:     "rejected with warning",
+			cmd:      &Command{Cmd: "bash -c exit 3", OnError: OnErrorWarn},
+			rejected: "exit status 3",
+		},
+		{
+			name:     "rejected and continued",
+			cmd:      &Command{Cmd: "routercommander-no-such-command", OnError: OnErrorContinue},
+			rejected: "executable file not found",
+		},
+		{
+			name: "rejected and failed",
+			cmd:  &Command{Cmd: "bash -c exit 3", OnError: OnErrorFail},
+			fail: true,
+		},
+		{
+			name: "configuration block",
+			cmd:  &Command{Cmd: "interfaces", Config: &ConfigBlock{Lines: []string{"interface Loopback0"}}},
+			fail: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, err := r.ProcessCommand(context.Background(), tt.cmd, false)
+			if err != nil && !tt.fail {
+				t.Fatalf("supposed to succeed but failed with error: %+v", err)
+			}
+			if err == nil && tt.fail {
+				t.Fatalf("supposed to fail but succeeded")
+			}
+			if err != nil {
+				return
+			}
+			// Only rejected results are returned when results are not collected
+			if tt.rejected == "" {
+				if len(rs) != 0 {
+					t.Fatalf("expected no results, got %+v", rs)
+				}
+				return
+			}
+			if len(rs) != 1 || !strings.Contains(rs[0].Error, tt.rejected) {
+				t.Fatalf("expected rejected result %q, got %+v", tt.rejected, rs)
+			}
+		})
+	}
+	if _, err := r.ProcessCommand(context.Background(), tests[4].cmd, false); !errors.Is(err, ErrLocalConfig) {
+		t.Fatalf("expected ErrLocalConfig, got %+v", err)
+	}
+}
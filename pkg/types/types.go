@@ -1,6 +1,11 @@
 package types
 
-import "regexp"
+import (
+	"regexp"
+	"time"
+
+	"github.com/expr-lang/expr/vm"
+)
 
 type Command struct {
 	Cmd                string     `yaml:"command"`
@@ -20,15 +25,44 @@ type Command struct {
 	// from commands to specific set of tests
 	// defined in tests section for a specific command. If TestIDs are not specified
 	// then all tests defined for a specific command are executed.
-	TestIDs       []int `yaml:"command_test_ids"`
+	TestIDs []int `yaml:"command_test_ids"`
+	// OnError defines what happens when the router rejects the command, for example because of a typo: fail, warn
+	// or continue. The default is warn, or fail when stop_on_error of the collect section is true.
+	OnError string `yaml:"on_error"`
+	// Foreach is the name of a capture group of a pattern with store_captures set, the command is executed for every
+	// captured value referenced in the command as {{.name}}, the command is skipped when nothing is captured.
+	Foreach string `yaml:"foreach"`
+	// When is an expression evaluated against the router's Facts before the command is executed, the command
+	// is skipped when it evaluates to false, for example: Platform == "iosxr" && IsExistingLocation("0/RP1/CPU0")
+	When        string      `yaml:"when"`
+	WhenProgram *vm.Program `yaml:"-"`
+	// Config, when defined, turns the command into a configuration block applied in configuration mode,
+	// Cmd is then used only as the name of the block.
+	Config        *ConfigBlock `yaml:"config"`
 	CommandResult *CommandResult
 }
 
+// ConfigBlock defines configuration lines applied and committed in configuration mode.
+type ConfigBlock struct {
+	Lines []string `yaml:"lines"`
+	// CommitConfirmed, when not 0, commits the configuration with the platform's commit confirmed, "commit confirmed <seconds>"
+	// on IOS-XR, the configuration is committed permanently only when all post checks pass, otherwise it is rolled back.
+	CommitConfirmed int        `yaml:"commit_confirmed"`
+	PostChecks      []*Command `yaml:"post_checks"`
+	// RollbackCommand is executed when post checks fail, default is the platform's rollback, "rollback configuration last 1" on IOS-XR
+	RollbackCommand string `yaml:"rollback_command"`
+}
+
 type Commander struct {
-	Repro             *Repro     `yaml:"repro"`
-	Collect           *Collect   `yaml:"collect"`
-	Tests             []*Tests   `yaml:"tests"`
-	MainCommandGroup  []*Command `yaml:"commands"`
+	// Include lists commands files merged into the commands file, relative paths are relative to the
+	// commands file, globs are supported, see GetCommands
+	Include []string `yaml:"include"`
+	// Vars defines variables referenced by commands as {{.Name}}, see Expand
+	Vars              map[string]string `yaml:"vars"`
+	Repro             *Repro            `yaml:"repro"`
+	Collect           *Collect          `yaml:"collect"`
+	Tests             []*Tests          `yaml:"tests"`
+	MainCommandGroup  []*Command        `yaml:"commands"`
 	CommandsWithTests map[string]*Tests
 }
 
@@ -39,7 +73,19 @@ type Repro struct {
 	StopWhenTriggered      bool       `yaml:"stop_when_triggered"`
 }
 
+// Policies applied to the command rejected by the router
+const (
+	// OnErrorFail stops the processing of the router
+	OnErrorFail = "fail"
+	// OnErrorWarn logs a warning and reports the command as failed, the processing continues
+	OnErrorWarn = "warn"
+	// OnErrorContinue processes the output as usual, the error is only recorded with the command's results
+	OnErrorContinue = "continue"
+)
+
 type Collect struct {
+	// StopOnError stops starting remaining routers when a router fails to connect, it also makes commands
+	// rejected by the router fail the router's processing, unless the command's on_error says otherwise.
 	StopOnError   bool `yaml:"stop_on_error"`
 	ProcessResult bool `yaml:"process_result"`
 }
@@ -59,18 +105,34 @@ type Test struct {
 	Separator           string     `yaml:"separator"`
 	IfTriggeredCommands []*Command `yaml:"if_triggered_commands"`
 	CheckAllResults     bool       `yaml:"check_all_results"`
-	ValuesStore         map[int]map[int]interface{}
+	// Condition is an expression evaluated for every match of the pattern instead of fields' operations, the test
+	// is triggered when it evaluates to true, for example: delta.drops > 100 && state == "UP", see EvaluateCondition
+	Condition        string      `yaml:"condition"`
+	ConditionProgram *vm.Program `yaml:"-"`
+	// ValuesStore keeps fields' values per iteration keyed by the field's index
+	ValuesStore map[int]map[int]interface{}
+	// ConditionStore keeps values of the pattern's capture groups evaluated by the condition per iteration
+	// keyed by the match
+	ConditionStore map[int]map[ConditionMatch]*ConditionValues
+	// TimeStore keeps the time when fields' values were extracted per iteration, used by rate operations
+	TimeStore map[int]time.Time
 }
 
 type Field struct {
-	FieldNumber int    `yaml:"field_number"`
-	Operation   string `yaml:"operation"`
-	Value       string `yaml:"value"`
-	Result      interface{}
+	FieldNumber int `yaml:"field_number"`
+	// Group is the name of the test pattern's capture group to use as the field's value, when set
+	// the field number and the test separator are not used.
+	Group     string `yaml:"group"`
+	Operation string `yaml:"operation"`
+	Value     string `yaml:"value"`
+	Result    interface{}
 }
 
 type Pattern struct {
 	PatternString string `yaml:"pattern_string"`
+	// StoreCaptures stores values of the pattern's named capture groups, following commands of the router
+	// reference them as {{.name}}, see Captures.
+	StoreCaptures bool `yaml:"store_captures"`
 	RegExp        *regexp.Regexp
 }
 
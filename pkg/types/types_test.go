@@ -63,6 +63,7 @@ func normalizeCommanderForTest(c *Commander) *Commander {
 					}
 					testCopy := *test
 					testCopy.ValuesStore = nil
+					testCopy.TimeStore = nil
 					if test.Pattern != nil {
 						pCopy := *test.Pattern
 						pCopy.RegExp = nil
@@ -125,6 +126,122 @@ func TestParseCommandFile(t *testing.T) {
 			},
 			fail: false,
 		},
+		{
+			name: "test field with capture group",
+			input: []byte(`tests:
+- command: "show controllers npu stats"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: 'drops\s+(?P<drops>\d+)'
+    fields:
+    - group: drops
+      operation: "delta_with_previous_gt"
+      value: "100"`),
+			expect: &Commander{
+				Tests: []*Tests{
+					{
+						Cmd: "show controllers npu stats",
+						Source: []*Test{
+							{
+								ID: 1,
+								Pattern: &Pattern{
+									PatternString: `drops\s+(?P<drops>\d+)`,
+								},
+								Fields: []*Field{
+									{
+										Group:     "drops",
+										Operation: "delta_with_previous_gt",
+										Value:     "100",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			fail: false,
+		},
+		{
+			name: "test field with unknown capture group",
+			input: []byte(`tests:
+- command: "show controllers npu stats"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: 'drops\s+(?P<drops>\d+)'
+    fields:
+    - group: errors
+      operation: "compare_with_value_gt"
+      value: "0"`),
+			fail: true,
+		},
+		{
+			name: "post checks without commit confirmed",
+			input: []byte(`commands:
+- command: "shut bundle"
+  config:
+    lines:
+    - "interface Bundle-Ether1"
+    - "shutdown"
+    post_checks:
+    - command: "show bgp summary"`),
+			fail: true,
+		},
+		{
+			name: "numeric operation with non numeric value",
+			input: []byte(`tests:
+- command: "show processes cpu"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: 'CPU utilization'
+    fields:
+    - field_number: 6
+      operation: "compare_with_value_gt"
+      value: "high"`),
+			fail: true,
+		},
+		{
+			name: "invalid on_error",
+			input: []byte(`commands:
+- command: "show version"
+  on_error: "ignore"`),
+			fail: true,
+		},
+		{
+			name: "invalid when",
+			input: []byte(`commands:
+- command: "show version"
+  when: 'Platform = "iosxr"'`),
+			fail: true,
+		},
+		{
+			name: "condition with unknown capture group",
+			input: []byte(`tests:
+- command: "show controllers npu stats"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: 'drops\s+(?P<drops>\d+)'
+    condition: 'delta.drops > 100 && state == "UP"'`),
+			fail: true,
+		},
+		{
+			name: "condition and fields",
+			input: []byte(`tests:
+- command: "show controllers npu stats"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: 'drops\s+(?P<drops>\d+)'
+    condition: 'drops > 100'
+    fields:
+    - group: drops
+      operation: "compare_with_value_gt"
+      value: "100"`),
+			fail: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,528 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is a problem found in the commands file.
+type Problem struct {
+	// File is the commands file, empty when the validated commands are not read from a file
+	File string
+	// Line is the line of the commands file, 0 when the line is not known
+	Line    int
+	Message string
+}
+
+func (p *Problem) String() string {
+	s := p.Message
+	if p.Line != 0 {
+		s = fmt.Sprintf("line %d: %s", p.Line, p.Message)
+	}
+	if p.File != "" {
+		s = p.File + ": " + s
+	}
+	return s
+}
+
+// ValidateCommandFile validates the commands file and the files it includes, see ValidateCommands. References
+// between commands and tests are checked against the commands merged from all files.
+func ValidateCommandFile(fn string) ([]*Problem, error) {
+	return validateCommandFile(fn, false)
+}
+
+// ValidateLocalCommandFile validates the commands file executed on the local host, see ValidateCommandFile,
+// configuration blocks are reported as they are not supported for local routers.
+func ValidateLocalCommandFile(fn string) ([]*Problem, error) {
+	return validateCommandFile(fn, true)
+}
+
+func validateCommandFile(fn string, local bool) ([]*Problem, error) {
+	b, err := readCommandFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := loadCommandFile(fn, nil, make(map[string]bool))
+	if err != nil {
+		// Problems of includes are reported by validating the files, references are checked against all files
+		// which can be loaded
+		merged = &Commander{}
+		mergeAll(fn, merged, make(map[string]bool))
+	}
+	problems := make([]*Problem, 0)
+	validated := make(map[string]bool)
+	var validate func(fn string, b []byte, stack []string)
+	validate = func(fn string, b []byte, stack []string) {
+		abs, _ := filepath.Abs(fn)
+		validated[abs] = true
+		stack = append(stack, abs)
+		v := &validator{dir: filepath.Dir(fn), merged: merged, local: local}
+		ps := v.validate(b)
+		for _, inc := range v.includes {
+			a, _ := filepath.Abs(inc.file)
+			if onStack(stack, a) {
+				ps = append(ps, &Problem{Line: inc.line, Message: fmt.Sprintf("include of %s makes a cycle", inc.file)})
+			}
+		}
+		sort.SliceStable(ps, func(i, j int) bool {
+			return ps[i].Line < ps[j].Line
+		})
+		for _, p := range ps {
+			p.File = fn
+		}
+		problems = append(problems, ps...)
+		for _, inc := range v.includes {
+			a, _ := filepath.Abs(inc.file)
+			if validated[a] {
+				continue
+			}
+			ib, err := readCommandFile(inc.file)
+			if err != nil {
+				problems = append(problems, &Problem{File: fn, Line: inc.line, Message: err.Error()})
+				continue
+			}
+			validate(inc.file, ib, stack)
+		}
+	}
+	validate(fn, b, nil)
+
+	return problems, nil
+}
+
+// mergeAll merges the file and all files it includes skipping files which can not be loaded
+func mergeAll(fn string, m *Commander, visited map[string]bool) {
+	abs, _ := filepath.Abs(fn)
+	if visited[abs] {
+		return
+	}
+	visited[abs] = true
+	b, err := readCommandFile(fn)
+	if err != nil {
+		return
+	}
+	c := &Commander{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return
+	}
+	for _, inc := range c.Include {
+		files, err := resolveInclude(filepath.Dir(fn), inc)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			mergeAll(f, m, visited)
+		}
+	}
+	merge(m, c)
+}
+
+// ValidateCommands decodes the commands file rejecting unknown keys and checks commands and tests for problems
+// which are not detected until the commands are executed. All found problems are returned sorted by line.
+// Includes are not followed, see ValidateCommandFile.
+func ValidateCommands(b []byte) []*Problem {
+	return (&validator{}).validate(b)
+}
+
+func (v *validator) validate(b []byte) []*Problem {
+	c := &Commander{}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && err != io.EOF {
+		var te *yaml.TypeError
+		if !errors.As(err, &te) {
+			// The file is not a valid yaml, nothing else can be checked
+			v.addError(strings.TrimPrefix(err.Error(), "yaml: "))
+			return v.problems
+		}
+		for _, e := range te.Errors {
+			v.addError(e)
+		}
+	}
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(b, doc); err != nil {
+		v.addError(strings.TrimPrefix(err.Error(), "yaml: "))
+		return v.problems
+	}
+	root := doc
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) != 0 {
+		root = doc.Content[0]
+	}
+	v.commander(c, root)
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		return v.problems[i].Line < v.problems[j].Line
+	})
+
+	return v.problems
+}
+
+// include is a file included by the validated file
+type include struct {
+	file string
+	line int
+}
+
+var errorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// validator collects problems of the commands file
+type validator struct {
+	// dir is the directory of the validated file, includes are resolved when it is set
+	dir string
+	// merged are commands merged from all files, references are checked against them when they are set
+	merged *Commander
+	// includes are files included by the validated file
+	includes []*include
+	problems []*Problem
+	// captures are names of capture groups of patterns with store_captures set
+	captures map[string]bool
+	// local is true when commands are executed on the local host
+	local bool
+}
+
+func (v *validator) add(n *yaml.Node, format string, a ...interface{}) {
+	v.problems = append(v.problems, &Problem{Line: line(n), Message: fmt.Sprintf(format, a...)})
+}
+
+// addError adds the decoder's error, the line is extracted from the error's text
+func (v *validator) addError(e string) {
+	p := &Problem{Message: e}
+	if m := errorLine.FindStringSubmatch(e); m != nil {
+		p.Line, _ = strconv.Atoi(m[1])
+		p.Message = m[2]
+	}
+	v.problems = append(v.problems, p)
+}
+
+func (v *validator) commander(c *Commander, n *yaml.Node) {
+	ref := c
+	if v.merged != nil {
+		ref = v.merged
+	}
+	v.captures = make(map[string]bool)
+	for _, name := range captureNames(ref) {
+		v.captures[name] = true
+	}
+	// Tests are executed only for commands of the main group
+	commands := make(map[string]bool)
+	for _, cmd := range ref.MainCommandGroup {
+		if cmd != nil {
+			commands[cmd.Cmd] = true
+		}
+	}
+	testIDs := v.tests(c.Tests, child(n, "tests"), commands)
+	if v.merged != nil {
+		testIDs = make(map[string]map[int]bool)
+		for _, t := range v.merged.Tests {
+			if t == nil {
+				continue
+			}
+			ids := make(map[int]bool)
+			for _, e := range t.Source {
+				if e != nil {
+					ids[e.ID] = true
+				}
+			}
+			testIDs[t.Cmd] = ids
+		}
+	}
+	if v.dir != "" {
+		in := child(n, "include")
+		for i, inc := range c.Include {
+			files, err := resolveInclude(v.dir, inc)
+			if err != nil {
+				v.add(item(in, i), "%v", err)
+				continue
+			}
+			for _, f := range files {
+				v.includes = append(v.includes, &include{file: f, line: line(item(in, i))})
+			}
+		}
+	}
+	cn := child(n, "commands")
+	v.commands(c.MainCommandGroup, cn)
+	for i, cmd := range c.MainCommandGroup {
+		if cmd == nil || len(cmd.TestIDs) == 0 {
+			continue
+		}
+		ids, ok := testIDs[cmd.Cmd]
+		if !ok {
+			v.add(keyOf(item(cn, i), "command_test_ids"), "command_test_ids are defined but there are no tests for command %q", cmd.Cmd)
+			continue
+		}
+		for _, id := range cmd.TestIDs {
+			if !ids[id] {
+				v.add(keyOf(item(cn, i), "command_test_ids"), "command_test_ids refers to test id %d which is not defined for command %q", id, cmd.Cmd)
+			}
+		}
+	}
+	for k := range c.Vars {
+		if _, ok := reservedVars[k]; ok {
+			v.add(keyOf(child(n, "vars"), k), "variable %q is reserved, it is defined when the command is executed", k)
+		}
+	}
+	if c.Repro != nil {
+		rn := child(n, "repro")
+		if c.Repro.Times < 0 || c.Repro.Interval < 0 {
+			v.add(rn, "repro times and interval must not be negative")
+		}
+		v.commands(c.Repro.PostMortemCommandGroup, child(rn, "if_triggered_commands"))
+	}
+}
+
+// tests checks tests and returns ids of tests defined per command
+func (v *validator) tests(tests []*Tests, n *yaml.Node, commands map[string]bool) map[string]map[int]bool {
+	testIDs := make(map[string]map[int]bool)
+	for i, t := range tests {
+		if t == nil {
+			continue
+		}
+		tn := item(n, i)
+		if _, ok := testIDs[t.Cmd]; ok {
+			v.add(tn, "tests for command %q are already defined, only the last definition is used", t.Cmd)
+		}
+		if !commands[t.Cmd] {
+			v.add(keyOf(tn, "command"), "tests are defined for command %q which is not in commands", t.Cmd)
+		}
+		ids := make(map[int]bool)
+		sn := child(tn, "command_tests")
+		for j, e := range t.Source {
+			if e == nil {
+				continue
+			}
+			en := item(sn, j)
+			if ids[e.ID] {
+				v.add(keyOf(en, "id"), "test id %d is already defined for command %q", e.ID, t.Cmd)
+			}
+			ids[e.ID] = true
+			v.test(e, en)
+		}
+		testIDs[t.Cmd] = ids
+	}
+
+	return testIDs
+}
+
+func (v *validator) test(t *Test, n *yaml.Node) {
+	var re *regexp.Regexp
+	if t.Pattern == nil {
+		v.add(n, "test id %d has no pattern", t.ID)
+	} else {
+		var err error
+		if re, err = regexp.Compile(t.Pattern.PatternString); err != nil {
+			v.add(keyOf(n, "pattern"), "test id %d has invalid pattern %q: %v", t.ID, t.Pattern.PatternString, err)
+		}
+	}
+	if t.Occurrence < 0 {
+		v.add(keyOf(n, "occurrence"), "test id %d occurrence must not be negative", t.ID)
+	}
+	if t.Condition != "" {
+		if len(t.Fields) != 0 {
+			v.add(keyOf(n, "condition"), "test id %d defines both fields and condition", t.ID)
+		}
+		if re != nil && !t.hasVars() {
+			// The test is not prepared, the condition is compiled with a copy of the compiled pattern,
+			// conditions referencing variables are checked when variables are expanded
+			tc, p := *t, *t.Pattern
+			p.RegExp = re
+			tc.Pattern = &p
+			if _, err := compileCondition(&tc); err != nil {
+				v.add(keyOf(n, "condition"), "%v", err)
+			}
+		}
+	}
+	fn := child(n, "fields")
+	for i, f := range t.Fields {
+		if f == nil {
+			continue
+		}
+		fi := item(fn, i)
+		if !IsOperation(f.Operation) {
+			v.add(keyOf(fi, "operation"), "test id %d field uses unsupported operation %q", t.ID, f.Operation)
+		}
+		if f.Group != "" && re != nil && re.SubexpIndex(f.Group) == -1 {
+			v.add(keyOf(fi, "group"), "test id %d field uses capture group %q which is not defined in the test pattern", t.ID, f.Group)
+		}
+		if IsNumericOperation(f.Operation) && !isTemplate(f.Value) {
+			if _, err := ParseNumber(f.Value); err != nil {
+				v.add(keyOf(fi, "value"), "test id %d field operation %s requires a numeric value: %v", t.ID, f.Operation, err)
+			}
+		}
+	}
+	v.commands(t.IfTriggeredCommands, child(n, "if_triggered_commands"))
+}
+
+func (v *validator) commands(cmds []*Command, n *yaml.Node) {
+	for i, cmd := range cmds {
+		if cmd != nil {
+			v.command(cmd, item(n, i))
+		}
+	}
+}
+
+func (v *validator) command(cmd *Command, n *yaml.Node) {
+	if cmd.Config == nil && strings.TrimSpace(cmd.Cmd) == "" {
+		v.add(n, "command is not defined")
+	}
+	for _, p := range []struct {
+		key   string
+		value int
+	}{
+		{"times", cmd.Times},
+		{"interval", cmd.Interval},
+		{"wait_before", cmd.WaitBefore},
+		{"wait_after", cmd.WaitAfter},
+		{"command_timeout", cmd.CmdTimeout},
+	} {
+		if p.value < 0 {
+			v.add(keyOf(n, p.key), "command %q %s must not be negative", cmd.Cmd, p.key)
+		}
+	}
+	if cmd.Times > 1 && cmd.Interval == 0 {
+		v.add(keyOf(n, "times"), "command %q times %d requires interval, without it the command is executed once", cmd.Cmd, cmd.Times)
+	}
+	if cmd.Interval > 0 && cmd.Times == 0 {
+		v.add(keyOf(n, "interval"), "command %q interval %d requires times, without it the command is executed once", cmd.Cmd, cmd.Interval)
+	}
+	if cmd.LocationFmtTmpl != "" {
+		if err := checkTemplate(cmd.LocationFmtTmpl); err != nil {
+			v.add(keyOf(n, "location_fmt_tmpl"), "command %q has invalid location_fmt_tmpl %q: %v", cmd.Cmd, cmd.LocationFmtTmpl, err)
+		}
+	}
+	if err := checkTemplate(cmd.Cmd); err != nil {
+		v.add(keyOf(n, "command"), "command %q is an invalid template: %v", cmd.Cmd, err)
+	}
+	if err := checkTemplate(cmd.PipeModifier); err != nil {
+		v.add(keyOf(n, "pipe_modifier"), "command %q has invalid pipe_modifier %q: %v", cmd.Cmd, cmd.PipeModifier, err)
+	}
+	ln := child(n, "location")
+	for i, l := range cmd.Location {
+		if err := checkTemplate(l); err != nil {
+			v.add(item(ln, i), "command %q has invalid location %q: %v", cmd.Cmd, l, err)
+		}
+	}
+	pn := child(n, "patterns")
+	for i, p := range cmd.Patterns {
+		if p == nil {
+			continue
+		}
+		re, err := regexp.Compile(p.PatternString)
+		if err != nil {
+			v.add(item(pn, i), "command %q has invalid pattern %q: %v", cmd.Cmd, p.PatternString, err)
+		} else if p.StoreCaptures && !hasNamedGroups(re) {
+			v.add(item(pn, i), "command %q pattern %q sets store_captures but has no named capture groups", cmd.Cmd, p.PatternString)
+		}
+	}
+	if cmd.Foreach != "" && !v.captures[cmd.Foreach] {
+		v.add(keyOf(n, "foreach"), "command %q foreach %q is not a capture group of any pattern with store_captures set", cmd.Cmd, cmd.Foreach)
+	}
+	if cmd.When != "" {
+		if _, err := compileWhen(cmd.When); err != nil {
+			v.add(keyOf(n, "when"), "command %q has invalid when %q: %v", cmd.Cmd, cmd.When, err)
+		}
+	}
+	switch cmd.OnError {
+	case "", OnErrorFail, OnErrorWarn, OnErrorContinue:
+	default:
+		v.add(keyOf(n, "on_error"), "command %q has invalid on_error %q, supported values: %s, %s, %s", cmd.Cmd, cmd.OnError, OnErrorFail, OnErrorWarn, OnErrorContinue)
+	}
+	if cmd.Config != nil {
+		if v.local {
+			v.add(keyOf(n, "config"), "configuration block %q: %v", cmd.Cmd, ErrLocalConfig)
+		}
+		v.config(cmd, child(n, "config"))
+	}
+}
+
+func (v *validator) config(cmd *Command, n *yaml.Node) {
+	cfg := cmd.Config
+	if len(cfg.Lines) == 0 {
+		v.add(n, "configuration block %q has no lines", cmd.Cmd)
+	}
+	if cfg.CommitConfirmed < 0 {
+		v.add(keyOf(n, "commit_confirmed"), "configuration block %q has invalid commit_confirmed %d", cmd.Cmd, cfg.CommitConfirmed)
+	}
+	if len(cfg.PostChecks) != 0 && cfg.CommitConfirmed == 0 {
+		v.add(keyOf(n, "post_checks"), "configuration block %q defines post_checks which require commit_confirmed", cmd.Cmd)
+	}
+	pn := child(n, "post_checks")
+	for i, pc := range cfg.PostChecks {
+		if pc != nil && pc.When != "" {
+			v.add(keyOf(item(pn, i), "when"), "post check %q of configuration block %q can not have when, post checks are always executed", pc.Cmd, cmd.Cmd)
+		}
+	}
+	v.commands(cfg.PostChecks, pn)
+}
+
+func hasNamedGroups(re *regexp.Regexp) bool {
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTemplate parses the template and executes it, references to variables are not checked as variables
+// of routers are known only when routers are processed
+func checkTemplate(s string) error {
+	tmpl, err := template.New("check").Parse(s)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(io.Discard, map[string]interface{}{})
+}
+
+// child returns the value of the key in the mapping node, nil when it is not found.
+func child(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// keyOf returns the key in the mapping node, the mapping node when the key is not found.
+func keyOf(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return n
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i]
+		}
+	}
+
+	return n
+}
+
+// item returns the i-th item of the sequence node, nil when it is not found.
+func item(n *yaml.Node, i int) *yaml.Node {
+	if n == nil || n.Kind != yaml.SequenceNode || i >= len(n.Content) {
+		return nil
+	}
+
+	return n.Content[i]
+}
+
+func line(n *yaml.Node) int {
+	if n == nil {
+		return 0
+	}
+
+	return n.Line
+}
@@ -0,0 +1,212 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateCommands(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expect []string
+	}{
+		{
+			name: "valid",
+			input: `collect:
+  process_result: true
+tests:
+- command: "show cef drops"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: 'Discard drops packets\s*:\s*(?P<drops>\d+)'
+    fields:
+    - group: drops
+      operation: "compare_with_value_gt"
+      value: "100"
+commands:
+- command: "show cef drops"
+  location: ["all-lc"]
+  location_fmt_tmpl: "node0_{{.Slot}}_cpu0"
+  times: 3
+  interval: 10
+  command_test_ids: [1]`,
+		},
+		{
+			name: "unknown keys",
+			input: `collect:
+  health_check: true
+commands:
+- command: "show version"
+  proces_result: true`,
+			expect: []string{
+				"line 2: field health_check not found",
+				"line 5: field proces_result not found",
+			},
+		},
+		{
+			name:   "invalid yaml",
+			input:  "commands:\n- command: \"show version\n",
+			expect: []string{"line 2: found unexpected end of stream"},
+		},
+		{
+			name: "semantic problems",
+			input: `tests:
+- command: "show cef drops"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: 'drops\s+(?P<drops>\d+)'
+    fields:
+    - group: errors
+      operation: "compare_with_value_greater"
+      value: "100"
+- command: "show bgp summary"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: 'Idle'
+commands:
+- command: "show cef drops"
+  command_test_ids: [1, 2]
+  location: ["all-lc"]
+  location_fmt_tmpl: "node0_{{.Slot}_cpu0"
+- command: "show interfaces"
+  times: 10
+  command_test_ids: [3]
+  patterns:
+  - pattern_string: "[a-"
+  on_error: ignore`,
+			expect: []string{
+				"line 8: test id 1 field uses capture group \"errors\"",
+				"line 9: test id 1 field uses unsupported operation \"compare_with_value_greater\"",
+				"line 11: tests are defined for command \"show bgp summary\" which is not in commands",
+				"line 18: command_test_ids refers to test id 2",
+				"line 20: command \"show cef drops\" has invalid location_fmt_tmpl",
+				"line 22: command \"show interfaces\" times 10 requires interval",
+				"line 23: command_test_ids are defined but there are no tests for command \"show interfaces\"",
+				"line 25: command \"show interfaces\" has invalid pattern",
+				"line 26: command \"show interfaces\" has invalid on_error \"ignore\"",
+			},
+		},
+		{
+			name: "variables",
+			input: `vars:
+  VRF: GI
+  Location: "0/0/CPU0"
+commands:
+- command: "show cef vrf {{.VRF}} drops"
+  location: ["{{.LC}"]
+- command: 'run echo "<a href={{.VRF}}"'`,
+			expect: []string{
+				"line 3: variable \"Location\" is reserved",
+				"line 6: command \"show cef vrf {{.VRF}} drops\" has invalid location",
+			},
+		},
+		{
+			name: "captures",
+			input: `commands:
+- command: "show interfaces summary"
+  patterns:
+  - pattern_string: '(?P<intf>\S+) has input errors'
+    store_captures: true
+  - pattern_string: '\S+ is down'
+    store_captures: true
+- command: "show interface {{.intf}}"
+  foreach: intf
+- command: "show controllers {{.port}}"
+  foreach: port
+- command: "show platform"
+  when: 'Platform == "iosxr" &&'`,
+			expect: []string{
+				"line 6: command \"show interfaces summary\" pattern \"\\\\S+ is down\" sets store_captures but has no named capture groups",
+				"line 11: command \"show controllers {{.port}}\" foreach \"port\" is not a capture group",
+				"line 13: command \"show platform\" has invalid when",
+			},
+		},
+		{
+			name: "conditions",
+			input: `tests:
+- command: "show interfaces"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: '(?P<intf>\S+) is (?P<state>\w+), (?P<drops>\d+) drops'
+    condition: 'delta.drops > 100 && state == "UP"'
+  - id: 2
+    pattern:
+      pattern_string: '(?P<count>\d+) drops'
+    condition: 'count > 1'
+  - id: 3
+    pattern:
+      pattern_string: '(?P<drops>\d+) drops'
+    condition: 'drop > 1'
+commands:
+- command: "show interfaces"
+  command_test_ids: [1, 2, 3]`,
+			expect: []string{
+				"line 11: test id 2 capture group \"count\" is reserved in conditions",
+				"line 15: test id 3 has invalid condition \"drop > 1\"",
+			},
+		},
+		{
+			name: "configuration block",
+			input: `repro:
+  if_triggered_commands:
+  - command: "shut bundle"
+    config:
+      lines:
+      - "interface Bundle-Ether1"
+      - "shutdown"
+      post_checks:
+      - command: "show bgp summary"
+        wait_before: -5
+        when: 'Platform == "iosxr"'`,
+			expect: []string{
+				"line 8: configuration block \"shut bundle\" defines post_checks which require commit_confirmed",
+				"line 10: command \"show bgp summary\" wait_before must not be negative",
+				"line 11: post check \"show bgp summary\" of configuration block \"shut bundle\" can not have when",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := ValidateCommands([]byte(tt.input))
+			if len(problems) != len(tt.expect) {
+				t.Fatalf("expected %d problems, got %d: %+v", len(tt.expect), len(problems), problems)
+			}
+			for i, p := range problems {
+				if !strings.HasPrefix(p.String(), tt.expect[i]) {
+					t.Fatalf("expected problem %q, got %q", tt.expect[i], p.String())
+				}
+			}
+		})
+	}
+}
+
+func TestValidateLocalCommandFile(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "local.yaml")
+	if err := os.WriteFile(fn, []byte(`commands:
+- command: "uptime"
+- command: "interfaces"
+  config:
+    lines:
+    - interface Loopback0`), 0o644); err != nil {
+		t.Fatalf("failed to write file with error: %+v", err)
+	}
+	problems, err := ValidateCommandFile(fn)
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("expected no problems, got %+v, error: %+v", problems, err)
+	}
+	problems, err = ValidateLocalCommandFile(fn)
+	if err != nil {
+		t.Fatalf("failed to validate with error: %+v", err)
+	}
+	expect := fn + ": line 4: configuration block \"interfaces\": configuration blocks are not supported for local routers"
+	if len(problems) != 1 || problems[0].String() != expect {
+		t.Fatalf("expected problem %q, got %+v", expect, problems)
+	}
+}
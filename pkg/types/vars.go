@@ -0,0 +1,166 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Variables predefined for every router
+const (
+	// VarHostname is the name of the router
+	VarHostname = "Hostname"
+	// VarPlatform is the name of the router's platform
+	VarPlatform = "Platform"
+)
+
+// reservedVars are expanded when the command is executed, their references are kept by Expand
+var reservedVars = map[string]string{
+	"Location": "{{.Location}}",
+	"Slot":     "{{.Slot}}",
+}
+
+// Expand replaces references to variables, like {{.VRF}}, in commands, pipe modifiers, locations, location
+// templates, patterns, configuration lines and tests' fields values with values of variables. Variables of
+// the commands file's vars section are overridden by vars, a reference to an undefined variable is an error.
+// References to captured values are kept, they are replaced when the command is executed, see Captures.
+// The commander must not be shared, Clone returns a copy which can be expanded for a router.
+func (c *Commander) Expand(vars map[string]string) error {
+	data := make(map[string]string, len(c.Vars)+len(vars)+len(reservedVars))
+	for k, v := range c.Vars {
+		data[k] = v
+	}
+	for k, v := range vars {
+		data[k] = v
+	}
+	for k, v := range reservedVars {
+		data[k] = v
+	}
+	for _, name := range captureNames(c) {
+		data[name] = "{{." + name + "}}"
+	}
+	x := &expander{data: data}
+	x.commands(c.MainCommandGroup)
+	if c.Repro != nil {
+		x.commands(c.Repro.PostMortemCommandGroup)
+	}
+	for _, t := range c.Tests {
+		t.Cmd = x.expand("tests of command "+t.Cmd, t.Cmd)
+		for _, e := range t.Source {
+			what := fmt.Sprintf("test id %d", e.ID)
+			recompile := e.Condition != "" && e.hasVars()
+			x.pattern(what, e.Pattern)
+			e.Condition = x.expand(what, e.Condition)
+			if recompile && x.err == nil {
+				// The condition is compiled with the expanded pattern's capture groups
+				e.ConditionProgram, x.err = compileCondition(e)
+			}
+			for _, f := range e.Fields {
+				if !isTemplate(f.Value) {
+					continue
+				}
+				f.Value = x.expand(what, f.Value)
+				if x.err != nil || !IsNumericOperation(f.Operation) {
+					continue
+				}
+				if _, err := ParseNumber(f.Value); err != nil {
+					x.err = fmt.Errorf("test id %d field %d operation %s requires a numeric value: %+v", e.ID, f.FieldNumber, f.Operation, err)
+				}
+			}
+			x.commands(e.IfTriggeredCommands)
+		}
+	}
+	if x.err != nil {
+		return x.err
+	}
+	if c.CommandsWithTests != nil {
+		// Tests are found by the command, so they are keyed by the expanded command
+		c.CommandsWithTests = make(map[string]*Tests, len(c.Tests))
+		for _, t := range c.Tests {
+			c.CommandsWithTests[t.Cmd] = t
+		}
+	}
+
+	return nil
+}
+
+// expander expands strings keeping the first error
+type expander struct {
+	data map[string]string
+	err  error
+}
+
+// hasVars returns true if the test's pattern or condition references variables
+func (t *Test) hasVars() bool {
+	return isTemplate(t.Condition) || (t.Pattern != nil && isTemplate(t.Pattern.PatternString))
+}
+
+// isTemplate returns true if the string references variables
+func isTemplate(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+func (x *expander) expand(what string, s string) string {
+	if x.err != nil || !isTemplate(s) {
+		return s
+	}
+	tmpl, err := template.New("vars").Option("missingkey=error").Parse(s)
+	if err != nil {
+		x.err = fmt.Errorf("%s: failed to parse %q with error: %+v", what, s, err)
+		return s
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, x.data); err != nil {
+		x.err = fmt.Errorf("%s: failed to expand %q with error: %+v", what, s, err)
+		return s
+	}
+
+	return buf.String()
+}
+
+func (x *expander) pattern(what string, p *Pattern) {
+	if p == nil {
+		return
+	}
+	s := x.expand(what, p.PatternString)
+	if s == p.PatternString || x.err != nil {
+		return
+	}
+	p.PatternString = s
+	if p.RegExp == nil {
+		return
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		x.err = fmt.Errorf("%s: fail to compile regular expression %q with error: %+v", what, s, err)
+		return
+	}
+	p.RegExp = re
+}
+
+func (x *expander) commands(cmds []*Command) {
+	for _, cmd := range cmds {
+		if cmd == nil {
+			continue
+		}
+		what := fmt.Sprintf("command %q", cmd.Cmd)
+		cmd.Cmd = x.expand(what, cmd.Cmd)
+		cmd.PipeModifier = x.expand(what, cmd.PipeModifier)
+		cmd.LocationFmtTmpl = x.expand(what, cmd.LocationFmtTmpl)
+		for i := range cmd.Location {
+			cmd.Location[i] = x.expand(what, cmd.Location[i])
+		}
+		for _, p := range cmd.Patterns {
+			x.pattern(what, p)
+		}
+		if cmd.Config != nil {
+			for i := range cmd.Config.Lines {
+				cmd.Config.Lines[i] = x.expand(what, cmd.Config.Lines[i])
+			}
+			cmd.Config.RollbackCommand = x.expand(what, cmd.Config.RollbackCommand)
+			x.commands(cmd.Config.PostChecks)
+		}
+	}
+}
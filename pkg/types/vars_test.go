@@ -0,0 +1,127 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCommanderExpand(t *testing.T) {
+	input := `vars:
+  VRF: "GI"
+  LC: "0/0/CPU0"
+  THRESHOLD: "100"
+tests:
+- command: "show cef vrf {{.VRF}} drops"
+  command_tests:
+  - id: 1
+    pattern:
+      pattern_string: '{{.VRF}} drops\s+(?P<drops>\d+)'
+    fields:
+    - group: drops
+      operation: "compare_with_value_gt"
+      value: "{{.THRESHOLD}}"
+  - id: 2
+    pattern:
+      pattern_string: '{{.VRF}} errors\s+(?P<errors>\d+)'
+    condition: 'num(errors) > {{.THRESHOLD}}'
+commands:
+- command: "show cef vrf {{.VRF}} drops"
+  command_test_ids: [1, 2]
+- command: "show controllers {{.Location}} on {{.Hostname}}"
+  location_customized: true
+  location: ["{{.LC}}"]
+  pipe_modifier: "include {{.VRF}}"
+`
+	tests := []struct {
+		name  string
+		vars  map[string]string
+		cmds  []string
+		loc   string
+		value string
+		match string
+		// errors is evaluated by the condition of test id 2, it is triggered when errors are above the threshold
+		errors    string
+		triggered bool
+		fail      bool
+	}{
+		{
+			name:   "variables of the commands file",
+			vars:   map[string]string{VarHostname: "r1"},
+			cmds:   []string{"show cef vrf GI drops", "show controllers {{.Location}} on r1"},
+			loc:    "0/0/CPU0",
+			value:  "100",
+			match:  "GI drops 10",
+			errors: "GI errors 50",
+		},
+		{
+			name:      "overridden variables",
+			vars:      map[string]string{VarHostname: "r2", "VRF": "RED", "LC": "0/1/CPU0", "THRESHOLD": "5"},
+			cmds:      []string{"show cef vrf RED drops", "show controllers {{.Location}} on r2"},
+			loc:       "0/1/CPU0",
+			value:     "5",
+			match:     "RED drops 10",
+			errors:    "RED errors 50",
+			triggered: true,
+		},
+		{
+			name: "undefined variable",
+			vars: map[string]string{},
+			fail: true,
+		},
+		{
+			name: "non numeric value",
+			vars: map[string]string{VarHostname: "r1", "THRESHOLD": "high"},
+			fail: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseCommandFile([]byte(input))
+			if err != nil {
+				t.Fatalf("failed to parse commands with error: %+v", err)
+			}
+			err = c.Expand(tt.vars)
+			if err != nil && !tt.fail {
+				t.Fatalf("supposed to succeed but failed with error: %+v", err)
+			}
+			if err == nil && tt.fail {
+				t.Fatalf("supposed to fail but succeeded")
+			}
+			if err != nil {
+				return
+			}
+			for i, cmd := range tt.cmds {
+				if c.MainCommandGroup[i].Cmd != cmd {
+					t.Fatalf("expected command %q, got %q", cmd, c.MainCommandGroup[i].Cmd)
+				}
+			}
+			if c.MainCommandGroup[1].Location[0] != tt.loc {
+				t.Fatalf("expected location %q, got %q", tt.loc, c.MainCommandGroup[1].Location[0])
+			}
+			tests, ok := c.CommandsWithTests[tt.cmds[0]]
+			if !ok {
+				t.Fatalf("tests are not found for expanded command %q", tt.cmds[0])
+			}
+			test := tests.Tests[1]
+			if test.Fields[0].Value != tt.value {
+				t.Fatalf("expected field value %q, got %q", tt.value, test.Fields[0].Value)
+			}
+			if !test.Pattern.RegExp.MatchString(tt.match) {
+				t.Fatalf("expanded pattern %q does not match %q", test.Pattern.RegExp.String(), tt.match)
+			}
+			test = tests.Tests[2]
+			b := []byte(tt.errors)
+			m := test.Pattern.RegExp.FindSubmatchIndex(b)
+			if m == nil {
+				t.Fatalf("expanded pattern %q does not match %q", test.Pattern.RegExp.String(), tt.errors)
+			}
+			triggered, err := test.EvaluateCondition(b, m, ConditionMatch{}, 1, 0, tt.vars, time.Now())
+			if err != nil {
+				t.Fatalf("failed to evaluate condition with error: %+v", err)
+			}
+			if triggered != tt.triggered {
+				t.Fatalf("expect triggered to be %t but got %t", tt.triggered, triggered)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// Facts are facts of the router and results of its commands, commands' when expressions are evaluated against them.
+type Facts struct {
+	// Hostname is the name of the router
+	Hostname string
+	// Platform is the name of the router's platform, empty when the router is not a network device
+	Platform string
+	// Vars are variables of the router, see Expand
+	Vars map[string]string
+	// Captures are values captured by the router's commands, see Captures
+	Captures Captures
+	// Triggered are ids of tests triggered by commands in the current iteration, keyed by the command
+	Triggered map[string][]int
+	router    Router
+}
+
+// NewFacts returns facts of the router, vars override variables of the commands file.
+func NewFacts(r Router, c *Commander, vars map[string]string) *Facts {
+	f := &Facts{
+		Hostname:  r.GetName(),
+		Vars:      make(map[string]string, len(c.Vars)+len(vars)),
+		Captures:  NewCaptures(),
+		Triggered: make(map[string][]int),
+		router:    r,
+	}
+	if p := r.GetPlatform(); p != nil {
+		f.Platform = p.Name()
+	}
+	for k, v := range c.Vars {
+		f.Vars[k] = v
+	}
+	for k, v := range vars {
+		f.Vars[k] = v
+	}
+
+	return f
+}
+
+// GetActiveRP returns the router's active route processor.
+func (f *Facts) GetActiveRP() string {
+	return f.router.GetActiveRP()
+}
+
+// GetAllLCs returns the router's line cards.
+func (f *Facts) GetAllLCs() []string {
+	return f.router.GetAllLCs()
+}
+
+// GetAllRPs returns the router's route processors.
+func (f *Facts) GetAllRPs() []string {
+	return f.router.GetAllRPs()
+}
+
+// IsExistingLocation returns true when the location exists on the router.
+func (f *Facts) IsExistingLocation(l string) bool {
+	return f.router.IsExistingLocation(l)
+}
+
+// compileWhen compiles the when expression, it must evaluate to a boolean
+func compileWhen(s string) (*vm.Program, error) {
+	return expr.Compile(s, expr.Env(&Facts{}), expr.AsBool())
+}
+
+// IsExecuted evaluates the command's when expression, commands without when are always executed.
+func (f *Facts) IsExecuted(cmd *Command) (bool, error) {
+	if cmd.When == "" {
+		return true, nil
+	}
+	p := cmd.WhenProgram
+	if p == nil {
+		var err error
+		if p, err = compileWhen(cmd.When); err != nil {
+			return false, fmt.Errorf("command %q has invalid when %q with error: %+v", cmd.Cmd, cmd.When, err)
+		}
+		cmd.WhenProgram = p
+	}
+	v, err := expr.Run(p, f)
+	if err != nil {
+		return false, fmt.Errorf("command %q failed to evaluate when %q with error: %+v", cmd.Cmd, cmd.When, err)
+	}
+
+	return v.(bool), nil
+}
+
+// prepareWhen compiles when expressions of commands
+func prepareWhen(c *Commander) error {
+	for _, cmd := range allCommands(c) {
+		if cmd.When == "" {
+			continue
+		}
+		p, err := compileWhen(cmd.When)
+		if err != nil {
+			return fmt.Errorf("command %q has invalid when %q with error: %+v", cmd.Cmd, cmd.When, err)
+		}
+		cmd.WhenProgram = p
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFactsIsExecuted(t *testing.T) {
+	captured, err := ParseCapturedLog(strings.NewReader(capturedLog))
+	if err != nil {
+		t.Fatalf("failed to parse captured log with error: %+v", err)
+	}
+	r := newReplayRouter("r1", captured, nil)
+	c := &Commander{Vars: map[string]string{"VRF": "GI"}}
+	f := NewFacts(r, c, map[string]string{"ROLE": "pe"})
+	f.Captures["intf"] = []string{"Hu0/0/0/0"}
+	f.Triggered["show cef drops"] = []int{1}
+	tests := []struct {
+		name   string
+		when   string
+		expect bool
+		fail   bool
+	}{
+		{
+			name:   "no when",
+			expect: true,
+		},
+		{
+			name:   "platform and hostname",
+			when:   `Platform == "iosxr" && Hostname == "r1"`,
+			expect: true,
+		},
+		{
+			name:   "active rp",
+			when:   `GetActiveRP() == "0/RSP1/CPU0"`,
+			expect: false,
+		},
+		{
+			name:   "existing location",
+			when:   `IsExistingLocation("0/0/CPU0") && len(GetAllLCs()) == 1`,
+			expect: true,
+		},
+		{
+			name:   "variables",
+			when:   `Vars.VRF == "GI" && Vars.ROLE == "pe"`,
+			expect: true,
+		},
+		{
+			name:   "captures",
+			when:   `"Hu0/0/0/0" in Captures.intf`,
+			expect: true,
+		},
+		{
+			name:   "triggered tests",
+			when:   `"show cef drops" in Triggered && !("show clock" in Triggered)`,
+			expect: true,
+		},
+		{
+			name: "not a boolean",
+			when: `Hostname`,
+			fail: true,
+		},
+		{
+			name: "unknown fact",
+			when: `Model == "NCS-5508"`,
+			fail: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executed, err := f.IsExecuted(&Command{Cmd: "show version", When: tt.when})
+			if err != nil && !tt.fail {
+				t.Fatalf("supposed to succeed but failed with error: %+v", err)
+			}
+			if err == nil && tt.fail {
+				t.Fatalf("supposed to fail but succeeded")
+			}
+			if err == nil && executed != tt.expect {
+				t.Fatalf("expected %t, got %t", tt.expect, executed)
+			}
+		})
+	}
+}